@@ -2,22 +2,30 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"log"
 	"sort"
 	"strconv"
-	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
 	"time"
 
 	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
 	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"go-xpx-check-fork-util/internal/table"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	tablewriter "github.com/olekukonko/tablewriter"
 )
 
 type (
+	// AlertManager is shared by the check loop, the command listener, the status server and the background
+	// monitors. Its alert state is guarded by mu: the handle methods take it, and the helpers they call expect it
+	// to be held. Untracked alerts, incident mode and pausing only touch state that has locks of its own.
 	AlertManager struct {
+		mu sync.Mutex
+
 		config           AlertConfig
 		lastAlertTimes   map[AlertType]time.Time
 		lastStuckHeight  uint64
@@ -25,12 +33,47 @@ type (
 		offlineNodeStats map[string]NodeStatus
 		nodeInfos        []*health.NodeInfo
 		notifier         *Notifier
+		pager            Pager
+		openIncidents    map[AlertType]string
+		templates        map[AlertType]*template.Template
+		maintenance      *MaintenanceSchedule
+		hashStreaks      *HashStreakTracker
+		redactor         *Redactor
+		digest           *DigestCollector
+		incidentMode     *IncidentMode
+		identities       *IdentityMonitor
+		addresses        *AddressMonitor
+		links            *LinkMonitor
+		pause            *Pause
+		discovery        *DiscoveryTracker
+		heights          *HeightTracker
+		noPeers          *NoPeersTracker
+		probation        *Probation
+		// anchor is the reference hash of the trusted anchors at the checkpoint of the current cycle.
+		anchor *AnchorReference
+		// standby is set while another checker instance holds the lease, suppressing every notification.
+		standby atomic.Bool
+		// timedOffline measures the offline duration from the time the node went offline, known when pings are enabled.
+		timedOffline bool
+
+		// chainHeight is the highest height reported by any node in the last check cycle.
+		chainHeight uint64
+
+		// Latest results of the check loop, used to summarize maintenance windows.
+		lastFailedConnections map[string]*health.NodeInfo
+		lastNotReached        map[health.NodeInfo]uint64
+		// lastProbationOffline are the offline nodes in probation, reported along with the sync results.
+		lastProbationOffline []*health.NodeInfo
 	}
 
 	Notifier struct {
 		bot     *tgbotapi.BotAPI
 		chatID  int64
 		enabled bool
+		// dryRun logs the messages instead of sending them.
+		dryRun bool
+		// threads holds the message ID of the first alert of each open incident, if alerts are threaded.
+		threads map[AlertType]int
 	}
 
 	Alert interface {
@@ -39,18 +82,33 @@ type (
 	}
 
 	SyncAlert struct {
-		Height     uint64
-		NotReached map[health.NodeInfo]uint64
-		Reached    map[health.NodeInfo]uint64
+		Height uint64
+		// ChainHeight is the highest height reported by any node, 0 if unknown.
+		ChainHeight uint64
+		NotReached  map[health.NodeInfo]uint64
+		Reached     map[health.NodeInfo]uint64
+
+		// Versions of the nodes keyed by identity key, if identity monitoring is enabled.
+		Versions map[string]string
 	}
 
 	HashAlert struct {
-		Height uint64
-		Hashes map[string]sdk.Hash
+		Height      uint64
+		ChainHeight uint64
+		Hashes      map[string]sdk.Hash
+		// NoData lists the endpoints that had not answered when the alert was sent.
+		NoData []string
+		// Anchor is the hash of the trusted anchors, if configured and in agreement.
+		Anchor *sdk.Hash
+		// Divergence tells whether the nodes diverge from each other, from the anchor, or both.
+		Divergence string
+		// Final marks the follow-up of an alert sent before every node answered, with the complete hashes.
+		Final bool
 	}
 
 	OfflineAlert struct {
 		NotConnected map[string]*health.NodeInfo
+		ChainHeight  uint64
 	}
 
 	AlertType int
@@ -58,6 +116,8 @@ type (
 	NodeStatus struct {
 		consecutiveOfflineCount int
 		lastOfflineAlertTime    time.Time
+		// offlineSince is the time the node was first found offline, by a check cycle or a failed ping.
+		offlineSince time.Time
 	}
 )
 
@@ -65,8 +125,67 @@ const (
 	OfflineAlertType AlertType = iota
 	SyncAlertType
 	HashAlertType
+	MaintenanceAlertType
+	ApiGatewayAlertType
+	DigestAlertType
+	PeerListAlertType
+	IncidentModeAlertType
+	IncidentUpdateAlertType
+	IdentityAlertType
+	PauseAlertType
+	DiscoveredNodesAlertType
+	HeightRegressionAlertType
+	CoverageGapAlertType
+	AddressAlertType
+	DuplicateInstanceAlertType
+	WatchdogAlertType
+	NoPeersAlertType
+	ProbationAlertType
 )
 
+var alertTypeNames = map[AlertType]string{
+	OfflineAlertType:     "offline",
+	SyncAlertType:        "sync",
+	HashAlertType:        "hash",
+	MaintenanceAlertType: "maintenance",
+	ApiGatewayAlertType:  "apiGateway",
+	DigestAlertType:      "digest",
+	PeerListAlertType:    "peerList",
+
+	IncidentModeAlertType:      "incidentMode",
+	IncidentUpdateAlertType:    "incidentUpdate",
+	IdentityAlertType:          "identity",
+	PauseAlertType:             "pause",
+	DiscoveredNodesAlertType:   "discoveredNodes",
+	HeightRegressionAlertType:  "heightRegression",
+	CoverageGapAlertType:       "coverageGap",
+	AddressAlertType:           "address",
+	DuplicateInstanceAlertType: "duplicateInstance",
+	WatchdogAlertType:          "watchdog",
+	NoPeersAlertType:           "noPeers",
+	ProbationAlertType:         "probation",
+}
+
+var ErrUnknownAlertType = errors.New("unknown alert type")
+
+func (t AlertType) String() string {
+	if name, exists := alertTypeNames[t]; exists {
+		return name
+	}
+
+	return strconv.Itoa(int(t))
+}
+
+func parseAlertType(name string) (AlertType, error) {
+	for alertType, typeName := range alertTypeNames {
+		if typeName == name {
+			return alertType, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %s", ErrUnknownAlertType, name)
+}
+
 func (a SyncAlert) getType() AlertType {
 	return SyncAlertType
 }
@@ -79,6 +198,22 @@ func (a OfflineAlert) getType() AlertType {
 	return OfflineAlertType
 }
 
+// writeChainHeight shows the chain height, if known, along with its distance from the checkpoint.
+func writeChainHeight(buf *bytes.Buffer, separator string, checkpoint, chainHeight uint64) {
+	if chainHeight > 0 {
+		fmt.Fprintf(buf, "%sChain height: <b>%d</b> (%s)", separator, chainHeight, heightOffset(checkpoint, chainHeight))
+	}
+}
+
+// heightOffset describes how far the chain tip is from the checkpoint, or from where the nodes stopped if the chain
+// is stuck below it.
+func heightOffset(checkpoint, chainHeight uint64) string {
+	if chainHeight >= checkpoint {
+		return fmt.Sprintf("%d ahead of the checkpoint", chainHeight-checkpoint)
+	}
+	return fmt.Sprintf("%d behind the checkpoint", checkpoint-chainHeight)
+}
+
 func (a SyncAlert) writeSynced(buf *bytes.Buffer) {
 	fmt.Fprintf(buf, "\n\nSynced at <b>%d</b> (%d):", a.Height, len(a.Reached))
 
@@ -86,39 +221,17 @@ func (a SyncAlert) writeSynced(buf *bytes.Buffer) {
 		return
 	}
 
-	var nodesStr [][]string
+	var rows [][]string
 	for node := range a.Reached {
-		nodeStr := make([]string, 0, 1)
-		host := abbreviateIfDNSName(node.Endpoint)
-
-		if node.FriendlyName != "" && strings.TrimSpace(node.FriendlyName) != strings.TrimSpace(host) {
-			nodeStr = append(nodeStr, fmt.Sprintf("%s(%s)", node.FriendlyName, host))
-		} else {
-			nodeStr = append(nodeStr, host)
+		row := []string{nodeName(node)}
+		if len(a.Versions) > 0 {
+			row = append(row, a.Versions[node.IdentityKey.String()])
 		}
 
-		nodesStr = append(nodesStr, nodeStr)
+		rows = append(rows, row)
 	}
 
-	sort.Slice(nodesStr, func(i, j int) bool {
-		return nodesStr[i][0] < nodesStr[j][0]
-	})
-
-	fmt.Fprintf(buf, "<pre>")
-
-	table := tablewriter.NewWriter(buf)
-	table.SetAlignment(tablewriter.ALIGN_LEFT)
-	table.SetCenterSeparator("")
-	table.SetColumnSeparator("")
-	table.SetRowSeparator("")
-	table.SetBorder(false)
-	table.SetAutoWrapText(true)
-	table.SetNoWhiteSpace(true)
-	table.SetTablePadding(" ")
-	table.AppendBulk(nodesStr)
-	table.Render()
-
-	fmt.Fprintf(buf, "</pre>")
+	fmt.Fprintf(buf, "<pre>%s</pre>", table.Render(rows, table.Options{Sort: true}))
 }
 
 func (a SyncAlert) writeOutOfSync(buf *bytes.Buffer) {
@@ -141,41 +254,24 @@ func (a SyncAlert) writeOutOfSync(buf *bytes.Buffer) {
 		nodeWidth = maxWidth
 	}
 
-	var nodesStr [][]string
-	for node, h := range a.NotReached {
-		nodeStr := make([]string, 0, 2)
-		host := abbreviateIfDNSName(node.Endpoint)
+	// The height is the last column, aligned to the right.
+	align := []table.Align{table.AlignLeft, table.AlignRight}
+	if len(a.Versions) > 0 {
+		align = []table.Align{table.AlignLeft, table.AlignLeft, table.AlignRight}
+	}
 
-		if node.FriendlyName != "" && strings.TrimSpace(node.FriendlyName) != strings.TrimSpace(host) {
-			nodeStr = append(nodeStr, insertSpaceIfExceedsLength(fmt.Sprintf("%s(%s)", node.FriendlyName, host), nodeWidth))
-		} else {
-			nodeStr = append(nodeStr, host)
+	var rows [][]string
+	for node, h := range a.NotReached {
+		row := []string{nodeName(node)}
+		if len(a.Versions) > 0 {
+			row = append(row, a.Versions[node.IdentityKey.String()])
 		}
 
-		nodeStr = append(nodeStr, fmt.Sprintf("%8s", strconv.FormatUint(h, 10)))
-		nodesStr = append(nodesStr, nodeStr)
+		rows = append(rows, append(row, strconv.FormatUint(h, 10)))
 	}
 
-	sort.Slice(nodesStr, func(i, j int) bool {
-		return nodesStr[i][0] < nodesStr[j][0]
-	})
-
-	fmt.Fprintf(buf, "<pre>")
-
-	table := tablewriter.NewWriter(buf)
-	table.SetAlignment(tablewriter.ALIGN_LEFT)
-	table.SetCenterSeparator("")
-	table.SetColumnSeparator("")
-	table.SetRowSeparator("")
-	table.SetBorder(false)
-	table.SetAutoWrapText(true)
-	table.SetNoWhiteSpace(true)
-	table.SetTablePadding(" ")
-	table.SetColWidth(nodeWidth)
-	table.AppendBulk(nodesStr)
-	table.Render()
-
-	fmt.Fprintf(buf, "</pre>")
+	options := table.Options{MaxWidth: nodeWidth, Wrap: true, Sort: true, Align: align}
+	fmt.Fprintf(buf, "<pre>%s</pre>", table.Render(rows, options))
 }
 
 func (a SyncAlert) createMessage() string {
@@ -187,6 +283,7 @@ func (a SyncAlert) createMessage() string {
 		fmt.Fprintf(&buf, "<b>⚠️ Warning </b>")
 	}
 
+	writeChainHeight(&buf, "\n\n", a.Height, a.ChainHeight)
 	a.writeSynced(&buf)
 	a.writeOutOfSync(&buf)
 
@@ -201,18 +298,46 @@ func (a HashAlert) createMessage() string {
 
 	var buf bytes.Buffer
 
-	fmt.Fprintf(&buf, "<b>❗Fork Alert </b>\n\n")
-	fmt.Fprintf(&buf, "Inconsistent block hash:  <b>%d</b>\n", a.Height)
+	if a.Final {
+		fmt.Fprintf(&buf, "<b>❗Fork Alert - final hashes </b>\n\n")
+	} else {
+		fmt.Fprintf(&buf, "<b>❗Fork Alert </b>\n\n")
+	}
+	fmt.Fprintf(&buf, "Inconsistent block hash:  <b>%d</b>", a.Height)
+	writeChainHeight(&buf, "\n", a.Height, a.ChainHeight)
+	fmt.Fprintf(&buf, "\n")
+
+	if a.Anchor != nil {
+		switch a.Divergence {
+		case NodesDivergence:
+			fmt.Fprintf(&buf, "\nThe nodes diverge from each other. %d of them agree with the trusted anchor.\n", len(hashesGroup[*a.Anchor]))
+		case AnchorDivergence:
+			fmt.Fprintf(&buf, "\nThe nodes agree with each other, but not with the trusted anchor hash %s.\n", a.Anchor)
+		case BothDivergence:
+			fmt.Fprintf(&buf, "\nThe nodes diverge from each other, and none agrees with the trusted anchor hash %s.\n", a.Anchor)
+		}
+	}
 
 	fmt.Fprintf(&buf, "<pre>")
 	for hash, endpoints := range hashesGroup {
-		fmt.Fprintf(&buf, "%s:\n\n", hash)
+		if a.Anchor != nil && hash == *a.Anchor {
+			fmt.Fprintf(&buf, "%s (anchor):\n\n", hash)
+		} else {
+			fmt.Fprintf(&buf, "%s:\n\n", hash)
+		}
 		sort.Strings(endpoints)
 		for _, endpoint := range endpoints {
 			fmt.Fprintln(&buf, endpoint)
 		}
 		fmt.Fprintf(&buf, "\n\n")
 	}
+
+	if len(a.NoData) > 0 {
+		fmt.Fprintf(&buf, "No data (%d):\n\n", len(a.NoData))
+		for _, endpoint := range a.NoData {
+			fmt.Fprintln(&buf, endpoint)
+		}
+	}
 	fmt.Fprintf(&buf, "</pre>")
 
 	return buf.String()
@@ -221,36 +346,28 @@ func (a HashAlert) createMessage() string {
 func (a OfflineAlert) createMessage() string {
 	var buf bytes.Buffer
 	fmt.Fprintf(&buf, "<b>⚠️ Warning - Offline nodes </b>")
+	if a.ChainHeight > 0 {
+		fmt.Fprintf(&buf, "\n\nChain height: <b>%d</b>", a.ChainHeight)
+	}
 	fmt.Fprintf(&buf, "\n\nFailed connection  (%d):", len(a.NotConnected))
 
-	fmt.Fprintf(&buf, "<pre>")
-	var nodeStrings []string
+	var rows [][]string
 	for _, node := range a.NotConnected {
-		abbreviatedNode := abbreviateIfDNSName(node.Endpoint)
-		nodeStr := abbreviatedNode
-		if node.FriendlyName != "" && strings.TrimSpace(node.FriendlyName) != strings.TrimSpace(abbreviatedNode) {
-			nodeStr = fmt.Sprintf("%s(%s)", node.FriendlyName, abbreviatedNode)
-		}
-		nodeStrings = append(nodeStrings, nodeStr)
+		rows = append(rows, []string{nodeName(*node)})
 	}
-	sort.Strings(nodeStrings)
-
-	for _, str := range nodeStrings {
-		fmt.Fprintf(&buf, "%-37s\n", str)
-	}
-	fmt.Fprintf(&buf, "</pre>")
+	fmt.Fprintf(&buf, "<pre>%s</pre>", table.Render(rows, table.Options{Sort: true}))
 
 	return buf.String()
 }
 
 func (am *AlertManager) sendToTelegram(alert Alert) {
-	if !am.notifier.enabled {
+	if !am.notifier.enabled || am.paused(alert) {
 		return
 	}
 
-	msg := alert.createMessage()
+	msg := am.createMessage(am.redactor.redact(alert))
 
-	if err := am.notifier.sendToTelegram(msg); err != nil {
+	if err := am.notifier.sendToThread(alert.getType(), msg); err != nil {
 		log.Println(err)
 		return
 	}
@@ -262,12 +379,96 @@ func (am *AlertManager) sendToTelegram(alert Alert) {
 	}
 }
 
+// sendUntracked sends an alert without touching the alert state, so it is safe to call from other goroutines.
+func (am *AlertManager) sendUntracked(alert Alert) {
+	if !am.notifier.enabled || am.paused(alert) {
+		return
+	}
+
+	if err := am.notifier.sendToTelegram(am.createMessage(am.redactor.redact(alert))); err != nil {
+		log.Println(err)
+	}
+}
+
+// closeThread replies to the thread of an incident whose condition has cleared, so that the next alert of the
+// type starts a new one. While alerts are paused, the thread is closed without a reply.
+func (am *AlertManager) closeThread(alertType AlertType, summary string) {
+	if !am.notifier.enabled || am.pause.alertsPaused(time.Now()) {
+		delete(am.notifier.threads, alertType)
+		return
+	}
+
+	if err := am.notifier.closeThread(alertType, fmt.Sprintf("<b>✅ Resolved </b>\n\n%s.", summary)); err != nil {
+		log.Println(err)
+	}
+}
+
+// paused reports whether the alert is suppressed by a pause. Announcements of the pause itself are always sent.
+func (am *AlertManager) paused(alert Alert) bool {
+	if am.standby.Load() && alert.getType() != DuplicateInstanceAlertType {
+		log.Printf("Another instance holds the lease, not sending %s alert", alert.getType())
+		return true
+	}
+
+	if alert.getType() == PauseAlertType || !am.pause.alertsPaused(time.Now()) {
+		return false
+	}
+
+	log.Printf("Monitoring is paused, not sending %s alert", alert.getType())
+	return true
+}
+
+// createMessage renders the alert with its configured template, falling back to the built-in layout.
+func (am *AlertManager) createMessage(alert Alert) string {
+	tmpl, exists := am.templates[alert.getType()]
+	if !exists {
+		return alert.createMessage()
+	}
+
+	msg, err := renderTemplate(tmpl, alert)
+	if err != nil {
+		log.Printf("failed rendering %s alert template, using default message: %v", alert.getType(), err)
+		return alert.createMessage()
+	}
+
+	return msg
+}
+
 func (am *AlertManager) handleSyncAlert(checkpoint uint64, notReached, reached map[health.NodeInfo]uint64) {
-	if am.shouldSendSyncAlert(checkpoint, notReached, reached) && time.Since(am.lastAlertTimes[SyncAlertType]) > am.config.getSyncAlertRepeatInterval() {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	am.lastNotReached = notReached
+	am.chainHeight = maxHeight(notReached, reached)
+	am.observeDigestHeights(checkpoint, notReached, reached)
+
+	// Nodes under maintenance neither count toward the thresholds nor appear in the alert. Nodes in probation are
+	// reported on their own.
+	notReached = am.withoutMaintenanceNodes(notReached)
+	notReached, probation := am.withoutProbationNodes(notReached)
+	am.sendProbationAlert(ProbationAlert{Height: checkpoint, Offline: am.lastProbationOffline, OutOfSync: probation})
+
+	shouldAlert := am.shouldSendSyncAlert(checkpoint, notReached, reached)
+
+	if len(notReached) == 0 {
+		am.closeThread(SyncAlertType, fmt.Sprintf("All nodes reached height %d", checkpoint))
+	}
+
+	// Only a stuck chain is worth paging for, and the incident is resolved as soon as any node moves on.
+	if len(reached) > 0 {
+		am.resolveIncident(SyncAlertType)
+	} else if shouldAlert {
+		am.openIncident(SyncAlertType, incidentKey("stuck", checkpoint), fmt.Sprintf("Chain is stuck: no nodes reached height %d", checkpoint))
+	}
+
+	if shouldAlert && time.Since(am.lastAlertTimes[SyncAlertType]) > am.incidentMode.repeatInterval(am.config.getSyncAlertRepeatInterval()) {
+		am.digest.observeSyncAlert()
 		am.sendToTelegram(SyncAlert{
-			Height:     checkpoint,
-			NotReached: notReached,
-			Reached:    reached,
+			Height:      checkpoint,
+			ChainHeight: am.chainHeight,
+			NotReached:  notReached,
+			Reached:     reached,
+			Versions:    am.identities.versions(),
 		})
 	}
 }
@@ -282,12 +483,13 @@ func (am *AlertManager) shouldSendSyncAlert(checkpoint uint64, notReached, reach
 	}
 
 	criticalNodesCount := 0
+	threshold := am.config.OutOfSyncCriticalNodesThreshold.nodes(am.thresholdNodes())
 	for _, info := range am.nodeInfos {
 		if height, exists := notReached[*info]; exists {
 			if int(checkpoint-height) >= am.config.OutOfSyncBlocksThreshold {
 				criticalNodesCount++
 				// fmt.Println("criticalNodesCount:", criticalNodesCount)
-				if criticalNodesCount >= am.config.OutOfSyncCriticalNodesThreshold {
+				if criticalNodesCount >= threshold {
 					return true
 				}
 			}
@@ -308,16 +510,34 @@ func (am *AlertManager) isStuckDurationReached(checkpoint uint64) bool {
 	return false
 }
 
-func (am *AlertManager) handleOfflineAlert(failedConnectionsNodes map[string]*health.NodeInfo) {
+// handleOfflineAlert alerts on the nodes that could not be connected, stating the highest height reported by the
+// other nodes in the same cycle.
+func (am *AlertManager) handleOfflineAlert(failedConnectionsNodes map[string]*health.NodeInfo, chainHeight uint64) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	am.lastFailedConnections = failedConnectionsNodes
+	am.digest.observeOffline(time.Now(), am.nodeInfos, failedConnectionsNodes)
+	failedConnectionsNodes = am.withoutMaintenanceOfflineNodes(failedConnectionsNodes)
+	failedConnectionsNodes, am.lastProbationOffline = am.withoutProbationOfflineNodes(failedConnectionsNodes)
+
+	if len(failedConnectionsNodes) == 0 {
+		am.closeThread(OfflineAlertType, "All nodes are connected again")
+	}
+
 	if am.shouldSendOfflineAlert(failedConnectionsNodes) {
 		am.sendToTelegram(OfflineAlert{
 			NotConnected: failedConnectionsNodes,
+			ChainHeight:  chainHeight,
 		})
 	}
 }
 
+// shouldSendOfflineAlert reports whether at least offlineCriticalNodesThreshold configured nodes have been offline for
+// the offline duration threshold, and one of them was not alerted within the repeat interval.
 func (am *AlertManager) shouldSendOfflineAlert(failedConnectionsNodes map[string]*health.NodeInfo) bool {
 	shouldAlert := false
+	offlineNodes := 0
 
 	for _, info := range am.nodeInfos {
 		identityKey := info.IdentityKey.String()
@@ -330,17 +550,34 @@ func (am *AlertManager) shouldSendOfflineAlert(failedConnectionsNodes map[string
 				status.consecutiveOfflineCount++
 			}
 
+			if status.offlineSince.IsZero() {
+				status.offlineSince = time.Now()
+			}
+
 			am.updateNodeStatus(identityKey, status)
 
-			if status.consecutiveOfflineCount > am.config.getOfflineBlocksThreshold() && time.Since(status.lastOfflineAlertTime) > am.config.getOfflineAlertRepeatInterval() {
-				shouldAlert = true
+			if am.offlineThresholdReached(status) {
+				offlineNodes++
+				if time.Since(status.lastOfflineAlertTime) > am.incidentMode.repeatInterval(am.config.getOfflineAlertRepeatInterval()) {
+					shouldAlert = true
+				}
 			}
 		} else {
 			delete(am.offlineNodeStats, info.IdentityKey.String())
 		}
 	}
 
-	return shouldAlert
+	return shouldAlert && offlineNodes >= am.config.getOfflineCriticalNodesThreshold(am.thresholdNodes())
+}
+
+// offlineThresholdReached reports whether the node has been offline for the offline duration threshold. With pings,
+// the time since the node went offline is known; otherwise the duration is estimated from the number of check cycles.
+func (am *AlertManager) offlineThresholdReached(status NodeStatus) bool {
+	if am.timedOffline {
+		return time.Since(status.offlineSince) >= am.config.getOfflineDurationThreshold()
+	}
+
+	return status.consecutiveOfflineCount > am.config.getOfflineBlocksThreshold()
 }
 
 func (am *AlertManager) updateNodeStatusLastOfflineAlertTime(alert Alert) {
@@ -352,13 +589,101 @@ func (am *AlertManager) updateNodeStatusLastOfflineAlertTime(alert Alert) {
 	}
 }
 
+func (am *AlertManager) isConfiguredNode(node health.NodeInfo) bool {
+	for _, info := range am.nodeInfos {
+		if info.IdentityKey.String() == node.IdentityKey.String() {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (am *AlertManager) updateNodeStatus(key string, status NodeStatus) {
 	am.offlineNodeStats[key] = status
 }
 
-func (am *AlertManager) handleHashAlert(checkpoint uint64, hashes map[string]sdk.Hash) {
-	am.sendToTelegram(HashAlert{
-		Height: checkpoint,
-		Hashes: hashes,
-	})
+func (am *AlertManager) handleHashAlert(checkpoint uint64, hashes map[string]sdk.Hash, noData []*health.NodeInfo) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	am.digest.observeFork(checkpoint)
+	am.openIncident(HashAlertType, incidentKey("fork", checkpoint), fmt.Sprintf("Fork detected: inconsistent block hash at height %d", checkpoint))
+	am.enterIncidentMode(fmt.Sprintf("Fork detected at height %d", checkpoint), false)
+
+	am.sendToTelegram(am.newHashAlert(checkpoint, hashes, noData))
+}
+
+// handleFinalHashes follows up a fork alert sent before every node answered, once the rest have answered or missed
+// the hash deadline, so that the complete hash split is reported.
+func (am *AlertManager) handleFinalHashes(checkpoint uint64, hashes map[string]sdk.Hash, noData []*health.NodeInfo) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	alert := am.newHashAlert(checkpoint, hashes, noData)
+	alert.Final = true
+	am.sendToTelegram(alert)
+}
+
+func (am *AlertManager) newHashAlert(checkpoint uint64, hashes map[string]sdk.Hash, noData []*health.NodeInfo) HashAlert {
+	alert := HashAlert{
+		Height:      checkpoint,
+		ChainHeight: am.chainHeight,
+		Hashes:      hashes,
+		Anchor:      am.anchorHash(checkpoint),
+	}
+	alert.Divergence = hashDivergence(hashes, alert.Anchor)
+	for _, info := range noData {
+		alert.NoData = append(alert.NoData, info.Endpoint)
+	}
+
+	return alert
+}
+
+// handleHashRecovery resolves an open fork incident once the nodes agree on a block hash again.
+func (am *AlertManager) handleHashRecovery() {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	am.resolveIncident(HashAlertType)
+	am.closeThread(HashAlertType, "Block hashes agree again")
+	am.exitIncidentMode("Block hashes agree again", false)
+}
+
+func (am *AlertManager) openIncident(alertType AlertType, dedupKey, summary string) {
+	if am.pager == nil || am.pause.alertsPaused(time.Now()) || am.standby.Load() {
+		return
+	}
+
+	// Keep the first incident open until it is resolved rather than paging for every height.
+	if _, exists := am.openIncidents[alertType]; exists {
+		return
+	}
+
+	if err := am.pager.trigger(dedupKey, summary); err != nil {
+		log.Printf("failed to open incident %s: %v", dedupKey, err)
+		return
+	}
+
+	log.Printf("Opened incident %s", dedupKey)
+	am.openIncidents[alertType] = dedupKey
+}
+
+func (am *AlertManager) resolveIncident(alertType AlertType) {
+	if am.pager == nil {
+		return
+	}
+
+	dedupKey, exists := am.openIncidents[alertType]
+	if !exists {
+		return
+	}
+
+	if err := am.pager.resolve(dedupKey); err != nil {
+		log.Printf("failed to resolve incident %s: %v", dedupKey, err)
+		return
+	}
+
+	log.Printf("Resolved incident %s", dedupKey)
+	delete(am.openIncidents, alertType)
 }
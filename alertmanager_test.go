@@ -1,9 +1,11 @@
 package main
 
 import (
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
 	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -232,3 +234,39 @@ func getPublicKey(key string) *crypto.PublicKey {
 	publicKey, _ := crypto.NewPublicKeyfromHex(key)
 	return publicKey
 }
+
+func TestAlertManagerConcurrency(t *testing.T) {
+	am := newIncidentTestAlertManager(t, nil)
+	am.hashStreaks = NewHashStreakTracker()
+	fc := &ForkChecker{alertManager: am, status: &StatusServer{}}
+
+	offline := map[string]*health.NodeInfo{am.nodeInfos[0].IdentityKey.String(): am.nodeInfos[0]}
+	notReached := map[health.NodeInfo]uint64{*am.nodeInfos[0]: 9}
+
+	// The check loop, the status server and a state restore at the same time; run with -race.
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for height := uint64(10); height < 200; height++ {
+				switch i {
+				case 0:
+					am.handleOfflineAlert(offline, 0)
+					am.handleSyncAlert(height, notReached, map[health.NodeInfo]uint64{})
+					am.observeHashes(height, map[string]sdk.Hash{"a": {1}, "b": {2}}, nil)
+					am.handleHashAlert(height, nil, nil)
+					am.handleHashRecovery()
+				case 1:
+					fc.status.update(fc.buildStatus())
+				case 2:
+					fc.restoreState(fc.exportState())
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, fc.buildStatus().OfflineNodes, 1)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"go-xpx-check-fork-util/internal/table"
+)
+
+const DefaultAddressAlertRepeatInterval = time.Hour * 2
+
+var ErrInvalidAllowedIP = errors.New("invalid allowed IP")
+
+type (
+	// AddressMonitor checks that the connections to nodes with an allowlist come from one of the allowed addresses.
+	// The handshake only proves that the peer holds the node's key, so a hijacked DNS name or a misrouted endpoint
+	// that reaches another machine sharing the key would otherwise pass unnoticed.
+	AddressMonitor struct {
+		allowed             map[string][]*net.IPNet
+		alertRepeatInterval time.Duration
+		lastAlertTimes      map[string]time.Time
+	}
+
+	AddressMismatch struct {
+		Node     health.NodeInfo
+		RemoteIP string
+		Allowed  []string
+	}
+
+	// AddressAlert reports nodes whose connection comes from an address outside their allowlist.
+	AddressAlert struct {
+		Nodes []AddressMismatch
+	}
+)
+
+// parseAllowedIPs parses IP addresses and CIDR ranges. A single address is a range of one.
+func parseAllowedIPs(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+
+		if ip := net.ParseIP(value); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s is neither an IP address nor a CIDR range", ErrInvalidAllowedIP, value)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// NewAddressMonitor returns nil if no node has an allowlist. The node infos are those parsed from the nodes.
+func NewAddressMonitor(nodes []Node, nodeInfos []*health.NodeInfo) (*AddressMonitor, error) {
+	allowed := make(map[string][]*net.IPNet)
+	for i, node := range nodes {
+		if len(node.AllowedIPs) == 0 {
+			continue
+		}
+
+		networks, err := parseAllowedIPs(node.AllowedIPs)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", node.Endpoint, err)
+		}
+		allowed[nodeInfos[i].IdentityKey.String()] = networks
+	}
+
+	if len(allowed) == 0 {
+		return nil, nil
+	}
+
+	return &AddressMonitor{
+		allowed:             allowed,
+		alertRepeatInterval: DefaultAddressAlertRepeatInterval,
+		lastAlertTimes:      make(map[string]time.Time),
+	}, nil
+}
+
+func (m *AddressMonitor) isAllowed(key string, ip net.IP) bool {
+	for _, network := range m.allowed[key] {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// mismatches returns the nodes connected from an address outside their allowlist. A node that is back on an allowed
+// address has its repeat timer reset, so a new mismatch is alerted right away.
+func (m *AddressMonitor) mismatches(nodeInfos []*health.NodeInfo, addresses map[string]net.IP) []AddressMismatch {
+	var result []AddressMismatch
+	for _, info := range nodeInfos {
+		key := info.IdentityKey.String()
+		ip, connected := addresses[key]
+		if _, exists := m.allowed[key]; !exists || !connected {
+			continue
+		}
+
+		if m.isAllowed(key, ip) {
+			delete(m.lastAlertTimes, key)
+			continue
+		}
+
+		allowed := make([]string, 0, len(m.allowed[key]))
+		for _, network := range m.allowed[key] {
+			allowed = append(allowed, network.String())
+		}
+		result = append(result, AddressMismatch{Node: *info, RemoteIP: ip.String(), Allowed: allowed})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return nodeName(result[i].Node) < nodeName(result[j].Node)
+	})
+
+	return result
+}
+
+// RemoteAddresses returns the remote IP of every connection, keyed by identity key.
+func (p *NodePool) RemoteAddresses() map[string]net.IP {
+	addresses := make(map[string]net.IP)
+	for _, conn := range p.connections() {
+		if conn.remoteIP != nil {
+			addresses[conn.info.IdentityKey.String()] = conn.remoteIP
+		}
+	}
+
+	return addresses
+}
+
+// handleRemoteAddresses alerts on configured nodes connected from an address outside their allowlist. Nodes under
+// maintenance are skipped, as they may be moved there.
+func (am *AlertManager) handleRemoteAddresses(addresses map[string]net.IP) {
+	if am.addresses == nil {
+		return
+	}
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	now := time.Now()
+	m := am.addresses
+
+	var alert AddressAlert
+	for _, mismatch := range m.mismatches(am.nodeInfos, addresses) {
+		if am.maintenance.inMaintenance(mismatch.Node.Endpoint, now) {
+			continue
+		}
+
+		log.Printf("node %s is connected from %s, which is not in its allowed IPs %v", mismatch.Node.Endpoint, mismatch.RemoteIP, mismatch.Allowed)
+
+		key := mismatch.Node.IdentityKey.String()
+		if now.Sub(m.lastAlertTimes[key]) > am.incidentMode.repeatInterval(m.alertRepeatInterval) {
+			alert.Nodes = append(alert.Nodes, mismatch)
+			m.lastAlertTimes[key] = now
+		}
+	}
+
+	if len(alert.Nodes) > 0 {
+		am.sendToTelegram(alert)
+	}
+}
+
+func (a AddressAlert) getType() AlertType {
+	return AddressAlertType
+}
+
+func (a AddressAlert) createMessage() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "<b>❗ Unexpected node address </b>\n\n")
+	fmt.Fprintf(&buf, "Connected from outside the allowed IPs, possibly a DNS hijack or a misrouted endpoint (%d):", len(a.Nodes))
+
+	rows := make([][]string, 0, len(a.Nodes))
+	for _, node := range a.Nodes {
+		rows = append(rows, []string{nodeName(node.Node), node.RemoteIP, "allowed: " + strings.Join(node.Allowed, ", ")})
+	}
+	fmt.Fprintf(&buf, "<pre>%s</pre>", table.Render(rows, table.Options{}))
+
+	return buf.String()
+}
+
+// redact hides the addresses along with the node, as they locate the node just like its endpoint.
+func (a AddressAlert) redact(r *Redactor) Alert {
+	nodes := make([]AddressMismatch, 0, len(a.Nodes))
+	for _, node := range a.Nodes {
+		node.Node = r.node(node.Node)
+		node.RemoteIP = hashedLabel(node.RemoteIP)
+
+		allowed := make([]string, 0, len(node.Allowed))
+		for _, network := range node.Allowed {
+			allowed = append(allowed, hashedLabel(network))
+		}
+		node.Allowed = allowed
+
+		nodes = append(nodes, node)
+	}
+
+	a.Nodes = nodes
+	return a
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAllowedIPs(t *testing.T) {
+	networks, err := parseAllowedIPs([]string{"10.0.0.1", " 192.168.0.0/24 ", "2001:db8::1"})
+	require.NoError(t, err)
+	require.Len(t, networks, 3)
+	assert.Equal(t, "10.0.0.1/32", networks[0].String())
+	assert.True(t, networks[1].Contains(net.ParseIP("192.168.0.77")))
+	assert.Equal(t, "2001:db8::1/128", networks[2].String())
+
+	for _, value := range []string{"node.example.com", "10.0.0.0/33", ""} {
+		_, err := parseAllowedIPs([]string{value})
+		assert.ErrorIs(t, err, ErrInvalidAllowedIP, value)
+	}
+}
+
+func TestAddressMonitor(t *testing.T) {
+	config, err := LoadConfig("sample.config.json")
+	require.NoError(t, err)
+
+	t.Run("Disabled", func(t *testing.T) {
+		monitor, err := NewAddressMonitor(config.Nodes, nil)
+		require.NoError(t, err)
+		assert.Nil(t, monitor)
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		nodes := append([]Node(nil), config.Nodes...)
+		nodes[0].AllowedIPs = []string{"10.0.0.0/8"}
+		nodes[1].AllowedIPs = []string{"127.0.0.1"}
+
+		am.addresses, err = NewAddressMonitor(nodes, am.nodeInfos)
+		require.NoError(t, err)
+
+		// Only connected nodes with an allowlist are checked.
+		addresses := map[string]net.IP{}
+		for _, info := range am.nodeInfos {
+			addresses[info.IdentityKey.String()] = net.ParseIP("127.0.0.1")
+		}
+
+		mismatches := am.addresses.mismatches(am.nodeInfos, addresses)
+		require.Len(t, mismatches, 1)
+		assert.Equal(t, *am.nodeInfos[0], mismatches[0].Node)
+		assert.Equal(t, "127.0.0.1", mismatches[0].RemoteIP)
+		assert.Equal(t, []string{"10.0.0.0/8"}, mismatches[0].Allowed)
+
+		// A mismatch is alerted once per repeat interval, and again right away once it recurs.
+		am.handleRemoteAddresses(addresses)
+		key := am.nodeInfos[0].IdentityKey.String()
+		alerted := am.addresses.lastAlertTimes[key]
+		require.False(t, alerted.IsZero())
+
+		am.handleRemoteAddresses(addresses)
+		assert.Equal(t, alerted, am.addresses.lastAlertTimes[key])
+
+		addresses[key] = net.ParseIP("10.1.2.3")
+		am.handleRemoteAddresses(addresses)
+		assert.NotContains(t, am.addresses.lastAlertTimes, key)
+	})
+
+	t.Run("Alert", func(t *testing.T) {
+		node := *newScoreTestNodes()[0]
+		alert := AddressAlert{Nodes: []AddressMismatch{{Node: node, RemoteIP: "203.0.113.7", Allowed: []string{"10.0.0.1/32"}}}}
+		assert.Equal(t, AddressAlertType, alert.getType())
+		assert.Contains(t, alert.createMessage(), "203.0.113.7")
+
+		redacted := alert.redact(NewRedactor(HashedPrivacyMode, nil)).createMessage()
+		assert.NotContains(t, redacted, "203.0.113.7")
+		assert.NotContains(t, redacted, "10.0.0.1")
+	})
+}
+
+func TestNodePoolRemoteAddresses(t *testing.T) {
+	node := newTestNode(t, 10, sdk.Hash{1})
+
+	pool := newTestNodePool(t)
+	_, err := pool.ConnectToNodes([]*health.NodeInfo{node.info()}, false)
+	require.NoError(t, err)
+
+	addresses := pool.RemoteAddresses()
+	require.Contains(t, addresses, node.info().IdentityKey.String())
+	assert.True(t, addresses[node.info().IdentityKey.String()].IsLoopback())
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+)
+
+const DefaultAnchorTimeout = 10 * time.Second
+
+// Hash divergences, as stated in a fork alert.
+const (
+	// NodesDivergence means the nodes disagree with each other, and some of them agree with the anchors if any.
+	NodesDivergence = "nodes"
+	// AnchorDivergence means the nodes agree with each other on a hash the anchors do not have.
+	AnchorDivergence = "anchor"
+	// BothDivergence means the nodes disagree with each other and none of them agrees with the anchors.
+	BothDivergence = "both"
+)
+
+type (
+	// AnchorMonitor fetches the block hash at the checkpoint from trusted REST endpoints, e.g. the explorer API run
+	// by the foundation, whose hash is taken as the canonical one. It tells a fork among the monitored nodes from
+	// the monitored nodes as a whole following the wrong chain.
+	AnchorMonitor struct {
+		urls   []string
+		client *http.Client
+	}
+
+	// AnchorReference is the block hash at a height according to the anchors.
+	AnchorReference struct {
+		Height uint64
+		// Hashes holds the hash of every anchor that answered, keyed by url.
+		Hashes map[string]sdk.Hash
+	}
+
+	blockMetaDTO struct {
+		Meta struct {
+			Hash string `json:"hash"`
+		} `json:"meta"`
+	}
+)
+
+// NewAnchorMonitor returns nil if no anchor is configured.
+func NewAnchorMonitor(config AnchorConfig) *AnchorMonitor {
+	if len(config.Urls) == 0 {
+		return nil
+	}
+
+	return &AnchorMonitor{urls: config.Urls, client: &http.Client{Timeout: config.getTimeout()}}
+}
+
+func (a *AnchorConfig) getTimeout() time.Duration {
+	return parseOptionalDuration(a.Timeout, "anchor timeout", DefaultAnchorTimeout)
+}
+
+// reference queries every anchor for the hash at the height. Anchors that fail or have not reached the height yet
+// are left out; it returns nil if none answered.
+func (m *AnchorMonitor) reference(height uint64) *AnchorReference {
+	if m == nil {
+		return nil
+	}
+
+	ref := &AnchorReference{Height: height, Hashes: make(map[string]sdk.Hash)}
+
+	var mu sync.Mutex
+	forEachLimited(len(m.urls), len(m.urls), func(i int) {
+		hash, err := m.fetchBlockHash(m.urls[i], height)
+		if err != nil {
+			log.Printf("failed to get the block hash at %d height from anchor %s: %v", height, m.urls[i], err)
+			return
+		}
+
+		mu.Lock()
+		ref.Hashes[m.urls[i]] = hash
+		mu.Unlock()
+	})
+
+	if len(ref.Hashes) == 0 {
+		return nil
+	}
+
+	if _, ok := ref.canonical(); !ok {
+		log.Printf("trusted anchors disagree on the block hash at %d height: %v", height, ref.Hashes)
+	}
+
+	return ref
+}
+
+func (m *AnchorMonitor) fetchBlockHash(url string, height uint64) (sdk.Hash, error) {
+	resp, err := m.client.Get(fmt.Sprintf("%s/block/%d", strings.TrimRight(url, "/"), height))
+	if err != nil {
+		return sdk.Hash{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return sdk.Hash{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	dto := &blockMetaDTO{}
+	if err := json.NewDecoder(resp.Body).Decode(dto); err != nil {
+		return sdk.Hash{}, fmt.Errorf("failed decoding block: %v", err)
+	}
+
+	hash, err := sdk.StringToHash(dto.Meta.Hash)
+	if err != nil {
+		return sdk.Hash{}, fmt.Errorf("invalid block hash %q: %v", dto.Meta.Hash, err)
+	}
+
+	return *hash, nil
+}
+
+// canonical returns the hash the anchors agree on. Anchors that disagree with each other give no reference.
+func (r *AnchorReference) canonical() (sdk.Hash, bool) {
+	if r == nil {
+		return sdk.Hash{}, false
+	}
+
+	var hash sdk.Hash
+	found := false
+	for _, h := range r.Hashes {
+		if found && h != hash {
+			return sdk.Hash{}, false
+		}
+		hash, found = h, true
+	}
+
+	return hash, found
+}
+
+// hashDivergence tells whether the node hashes diverge from each other, from the anchor, or both. It returns "" if
+// the nodes agree with each other and with the anchor, if any.
+func hashDivergence(hashes map[string]sdk.Hash, anchor *sdk.Hash) string {
+	distinct := make(map[sdk.Hash]struct{})
+	for _, hash := range hashes {
+		distinct[hash] = struct{}{}
+	}
+
+	matchesAnchor := false
+	if anchor != nil {
+		_, matchesAnchor = distinct[*anchor]
+	}
+
+	switch {
+	case len(distinct) > 1 && anchor != nil && !matchesAnchor:
+		return BothDivergence
+	case len(distinct) > 1:
+		return NodesDivergence
+	case len(distinct) == 1 && anchor != nil && !matchesAnchor:
+		return AnchorDivergence
+	default:
+		return ""
+	}
+}
+
+// observeAnchor records the anchor reference at the checkpoint of the cycle, for the fork alerts of the cycle.
+func (am *AlertManager) observeAnchor(ref *AnchorReference) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	am.anchor = ref
+}
+
+// anchorHash returns the canonical hash at the height, or nil if the anchors gave none.
+func (am *AlertManager) anchorHash(height uint64) *sdk.Hash {
+	if am.anchor == nil || am.anchor.Height != height {
+		return nil
+	}
+
+	hash, ok := am.anchor.canonical()
+	if !ok {
+		return nil
+	}
+
+	return &hash
+}
+
+// handleAnchorDivergence alerts if the nodes agreed on the hash at the checkpoint but the anchors have another one.
+// It reports whether they did.
+func (am *AlertManager) handleAnchorDivergence(checkpoint uint64, hashes map[string]sdk.Hash) bool {
+	am.mu.Lock()
+	diverged := hashDivergence(hashes, am.anchorHash(checkpoint)) == AnchorDivergence
+	am.mu.Unlock()
+
+	if diverged {
+		log.Printf("block hashes at %d height differ from the trusted anchors: %v", checkpoint, hashes)
+		am.handleHashAlert(checkpoint, hashes, nil)
+	}
+
+	return diverged
+}
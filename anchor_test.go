@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAnchor(t *testing.T, hash sdk.Hash) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/block/100" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"meta":{"hash":"%s"},"block":{}}`, strings.ToUpper(hash.String()))
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func TestAnchorMonitor(t *testing.T) {
+	canonical, other := sdk.Hash{1}, sdk.Hash{2}
+
+	t.Run("Disabled", func(t *testing.T) {
+		monitor := NewAnchorMonitor(AnchorConfig{})
+		assert.Nil(t, monitor)
+		assert.Nil(t, monitor.reference(100))
+	})
+
+	t.Run("Reference", func(t *testing.T) {
+		monitor := NewAnchorMonitor(AnchorConfig{Urls: []string{newTestAnchor(t, canonical), newTestAnchor(t, canonical) + "/"}})
+
+		ref := monitor.reference(100)
+		require.NotNil(t, ref)
+		assert.Len(t, ref.Hashes, 2)
+		hash, ok := ref.canonical()
+		assert.True(t, ok)
+		assert.Equal(t, canonical, hash)
+
+		// Anchors that have not reached the height give no reference.
+		assert.Nil(t, monitor.reference(101))
+	})
+
+	t.Run("Anchors disagree", func(t *testing.T) {
+		monitor := NewAnchorMonitor(AnchorConfig{Urls: []string{newTestAnchor(t, canonical), newTestAnchor(t, other)}})
+
+		_, ok := monitor.reference(100).canonical()
+		assert.False(t, ok)
+	})
+
+	t.Run("Unreachable anchor", func(t *testing.T) {
+		monitor := NewAnchorMonitor(AnchorConfig{Urls: []string{newTestAnchor(t, canonical), "http://127.0.0.1:1"}})
+
+		hash, ok := monitor.reference(100).canonical()
+		assert.True(t, ok)
+		assert.Equal(t, canonical, hash)
+	})
+}
+
+func TestHashDivergence(t *testing.T) {
+	canonical, other := sdk.Hash{1}, sdk.Hash{2}
+	split := map[string]sdk.Hash{"a": canonical, "b": other}
+
+	assert.Equal(t, NodesDivergence, hashDivergence(split, nil))
+	assert.Equal(t, NodesDivergence, hashDivergence(split, &canonical))
+	assert.Equal(t, BothDivergence, hashDivergence(split, &sdk.Hash{3}))
+	assert.Equal(t, AnchorDivergence, hashDivergence(map[string]sdk.Hash{"a": other, "b": other}, &canonical))
+	assert.Empty(t, hashDivergence(map[string]sdk.Hash{"a": canonical, "b": canonical}, &canonical))
+	assert.Empty(t, hashDivergence(map[string]sdk.Hash{"a": canonical}, nil))
+}
+
+func TestAnchorAlerts(t *testing.T) {
+	canonical, other := sdk.Hash{1}, sdk.Hash{2}
+
+	t.Run("Nodes agree on another hash", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		am.notifier = &Notifier{enabled: true, dryRun: true}
+		am.observeAnchor(&AnchorReference{Height: 100, Hashes: map[string]sdk.Hash{"anchor": canonical}})
+
+		assert.False(t, am.handleAnchorDivergence(100, map[string]sdk.Hash{"a": canonical, "b": canonical}))
+		assert.NotContains(t, am.lastAlertTimes, HashAlertType)
+
+		// The reference is only used at its own height.
+		assert.False(t, am.handleAnchorDivergence(101, map[string]sdk.Hash{"a": other, "b": other}))
+
+		assert.True(t, am.handleAnchorDivergence(100, map[string]sdk.Hash{"a": other, "b": other}))
+		assert.Contains(t, am.lastAlertTimes, HashAlertType)
+	})
+
+	t.Run("Message", func(t *testing.T) {
+		alert := HashAlert{Height: 100, Hashes: map[string]sdk.Hash{"a": canonical, "b": other}, Anchor: &canonical, Divergence: NodesDivergence}
+		assert.Contains(t, alert.createMessage(), "The nodes diverge from each other. 1 of them agree with the trusted anchor.")
+		assert.Contains(t, alert.createMessage(), canonical.String()+" (anchor):")
+
+		alert = HashAlert{Height: 100, Hashes: map[string]sdk.Hash{"a": other}, Anchor: &canonical, Divergence: AnchorDivergence}
+		assert.Contains(t, alert.createMessage(), "agree with each other, but not with the trusted anchor hash "+canonical.String())
+
+		alert.Divergence = BothDivergence
+		assert.Contains(t, alert.createMessage(), "none agrees with the trusted anchor")
+
+		// Without anchors, the message is unchanged.
+		alert = HashAlert{Height: 100, Hashes: map[string]sdk.Hash{"a": canonical, "b": other}, Divergence: NodesDivergence}
+		assert.NotContains(t, alert.createMessage(), "anchor")
+
+		templates, err := loadTemplates(map[string]string{"hash": "templates/hash.tmpl"})
+		require.NoError(t, err)
+		am := &AlertManager{templates: templates}
+		alert.Anchor = &canonical
+		assert.Contains(t, am.createMessage(alert), "(diverging: nodes)")
+	})
+}
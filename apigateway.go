@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultApiGatewayCheckInterval       = time.Minute
+	DefaultApiGatewayLatencyThreshold    = 3 * time.Second
+	DefaultApiGatewayAlertRepeatInterval = time.Hour * 2
+	DefaultApiGatewayLagBlocksThreshold  = 10
+
+	apiGatewayRequestTimeout = 10 * time.Second
+)
+
+type (
+	// ApiGatewayMonitor periodically checks the REST gateways in apiUrls, independently of the peer checks.
+	ApiGatewayMonitor struct {
+		config       ApiGatewayConfig
+		urls         []string
+		client       *http.Client
+		alertManager *AlertManager
+
+		// Highest height reported by the peers in the last check cycle.
+		peerHeight atomic.Uint64
+
+		mu             sync.Mutex
+		statuses       map[string]ApiGatewayStatus
+		lastAlertTimes map[string]time.Time
+	}
+
+	ApiGatewayStatus struct {
+		Url       string        `json:"url"`
+		Height    uint64        `json:"height"`
+		Latency   time.Duration `json:"latency"`
+		Healthy   bool          `json:"healthy"`
+		Problem   string        `json:"problem,omitempty"`
+		CheckedAt time.Time     `json:"checkedAt"`
+	}
+
+	ApiGatewayAlert struct {
+		Gateways []ApiGatewayStatus
+	}
+
+	chainHeightDTO struct {
+		Height [2]uint32 `json:"height"`
+	}
+)
+
+func (a ApiGatewayAlert) getType() AlertType {
+	return ApiGatewayAlertType
+}
+
+func (a ApiGatewayAlert) createMessage() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "<b>⚠️ Warning - API gateways </b>")
+	fmt.Fprintf(&buf, "\n\nProblems (%d):", len(a.Gateways))
+
+	fmt.Fprintf(&buf, "<pre>")
+	for _, gateway := range a.Gateways {
+		fmt.Fprintf(&buf, "%s\n  %s\n", gateway.Url, gateway.Problem)
+	}
+	fmt.Fprintf(&buf, "</pre>")
+
+	return buf.String()
+}
+
+func (a ApiGatewayAlert) redact(r *Redactor) Alert {
+	gateways := make([]ApiGatewayStatus, 0, len(a.Gateways))
+	for _, gateway := range a.Gateways {
+		gateway.Url = r.label(gateway.Url, "")
+		gateways = append(gateways, gateway)
+	}
+
+	a.Gateways = gateways
+	return a
+}
+
+func NewApiGatewayMonitor(config ApiGatewayConfig, urls []string, alertManager *AlertManager) *ApiGatewayMonitor {
+	return &ApiGatewayMonitor{
+		config:         config,
+		urls:           urls,
+		client:         &http.Client{Timeout: apiGatewayRequestTimeout},
+		alertManager:   alertManager,
+		statuses:       make(map[string]ApiGatewayStatus),
+		lastAlertTimes: make(map[string]time.Time),
+	}
+}
+
+func (m *ApiGatewayMonitor) observePeerHeight(height uint64) {
+	if m == nil {
+		return
+	}
+
+	m.peerHeight.Store(height)
+}
+
+// Run checks the gateways every check interval until the context is cancelled.
+func (m *ApiGatewayMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.getCheckInterval())
+	defer ticker.Stop()
+
+	for {
+		m.checkAll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *ApiGatewayMonitor) checkAll() {
+	results := make([]ApiGatewayStatus, len(m.urls))
+
+	var wg sync.WaitGroup
+	for i, url := range m.urls {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			results[i] = m.check(url)
+		}(i, url)
+	}
+	wg.Wait()
+
+	var problems []ApiGatewayStatus
+
+	m.mu.Lock()
+	for _, status := range results {
+		m.statuses[status.Url] = status
+
+		if !status.Healthy && time.Since(m.lastAlertTimes[status.Url]) > m.config.getAlertRepeatInterval() {
+			problems = append(problems, status)
+			m.lastAlertTimes[status.Url] = time.Now()
+		}
+	}
+	m.mu.Unlock()
+
+	if len(problems) > 0 {
+		m.alertManager.sendUntracked(ApiGatewayAlert{Gateways: problems})
+	}
+}
+
+func (m *ApiGatewayMonitor) check(url string) ApiGatewayStatus {
+	status := ApiGatewayStatus{Url: url, CheckedAt: time.Now().UTC()}
+
+	start := time.Now()
+	height, err := m.fetchChainHeight(url)
+	status.Latency = time.Since(start)
+
+	if err != nil {
+		status.Problem = fmt.Sprintf("unreachable: %v", err)
+		log.Printf("API gateway %s is %s", url, status.Problem)
+		return status
+	}
+
+	status.Height = height
+
+	var problems []string
+	if peerHeight := m.peerHeight.Load(); peerHeight > height && peerHeight-height >= m.config.getLagBlocksThreshold() {
+		problems = append(problems, fmt.Sprintf("lagging %d blocks behind peers (%d < %d)", peerHeight-height, height, peerHeight))
+	}
+
+	if status.Latency > m.config.getLatencyThreshold() {
+		problems = append(problems, fmt.Sprintf("slow response: %s", status.Latency.Round(time.Millisecond)))
+	}
+
+	if err := m.checkNodeHealth(url); err != nil {
+		problems = append(problems, fmt.Sprintf("unhealthy: %v", err))
+	}
+
+	if len(problems) > 0 {
+		status.Problem = strings.Join(problems, "; ")
+		log.Printf("API gateway %s: %s", url, status.Problem)
+		return status
+	}
+
+	status.Healthy = true
+	return status
+}
+
+func (m *ApiGatewayMonitor) fetchChainHeight(url string) (uint64, error) {
+	resp, err := m.client.Get(strings.TrimRight(url, "/") + "/chain/height")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	dto := &chainHeightDTO{}
+	if err := json.NewDecoder(resp.Body).Decode(dto); err != nil {
+		return 0, fmt.Errorf("failed decoding chain height: %v", err)
+	}
+
+	return uint64(dto.Height[1])<<32 | uint64(dto.Height[0]), nil
+}
+
+// checkNodeHealth queries the gateway's health endpoint. Gateways that do not provide one are considered healthy.
+func (m *ApiGatewayMonitor) checkNodeHealth(url string) error {
+	resp, err := m.client.Get(strings.TrimRight(url, "/") + "/node/health")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	return fmt.Errorf("health endpoint returned status %d", resp.StatusCode)
+}
+
+func (m *ApiGatewayMonitor) snapshot() []ApiGatewayStatus {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]ApiGatewayStatus, 0, len(m.statuses))
+	for _, status := range m.statuses {
+		result = append(result, status)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Url < result[j].Url
+	})
+
+	return result
+}
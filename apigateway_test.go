@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestGateway(t *testing.T, height string, healthStatus int, delay time.Duration) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+
+		switch r.URL.Path {
+		case "/chain/height":
+			w.Write([]byte(`{"height":` + height + `}`))
+		case "/node/health":
+			w.WriteHeader(healthStatus)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestApiGatewayMonitorCheck(t *testing.T) {
+	config := ApiGatewayConfig{LagBlocksThreshold: 5, LatencyThreshold: "1s"}
+
+	t.Run("Healthy", func(t *testing.T) {
+		server := newTestGateway(t, "[100,0]", http.StatusOK, 0)
+		monitor := NewApiGatewayMonitor(config, []string{server.URL}, nil)
+		monitor.observePeerHeight(102)
+
+		status := monitor.check(server.URL)
+		assert.True(t, status.Healthy)
+		assert.Equal(t, uint64(100), status.Height)
+	})
+
+	t.Run("Lagging", func(t *testing.T) {
+		server := newTestGateway(t, "[100,0]", http.StatusNotFound, 0)
+		monitor := NewApiGatewayMonitor(config, []string{server.URL}, nil)
+		monitor.observePeerHeight(110)
+
+		status := monitor.check(server.URL)
+		assert.False(t, status.Healthy)
+		assert.Contains(t, status.Problem, "lagging 10 blocks")
+	})
+
+	t.Run("Unhealthy", func(t *testing.T) {
+		server := newTestGateway(t, "[100,0]", http.StatusServiceUnavailable, 0)
+		monitor := NewApiGatewayMonitor(config, []string{server.URL}, nil)
+
+		status := monitor.check(server.URL)
+		assert.False(t, status.Healthy)
+		assert.Contains(t, status.Problem, "status 503")
+	})
+
+	t.Run("Slow", func(t *testing.T) {
+		server := newTestGateway(t, "[100,0]", http.StatusOK, 20*time.Millisecond)
+		monitor := NewApiGatewayMonitor(ApiGatewayConfig{LatencyThreshold: "10ms"}, []string{server.URL}, nil)
+
+		status := monitor.check(server.URL)
+		assert.False(t, status.Healthy)
+		assert.Contains(t, status.Problem, "slow response")
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		server := newTestGateway(t, "[100,0]", http.StatusOK, 0)
+		server.Close()
+		monitor := NewApiGatewayMonitor(config, []string{server.URL}, nil)
+
+		status := monitor.check(server.URL)
+		assert.False(t, status.Healthy)
+		assert.Contains(t, status.Problem, "unreachable")
+	})
+
+	t.Run("Large height", func(t *testing.T) {
+		server := newTestGateway(t, "[1,1]", http.StatusOK, 0)
+		monitor := NewApiGatewayMonitor(config, []string{server.URL}, nil)
+
+		status := monitor.check(server.URL)
+		assert.Equal(t, uint64(1<<32+1), status.Height)
+	})
+}
+
+func TestApiGatewayMonitorCheckAll(t *testing.T) {
+	healthy := newTestGateway(t, "[100,0]", http.StatusOK, 0)
+	unhealthy := newTestGateway(t, "[100,0]", http.StatusServiceUnavailable, 0)
+
+	am := newIncidentTestAlertManager(t, nil)
+	monitor := NewApiGatewayMonitor(ApiGatewayConfig{}, []string{healthy.URL, unhealthy.URL}, am)
+
+	monitor.checkAll()
+
+	statuses := monitor.snapshot()
+	require.Len(t, statuses, 2)
+	assert.Contains(t, monitor.lastAlertTimes, unhealthy.URL)
+	assert.NotContains(t, monitor.lastAlertTimes, healthy.URL)
+
+	// The alert is not repeated before the repeat interval has passed.
+	alertTime := monitor.lastAlertTimes[unhealthy.URL]
+	monitor.checkAll()
+	assert.Equal(t, alertTime, monitor.lastAlertTimes[unhealthy.URL])
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Capabilities are the groups of REST queries made by the checker. A network running several API generations
+// side by side may only serve some of them on each generation.
+const (
+	// ChainApiCapability covers /chain/height and /block/{height}, used for the checkpoint, the nemesis block and
+	// the gateway monitor.
+	ChainApiCapability = "chain"
+	// PeersApiCapability covers /node/info and /node/peers, used by the peer list monitor.
+	PeersApiCapability = "peers"
+)
+
+var (
+	ErrUnknownApiUrl        = errors.New("unknown API url")
+	ErrUnknownApiCapability = errors.New("unknown API capability")
+
+	apiCapabilities = []string{ChainApiCapability, PeersApiCapability}
+)
+
+func (c *Config) validateApiGenerations() error {
+	for url := range c.ApiGenerations {
+		found := false
+		for _, apiUrl := range c.ApiUrls {
+			if apiUrl == url {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("%w in apiGenerations: %s", ErrUnknownApiUrl, url)
+		}
+	}
+
+	for capability := range c.ApiCapabilities {
+		found := false
+		for _, known := range apiCapabilities {
+			if capability == known {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("%w: %s", ErrUnknownApiCapability, capability)
+		}
+	}
+
+	return nil
+}
+
+// apiUrlsFor returns the API urls whose generation serves the capability. Without generations listed for the
+// capability, every url is compatible.
+func (c *Config) apiUrlsFor(capability string) []string {
+	generations, restricted := c.ApiCapabilities[capability]
+	if !restricted {
+		return c.ApiUrls
+	}
+
+	var urls []string
+	for _, url := range c.ApiUrls {
+		generation := c.ApiGenerations[url]
+		for _, compatible := range generations {
+			if generation == compatible {
+				urls = append(urls, url)
+				break
+			}
+		}
+	}
+
+	return urls
+}
+
+// warnIncompatibleApiUrls logs the capabilities no configured API url can serve.
+func (c *Config) warnIncompatibleApiUrls() {
+	for _, capability := range apiCapabilities {
+		if len(c.apiUrlsFor(capability)) == 0 {
+			log.Printf("No API url serves the %s capability (generations %v)", capability, c.ApiCapabilities[capability])
+		}
+	}
+}
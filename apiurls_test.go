@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiUrlsFor(t *testing.T) {
+	config := &Config{
+		ApiUrls: []string{"http://v1:3000", "http://v2:3000", "http://untagged:3000"},
+		ApiGenerations: map[string]string{
+			"http://v1:3000": "v1",
+			"http://v2:3000": "v2",
+		},
+		ApiCapabilities: map[string][]string{
+			PeersApiCapability: {"v2"},
+		},
+	}
+	require.NoError(t, config.validateApiGenerations())
+
+	assert.Equal(t, config.ApiUrls, config.apiUrlsFor(ChainApiCapability))
+	assert.Equal(t, []string{"http://v2:3000"}, config.apiUrlsFor(PeersApiCapability))
+
+	config.ApiCapabilities[PeersApiCapability] = []string{"v3"}
+	assert.Empty(t, config.apiUrlsFor(PeersApiCapability))
+
+	config.ApiGenerations["http://other:3000"] = "v1"
+	require.ErrorIs(t, config.validateApiGenerations(), ErrUnknownApiUrl)
+	delete(config.ApiGenerations, "http://other:3000")
+
+	config.ApiCapabilities["receipts"] = []string{"v2"}
+	require.ErrorIs(t, config.validateApiGenerations(), ErrUnknownApiCapability)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+type (
+	// AuditLog records operator-visible changes to the checker's behaviour at runtime.
+	AuditLog struct {
+		mu   sync.Mutex
+		file string
+	}
+
+	AuditEntry struct {
+		Time    time.Time `json:"time"`
+		Actor   string    `json:"actor"`
+		Action  string    `json:"action"`
+		Details string    `json:"details"`
+	}
+)
+
+func NewAuditLog(file string) *AuditLog {
+	return &AuditLog{file: file}
+}
+
+// Record logs the entry and, if an audit log file is configured, appends it as a JSON line.
+func (a *AuditLog) Record(actor, action, details string) {
+	entry := AuditEntry{
+		Time:    time.Now().UTC(),
+		Actor:   actor,
+		Action:  action,
+		Details: details,
+	}
+
+	log.Printf("Audit: %s by %s: %s", entry.Action, entry.Actor, entry.Details)
+
+	if a.file == "" {
+		return
+	}
+
+	if err := a.append(entry); err != nil {
+		log.Printf("failed writing audit log '%s': %v", a.file, err)
+	}
+}
+
+func (a *AuditLog) append(entry AuditEntry) error {
+	var line bytes.Buffer
+	encoder := json.NewEncoder(&line)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(entry); err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %v", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	f, err := os.OpenFile(a.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(line.Bytes())
+	return err
+}
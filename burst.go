@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// DefaultBurstBlocks is the number of heights checked one by one past the ones produced during a stall.
+const DefaultBurstBlocks = 20
+
+func (b *BurstConfig) getBlocks() uint64 {
+	if b.Blocks == 0 {
+		return DefaultBurstBlocks
+	}
+	return b.Blocks
+}
+
+// observeStall records whether the chain was stuck at the checkpoint. When it moves again, burst mode checks every
+// height after the last checked one up to the highest one reported by the nodes, and burstConfig.blocks more, before
+// returning to the height check interval: forks most often appear while a stalled chain recovers, and the interval
+// would skip heights. The checkpoint is moved back to the first unchecked height.
+func (fc *ForkChecker) observeStall(stuck bool) {
+	if stuck || !fc.stalled {
+		fc.stalled = stuck
+		return
+	}
+
+	fc.stalled = false
+	if fc.cfg.BurstConfig.Disabled {
+		return
+	}
+
+	stalledAt := fc.checkpoint
+	if fc.lastChecked > 0 && fc.lastChecked < fc.checkpoint {
+		fc.checkpoint = fc.lastChecked + 1
+	}
+
+	until := fc.peerHeight
+	if until < stalledAt {
+		until = stalledAt
+	}
+	fc.burstUntil = until + fc.cfg.BurstConfig.getBlocks()
+
+	log.Printf("Chain moves again after a stall at %d height, checking every height from %d up to %d", stalledAt, fc.checkpoint, fc.burstUntil)
+	fc.audit.Record("burst", "start burst mode", fmt.Sprintf("heights %d-%d", fc.checkpoint, fc.burstUntil))
+}
+
+// nextCheckpoint returns the height to check after the current checkpoint: the next one in burst mode, otherwise
+// the one a height check interval further.
+func (fc *ForkChecker) nextCheckpoint() uint64 {
+	if fc.burstUntil == 0 {
+		return fc.checkpoint + fc.cfg.HeightCheckInterval
+	}
+
+	if fc.checkpoint >= fc.burstUntil {
+		log.Printf("Burst mode ended at %d height, checking every %d blocks again", fc.checkpoint, fc.cfg.HeightCheckInterval)
+		fc.burstUntil = 0
+		return fc.checkpoint + fc.cfg.HeightCheckInterval
+	}
+
+	return fc.checkpoint + 1
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBurstMode(t *testing.T) {
+	t.Run("Checkpoints", func(t *testing.T) {
+		fc := &ForkChecker{cfg: Config{HeightCheckInterval: 10}, audit: NewAuditLog(""), checkpoint: 100}
+		assert.Equal(t, uint64(110), fc.nextCheckpoint())
+
+		fc.observeStall(true)
+		fc.observeStall(true)
+		assert.Zero(t, fc.burstUntil)
+
+		fc.peerHeight = 105
+		fc.observeStall(false)
+		assert.Equal(t, uint64(105+DefaultBurstBlocks), fc.burstUntil)
+
+		for fc.checkpoint < fc.burstUntil {
+			assert.Equal(t, fc.checkpoint+1, fc.nextCheckpoint())
+			fc.checkpoint++
+		}
+
+		assert.Equal(t, fc.checkpoint+10, fc.nextCheckpoint())
+		assert.Zero(t, fc.burstUntil)
+
+		// Without a stall, recovering nodes do not start burst mode.
+		fc.observeStall(false)
+		assert.Zero(t, fc.burstUntil)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		fc := &ForkChecker{cfg: Config{HeightCheckInterval: 10, BurstConfig: BurstConfig{Disabled: true}}, checkpoint: 100}
+		fc.observeStall(true)
+		fc.observeStall(false)
+		assert.Zero(t, fc.burstUntil)
+		assert.False(t, fc.stalled)
+	})
+
+	t.Run("Check cycles", func(t *testing.T) {
+		nodeA := newTestNode(t, 5, sdk.Hash{1})
+		nodeB := newTestNode(t, 5, sdk.Hash{1})
+
+		fc := newReportTestForkChecker(t, nodeA, nodeB)
+		fc.audit = NewAuditLog("")
+		fc.cfg.HeightCheckInterval = 10
+		fc.cfg.BurstConfig.Blocks = 2
+
+		require.True(t, fc.checkCycle(context.Background()).Stuck)
+		assert.Equal(t, uint64(10), fc.checkpoint)
+
+		nodeA.height.Store(13)
+		nodeB.height.Store(13)
+
+		// Every height up to the one reached by the nodes and two more is checked, then the interval applies again.
+		var checked []uint64
+		for i := 0; i < 7; i++ {
+			report := fc.checkCycle(context.Background())
+			require.False(t, report.Fork)
+			checked = append(checked, report.Checkpoint)
+			if fc.checkpoint > 13 {
+				nodeA.height.Store(fc.checkpoint)
+				nodeB.height.Store(fc.checkpoint)
+			}
+		}
+
+		assert.Equal(t, []uint64{10, 11, 12, 13, 14, 15, 25}, checked)
+	})
+
+	t.Run("Heights before the stall", func(t *testing.T) {
+		nodeA := newTestNode(t, 15, sdk.Hash{1})
+		nodeB := newTestNode(t, 15, sdk.Hash{1})
+
+		fc := newReportTestForkChecker(t, nodeA, nodeB)
+		fc.audit = NewAuditLog("")
+		fc.cfg.HeightCheckInterval = 10
+		fc.cfg.BurstConfig.Blocks = 2
+
+		require.False(t, fc.checkCycle(context.Background()).Stuck)
+		require.True(t, fc.checkCycle(context.Background()).Stuck)
+		assert.Equal(t, uint64(20), fc.checkpoint)
+
+		nodeA.height.Store(22)
+		nodeB.height.Store(22)
+
+		// The heights produced up to the stall were skipped by the interval, so burst mode starts right after the
+		// last checked one.
+		var checked []uint64
+		for fc.checkpoint <= 24 {
+			report := fc.checkCycle(context.Background())
+			require.False(t, report.Fork)
+			checked = append(checked, report.Checkpoint)
+			if fc.checkpoint > 22 {
+				nodeA.height.Store(fc.checkpoint)
+				nodeB.height.Store(fc.checkpoint)
+			}
+		}
+
+		assert.Equal(t, []uint64{11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24}, checked)
+		assert.Equal(t, uint64(34), fc.checkpoint)
+	})
+}
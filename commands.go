@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const commandUpdateTimeout = 60
+
+// adminCommands may only be used by the users listed in the pause config.
+var adminCommands = map[string]struct{}{
+	"pause":  {},
+	"resume": {},
+}
+
+// CommandListener handles bot commands sent to the alert chat. Commands from other chats are ignored.
+type CommandListener struct {
+	bot          *tgbotapi.BotAPI
+	chatID       int64
+	alertManager *AlertManager
+}
+
+func NewCommandListener(bot *tgbotapi.BotAPI, chatID int64, alertManager *AlertManager) *CommandListener {
+	return &CommandListener{
+		bot:          bot,
+		chatID:       chatID,
+		alertManager: alertManager,
+	}
+}
+
+// Run polls the bot for commands until the context is cancelled.
+func (l *CommandListener) Run(ctx context.Context) {
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = commandUpdateTimeout
+	u.AllowedUpdates = []string{"message"}
+
+	updates := l.bot.GetUpdatesChan(u)
+	defer l.bot.StopReceivingUpdates()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update := <-updates:
+			if update.Message == nil || !update.Message.IsCommand() {
+				continue
+			}
+
+			if update.Message.Chat.ID != l.chatID {
+				log.Printf("ignoring command /%s from chat %d", update.Message.Command(), update.Message.Chat.ID)
+				continue
+			}
+
+			if !l.authorized(update.Message.Command(), update.Message.From) {
+				log.Printf("ignoring command /%s from %s, who is not an admin", update.Message.Command(), commandSender(update.Message))
+				continue
+			}
+
+			if reply := l.handle(update.Message.Command(), strings.TrimSpace(update.Message.CommandArguments()), commandSender(update.Message)); reply != "" {
+				if err := l.alertManager.notifier.sendToChat(l.chatID, reply); err != nil {
+					log.Println(err)
+				}
+			}
+		}
+	}
+}
+
+func (l *CommandListener) authorized(command string, from *tgbotapi.User) bool {
+	if _, exists := adminCommands[command]; !exists {
+		return true
+	}
+
+	return from != nil && l.alertManager.pause.isAdmin(from.ID)
+}
+
+// handle executes a command and returns the reply, if any.
+func (l *CommandListener) handle(command, args, sender string) string {
+	am := l.alertManager
+
+	switch command {
+	case "incident":
+		status := am.incidentMode.snapshot()
+		if status != nil && status.Manual {
+			return "Incident mode is already active: " + status.Reason
+		}
+
+		reason := args
+		if reason == "" {
+			reason = "no reason given"
+		}
+
+		am.enterIncidentMode(reason+" (by "+sender+")", true)
+
+		// Taking over an automatic incident mode is not announced, as it is already active.
+		if status != nil {
+			return "Incident mode stays active until /resolve."
+		}
+		return ""
+	case "resolve":
+		if !am.incidentMode.isActive() {
+			return "Incident mode is not active."
+		}
+
+		am.exitIncidentMode("Resolved by "+sender, true)
+		return ""
+	case "pause":
+		// The duration comes first, e.g. "/pause 2h network upgrade".
+		fields := strings.Fields(args)
+		if len(fields) == 0 {
+			return "Usage: /pause <duration> [reason], e.g. /pause 2h network upgrade"
+		}
+
+		duration, err := time.ParseDuration(fields[0])
+		if err != nil {
+			return fmt.Sprintf("Invalid duration %s: %v", fields[0], err)
+		}
+
+		if _, err := am.pauseMonitoring(sender, strings.Join(fields[1:], " "), duration); err != nil {
+			return err.Error()
+		}
+		return ""
+	case "resume":
+		if !am.resumeMonitoring(sender) {
+			return "Monitoring is not paused."
+		}
+		return ""
+	default:
+		return ""
+	}
+}
+
+func commandSender(msg *tgbotapi.Message) string {
+	if msg.From == nil {
+		return "unknown"
+	}
+
+	if msg.From.UserName != "" {
+		return "@" + msg.From.UserName
+	}
+
+	return msg.From.FirstName
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"os"
 	"time"
 
@@ -12,30 +13,232 @@ import (
 
 type (
 	Config struct {
-		Nodes               []Node      `json:"nodes"`
-		ApiUrls             []string    `json:"apiUrls"`
-		Discover            bool        `json:"discover"`
-		Checkpoint          uint64      `json:"checkpoint"`
-		HeightCheckInterval uint64      `json:"heightCheckInterval"`
-		BotAPIKey           string      `json:"botApiKey"`
-		ChatID              int64       `json:"chatID"`
-		Notify              bool        `json:"notify"`
-		AlertConfig         AlertConfig `json:"alertConfig"`
+		Nodes               []Node              `json:"nodes"`
+		ApiUrls             []string            `json:"apiUrls"`
+		ApiGenerations      map[string]string   `json:"apiGenerations"`
+		ApiCapabilities     map[string][]string `json:"apiCapabilities"`
+		NetworkType         string              `json:"networkType"`
+		GenerationHash      string              `json:"generationHash"`
+		Discover            bool                `json:"discover"`
+		Checkpoint          uint64              `json:"checkpoint"`
+		HeightCheckInterval uint64              `json:"heightCheckInterval"`
+		BotAPIKey           string              `json:"botApiKey"`
+		ChatID              int64               `json:"chatID"`
+		SecretsFile         string              `json:"secretsFile"`
+		Notify              bool                `json:"notify"`
+		BotCommands         bool                `json:"botCommands"`
+		AlertConfig         AlertConfig         `json:"alertConfig"`
+		PoolConfig          PoolConfig          `json:"poolConfig"`
+		IncidentConfig      IncidentConfig      `json:"incidentConfig"`
+		AuditLogFile        string              `json:"auditLogFile"`
+		StatusAddress       string              `json:"statusAddress"`
+
+		MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows"`
+		ApiGatewayConfig   ApiGatewayConfig    `json:"apiGatewayConfig"`
+		PeerListConfig     PeerListConfig      `json:"peerListConfig"`
+		DigestConfig       DigestConfig        `json:"digestConfig"`
+		StateConfig        StateConfig         `json:"stateConfig"`
+		IncidentModeConfig IncidentModeConfig  `json:"incidentModeConfig"`
+		IdentityConfig     IdentityConfig      `json:"identityConfig"`
+		LinkQualityConfig  LinkQualityConfig   `json:"linkQualityConfig"`
+		HashCacheConfig    HashCacheConfig     `json:"hashCacheConfig"`
+		PauseConfig        PauseConfig         `json:"pauseConfig"`
+		DiscoveryConfig    DiscoveryConfig     `json:"discoveryConfig"`
+		ScoreConfig        ScoreConfig         `json:"scoreConfig"`
+		WatchdogConfig     WatchdogConfig      `json:"watchdogConfig"`
+		SelfTestConfig     SelfTestConfig      `json:"selfTestConfig"`
+		AnchorConfig       AnchorConfig        `json:"anchorConfig"`
+		BurstConfig        BurstConfig         `json:"burstConfig"`
+
+		// fileName is the file the config was loaded from, used to reload it at runtime.
+		fileName string
+		// dryRun evaluates the alerts without sending them, set by the -dry-run flag.
+		dryRun bool
 	}
 
 	Node struct {
 		Endpoint     string `json:"endpoint"`
 		IdentityKey  string `json:"IdentityKey"`
 		FriendlyName string `json:"friendlyName"`
+		// AllowedIPs are the IP addresses or CIDR ranges the connection to the node may come from.
+		AllowedIPs []string `json:"allowedIPs"`
+	}
+
+	PoolConfig struct {
+		Concurrency       int    `json:"concurrency"`
+		ConnectTimeout    string `json:"connectTimeout"`
+		RequestTimeout    string `json:"requestTimeout"`
+		HeightWaitTimeout string `json:"heightWaitTimeout"`
+		HashConcurrency   int    `json:"hashConcurrency"`
+		HashDeadline      string `json:"hashDeadline"`
+		SecurityMode      string `json:"securityMode"`
+		ClientPrivateKey  string `json:"clientPrivateKey"`
+		MaxConnections    int    `json:"maxConnections"`
+		PingInterval      string `json:"pingInterval"`
+		// LocalAddress is the IP or network interface connections are made from, unless the node is in one of the
+		// LocalAddresses groups. Defaults to the OS choice.
+		LocalAddress   string              `json:"localAddress"`
+		LocalAddresses []LocalAddressGroup `json:"localAddresses"`
+	}
+
+	// LocalAddressGroup binds the connections to the listed nodes, given by endpoint, to a local IP or network
+	// interface, so that traffic to each region leaves through the right egress path.
+	LocalAddressGroup struct {
+		Address string   `json:"address"`
+		Nodes   []string `json:"nodes"`
 	}
 
 	AlertConfig struct {
-		OfflineAlertRepeatInterval      string `json:"offlineAlertRepeatInterval"`
-		OfflineDurationThreshold        string `json:"offlineDurationThreshold"`
-		SyncAlertRepeatInterval         string `json:"syncAlertRepeatInterval"`
-		StuckDurationThreshold          string `json:"stuckDurationThreshold"`
-		OutOfSyncBlocksThreshold        int    `json:"outOfSyncBlocksThreshold"`
-		OutOfSyncCriticalNodesThreshold int    `json:"outOfSyncCriticalNodesThreshold"`
+		OfflineAlertRepeatInterval      string            `json:"offlineAlertRepeatInterval"`
+		OfflineDurationThreshold        string            `json:"offlineDurationThreshold"`
+		SyncAlertRepeatInterval         string            `json:"syncAlertRepeatInterval"`
+		StuckDurationThreshold          string            `json:"stuckDurationThreshold"`
+		OutOfSyncBlocksThreshold        int               `json:"outOfSyncBlocksThreshold"`
+		OutOfSyncCriticalNodesThreshold NodeThreshold     `json:"outOfSyncCriticalNodesThreshold"`
+		OfflineCriticalNodesThreshold   NodeThreshold     `json:"offlineCriticalNodesThreshold"`
+		Templates                       map[string]string `json:"templates"`
+		PrivacyMode                     string            `json:"privacyMode"`
+		ThreadIncidents                 bool              `json:"threadIncidents"`
+		HeightRegressionTolerance       uint64            `json:"heightRegressionTolerance"`
+		NoPeersDurationThreshold        string            `json:"noPeersDurationThreshold"`
+		// ProbationPeriod is the time a node added to the config is left out of the critical thresholds.
+		ProbationPeriod string `json:"probationPeriod"`
+	}
+
+	MaintenanceWindow struct {
+		Name   string   `json:"name"`
+		Start  string   `json:"start"`
+		End    string   `json:"end"`
+		Repeat string   `json:"repeat"`
+		Nodes  []string `json:"nodes"`
+	}
+
+	ApiGatewayConfig struct {
+		Enabled             bool   `json:"enabled"`
+		CheckInterval       string `json:"checkInterval"`
+		LatencyThreshold    string `json:"latencyThreshold"`
+		AlertRepeatInterval string `json:"alertRepeatInterval"`
+		LagBlocksThreshold  uint64 `json:"lagBlocksThreshold"`
+	}
+
+	PeerListConfig struct {
+		Enabled             bool     `json:"enabled"`
+		CheckInterval       string   `json:"checkInterval"`
+		AlertRepeatInterval string   `json:"alertRepeatInterval"`
+		CriticalNodes       []string `json:"criticalNodes"`
+	}
+
+	DigestConfig struct {
+		Interval string `json:"interval"`
+		Start    string `json:"start"`
+		ChatID   int64  `json:"chatID"`
+	}
+
+	StateConfig struct {
+		Backend  string `json:"backend"`
+		File     string `json:"file"`
+		Bucket   string `json:"bucket"`
+		Key      string `json:"key"`
+		Region   string `json:"region"`
+		Endpoint string `json:"endpoint"`
+		// GapReportThreshold is the downtime after which a coverage gap report is sent on startup.
+		GapReportThreshold string `json:"gapReportThreshold"`
+		// LeaseDuration is the time after which a long-lived instance that stopped renewing its lease is replaced by
+		// one standing by. It has to exceed the longest check cycle.
+		LeaseDuration string `json:"leaseDuration"`
+		// InstanceID identifies the instance in the lease, so that a restarted one takes its own lease back. Every
+		// process gets a random one by default.
+		InstanceID string `json:"instanceId"`
+	}
+
+	IdentityConfig struct {
+		Enabled             bool   `json:"enabled"`
+		MinVersion          string `json:"minVersion"`
+		NemesisHash         string `json:"nemesisHash"`
+		CheckInterval       string `json:"checkInterval"`
+		AlertRepeatInterval string `json:"alertRepeatInterval"`
+	}
+
+	LinkQualityConfig struct {
+		Enabled       bool   `json:"enabled"`
+		CheckInterval string `json:"checkInterval"`
+		RttThreshold  string `json:"rttThreshold"`
+		MinThroughput int    `json:"minThroughput"`
+		ProbeHashes   int    `json:"probeHashes"`
+	}
+
+	DiscoveryConfig struct {
+		Include        []string `json:"include"`
+		Exclude        []string `json:"exclude"`
+		MaxNodes       int      `json:"maxNodes"`
+		NotifyNew      bool     `json:"notifyNew"`
+		HashSampleSize int      `json:"hashSampleSize"`
+	}
+
+	PauseConfig struct {
+		Scope       string  `json:"scope"`
+		MaxDuration string  `json:"maxDuration"`
+		AdminIDs    []int64 `json:"adminIDs"`
+		ApiToken    string  `json:"apiToken"`
+	}
+
+	ScoreConfig struct {
+		Enabled bool         `json:"enabled"`
+		Window  string       `json:"window"`
+		File    string       `json:"file"`
+		Weights ScoreWeights `json:"weights"`
+	}
+
+	ScoreWeights struct {
+		Uptime    float64 `json:"uptime"`
+		Sync      float64 `json:"sync"`
+		Agreement float64 `json:"agreement"`
+	}
+
+	// WatchdogConfig restarts a check cycle that has not completed within Multiplier times its expected duration.
+	WatchdogConfig struct {
+		Disabled   bool    `json:"disabled"`
+		Multiplier float64 `json:"multiplier"`
+		// Timeout replaces the timeout derived from the pool config, e.g. with discovery on large networks.
+		Timeout string `json:"timeout"`
+	}
+
+	// SelfTestConfig controls the test message sent to the alert channels on startup.
+	SelfTestConfig struct {
+		Disabled bool `json:"disabled"`
+		// Pager also triggers and resolves a test incident, which may notify whoever is on call.
+		Pager bool `json:"pager"`
+	}
+
+	// AnchorConfig lists the trusted REST endpoints whose block hash is taken as the canonical one.
+	AnchorConfig struct {
+		Urls    []string `json:"urls"`
+		Timeout string   `json:"timeout"`
+	}
+
+	// BurstConfig controls the checking of every height after the chain recovers from a stall.
+	BurstConfig struct {
+		Disabled bool   `json:"disabled"`
+		Blocks   uint64 `json:"blocks"`
+	}
+
+	HashCacheConfig struct {
+		Enabled bool   `json:"enabled"`
+		Size    int    `json:"size"`
+		Ttl     string `json:"ttl"`
+	}
+
+	IncidentModeConfig struct {
+		AlertRepeatInterval string `json:"alertRepeatInterval"`
+		UpdateInterval      string `json:"updateInterval"`
+		MaxDuration         string `json:"maxDuration"`
+	}
+
+	IncidentConfig struct {
+		Provider   string `json:"provider"`
+		RoutingKey string `json:"routingKey"`
+		ApiKey     string `json:"apiKey"`
+		ApiUrl     string `json:"apiUrl"`
 	}
 )
 
@@ -44,6 +247,12 @@ var (
 	ErrEmptyApiUrl = errors.New("API url cannot be empty")
 	ErrEmptyBotKey = errors.New("BotAPIKey cannot be empty")
 	ErrEmptyChatId = errors.New("ChatID cannot be empty")
+
+	ErrZeroHeightCheckInterval = errors.New("HeightCheckInterval must be greater than 0")
+
+	ErrUnknownIncidentProvider = errors.New("unknown incident provider")
+	ErrEmptyRoutingKey         = errors.New("RoutingKey cannot be empty for pagerduty provider")
+	ErrEmptyOpsgenieApiKey     = errors.New("ApiKey cannot be empty for opsgenie provider")
 )
 
 const (
@@ -51,6 +260,8 @@ const (
 	DefaultOfflineDurationThreshold   = time.Minute * 5
 	DefaultSyncAlertRepeatInterval    = time.Hour * 6
 	DefaultStuckDurationThreshold     = time.Minute * 10
+
+	DefaultHeightCheckInterval = 1
 )
 
 func LoadConfig(fileName string) (*Config, error) {
@@ -59,11 +270,23 @@ func LoadConfig(fileName string) (*Config, error) {
 		return nil, fmt.Errorf("failed reading config file '%s': %w", fileName, err)
 	}
 
+	content, err = resolveConfig(fileName, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed resolving config file '%s': %w", fileName, err)
+	}
+
 	config := &Config{}
 	if err := json.Unmarshal(content, config); err != nil {
 		return nil, fmt.Errorf("failed unmarshalling config file '%s': %w", fileName, err)
 	}
 
+	if err := config.applyEnvOverrides(); err != nil {
+		return nil, fmt.Errorf("failed applying overrides to config file '%s': %w", fileName, err)
+	}
+
+	config.fileName = fileName
+	config.normalize()
+
 	err = config.Validate()
 	if err != nil {
 		return nil, fmt.Errorf("validation error in config file '%s': %w", fileName, err)
@@ -72,6 +295,14 @@ func LoadConfig(fileName string) (*Config, error) {
 	return config, nil
 }
 
+// normalize replaces values that would silently break the check loop with their defaults.
+func (c *Config) normalize() {
+	if c.HeightCheckInterval == 0 {
+		log.Printf("heightCheckInterval is 0, which would never advance the checkpoint; using %d instead", DefaultHeightCheckInterval)
+		c.HeightCheckInterval = DefaultHeightCheckInterval
+	}
+}
+
 func (c *Config) Validate() error {
 	if len(c.Nodes) == 0 {
 		return ErrEmptyNodes
@@ -89,6 +320,101 @@ func (c *Config) Validate() error {
 		return ErrEmptyChatId
 	}
 
+	if err := c.validateApiGenerations(); err != nil {
+		return err
+	}
+
+	if _, _, err := c.parseNetwork(); err != nil {
+		return err
+	}
+
+	if err := validatePrivacyMode(c.AlertConfig.PrivacyMode); err != nil {
+		return err
+	}
+
+	if _, err := parseMaintenanceWindows(c.MaintenanceWindows); err != nil {
+		return err
+	}
+
+	if err := c.validateCriticalNodes(); err != nil {
+		return err
+	}
+
+	for _, node := range c.Nodes {
+		if _, err := parseAllowedIPs(node.AllowedIPs); err != nil {
+			return fmt.Errorf("node %s: %w", node.Endpoint, err)
+		}
+	}
+
+	if _, _, err := parseDigestConfig(c.DigestConfig); err != nil {
+		return err
+	}
+
+	if _, _, err := parseIdentityConfig(c.IdentityConfig); err != nil {
+		return err
+	}
+
+	if _, _, err := parsePoolSecurity(c.PoolConfig); err != nil {
+		return err
+	}
+
+	if err := validateLocalAddresses(c.PoolConfig, c.Nodes); err != nil {
+		return err
+	}
+
+	if err := validatePauseConfig(c.PauseConfig); err != nil {
+		return err
+	}
+
+	if _, err := NewDiscoveryFilter(c.DiscoveryConfig); err != nil {
+		return err
+	}
+
+	if err := validateScoreConfig(c.ScoreConfig); err != nil {
+		return err
+	}
+
+	if err := c.StateConfig.Validate(); err != nil {
+		return err
+	}
+
+	return c.IncidentConfig.Validate()
+}
+
+func (c *Config) validateCriticalNodes() error {
+	for _, endpoint := range c.PeerListConfig.CriticalNodes {
+		found := false
+		for _, node := range c.Nodes {
+			if node.Endpoint == endpoint {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("%w: %s", ErrUnknownCriticalNode, endpoint)
+		}
+	}
+
+	return nil
+}
+
+func (i *IncidentConfig) Validate() error {
+	switch i.Provider {
+	case "":
+		return nil
+	case PagerDutyProvider:
+		if i.RoutingKey == "" {
+			return ErrEmptyRoutingKey
+		}
+	case OpsgenieProvider:
+		if i.ApiKey == "" {
+			return ErrEmptyOpsgenieApiKey
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownIncidentProvider, i.Provider)
+	}
+
 	return nil
 }
 
@@ -131,3 +457,172 @@ func (a *AlertConfig) getOfflineDurationThreshold() time.Duration {
 func (a *AlertConfig) getOfflineBlocksThreshold() int {
 	return int(a.getOfflineDurationThreshold() / health.DefaultAvgSecondsPerBlock)
 }
+
+// getOfflineCriticalNodesThreshold defaults to a single node, so any offline node is alerted.
+func (a *AlertConfig) getOfflineCriticalNodesThreshold(total int) int {
+	if a.OfflineCriticalNodesThreshold.isZero() {
+		return 1
+	}
+	return a.OfflineCriticalNodesThreshold.nodes(total)
+}
+
+func (a *AlertConfig) getHeightRegressionTolerance() uint64 {
+	if a.HeightRegressionTolerance == 0 {
+		return DefaultHeightRegressionTolerance
+	}
+	return a.HeightRegressionTolerance
+}
+
+func (p *PoolConfig) getConcurrency() int {
+	if p.Concurrency <= 0 {
+		return DefaultPoolConcurrency
+	}
+	return p.Concurrency
+}
+
+func (p *PoolConfig) getConnectTimeout() time.Duration {
+	return parseOptionalDuration(p.ConnectTimeout, "connect timeout", DefaultPoolConnectTimeout)
+}
+
+func (p *PoolConfig) getRequestTimeout() time.Duration {
+	return parseOptionalDuration(p.RequestTimeout, "request timeout", DefaultPoolRequestTimeout)
+}
+
+func (p *PoolConfig) getHeightWaitTimeout() time.Duration {
+	return parseOptionalDuration(p.HeightWaitTimeout, "height wait timeout", DefaultPoolHeightWaitTimeout)
+}
+
+// getHashConcurrency defaults to the concurrency of the other requests.
+func (p *PoolConfig) getHashConcurrency() int {
+	if p.HashConcurrency <= 0 {
+		return p.getConcurrency()
+	}
+	return p.HashConcurrency
+}
+
+// getPingInterval returns 0 if pings are disabled.
+func (p *PoolConfig) getPingInterval() time.Duration {
+	return parseOptionalDuration(p.PingInterval, "ping interval", 0)
+}
+
+func (p *PoolConfig) getHashDeadline() time.Duration {
+	return parseOptionalDuration(p.HashDeadline, "hash deadline", DefaultPoolHashDeadline)
+}
+
+func (p *PauseConfig) getMaxDuration() time.Duration {
+	return parseOptionalDuration(p.MaxDuration, "pause max duration", DefaultPauseMaxDuration)
+}
+
+func (h *HashCacheConfig) getSize() int {
+	if h.Size <= 0 {
+		return DefaultHashCacheSize
+	}
+	return h.Size
+}
+
+func (h *HashCacheConfig) getTtl() time.Duration {
+	return parseOptionalDuration(h.Ttl, "hash cache TTL", DefaultHashCacheTtl)
+}
+
+func (s *StateConfig) getGapReportThreshold() time.Duration {
+	return parseOptionalDuration(s.GapReportThreshold, "gap report threshold", DefaultGapReportThreshold)
+}
+
+func (s *StateConfig) getLeaseDuration() time.Duration {
+	return parseOptionalDuration(s.LeaseDuration, "lease duration", DefaultLeaseDuration)
+}
+
+func (s *ScoreConfig) getWindow() time.Duration {
+	return parseOptionalDuration(s.Window, "score window", DefaultScoreWindow)
+}
+
+// getWeights returns the default weights unless any weight is set.
+func (s *ScoreConfig) getWeights() ScoreWeights {
+	if s.Weights == (ScoreWeights{}) {
+		return ScoreWeights{Uptime: DefaultUptimeWeight, Sync: DefaultSyncWeight, Agreement: DefaultAgreementWeight}
+	}
+	return s.Weights
+}
+
+// parseOptionalDuration parses an optional duration setting, using the default if it is unset or invalid.
+func parseOptionalDuration(value, name string, defaultValue time.Duration) time.Duration {
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		fmt.Printf("Error parsing %s: %v\n", name, err)
+		return defaultValue
+	}
+	return duration
+}
+
+func (a *ApiGatewayConfig) getCheckInterval() time.Duration {
+	return parseOptionalDuration(a.CheckInterval, "API gateway check interval", DefaultApiGatewayCheckInterval)
+}
+
+func (a *ApiGatewayConfig) getLatencyThreshold() time.Duration {
+	return parseOptionalDuration(a.LatencyThreshold, "API gateway latency threshold", DefaultApiGatewayLatencyThreshold)
+}
+
+func (a *ApiGatewayConfig) getAlertRepeatInterval() time.Duration {
+	return parseOptionalDuration(a.AlertRepeatInterval, "API gateway alert repeat interval", DefaultApiGatewayAlertRepeatInterval)
+}
+
+func (a *ApiGatewayConfig) getLagBlocksThreshold() uint64 {
+	if a.LagBlocksThreshold == 0 {
+		return DefaultApiGatewayLagBlocksThreshold
+	}
+	return a.LagBlocksThreshold
+}
+
+func (p *PeerListConfig) getCheckInterval() time.Duration {
+	return parseOptionalDuration(p.CheckInterval, "peer list check interval", DefaultPeerListCheckInterval)
+}
+
+func (p *PeerListConfig) getAlertRepeatInterval() time.Duration {
+	return parseOptionalDuration(p.AlertRepeatInterval, "peer list alert repeat interval", DefaultPeerListAlertRepeatInterval)
+}
+
+func (i *IncidentModeConfig) getAlertRepeatInterval() time.Duration {
+	return parseOptionalDuration(i.AlertRepeatInterval, "incident mode alert repeat interval", DefaultIncidentModeAlertRepeatInterval)
+}
+
+func (i *IncidentModeConfig) getUpdateInterval() time.Duration {
+	return parseOptionalDuration(i.UpdateInterval, "incident mode update interval", DefaultIncidentModeUpdateInterval)
+}
+
+func (i *IncidentModeConfig) getMaxDuration() time.Duration {
+	return parseOptionalDuration(i.MaxDuration, "incident mode max duration", DefaultIncidentModeMaxDuration)
+}
+
+func (i *IdentityConfig) getCheckInterval() time.Duration {
+	return parseOptionalDuration(i.CheckInterval, "identity check interval", DefaultIdentityCheckInterval)
+}
+
+func (i *IdentityConfig) getAlertRepeatInterval() time.Duration {
+	return parseOptionalDuration(i.AlertRepeatInterval, "identity alert repeat interval", DefaultIdentityAlertRepeatInterval)
+}
+
+func (l *LinkQualityConfig) getCheckInterval() time.Duration {
+	return parseOptionalDuration(l.CheckInterval, "link check interval", DefaultLinkCheckInterval)
+}
+
+func (l *LinkQualityConfig) getRttThreshold() time.Duration {
+	return parseOptionalDuration(l.RttThreshold, "link round trip threshold", DefaultLinkRttThreshold)
+}
+
+func (l *LinkQualityConfig) getMinThroughput() int {
+	if l.MinThroughput <= 0 {
+		return DefaultLinkMinThroughput
+	}
+	return l.MinThroughput
+}
+
+func (l *LinkQualityConfig) getProbeHashes() int {
+	if l.ProbeHashes <= 0 {
+		return DefaultLinkProbeHashes
+	}
+	return l.ProbeHashes
+}
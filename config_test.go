@@ -18,9 +18,19 @@ func TestLoadConfig(t *testing.T) {
 	assert.Equal(t, uint64(1), config.HeightCheckInterval)
 	assert.Equal(t, true, config.Notify)
 	assert.Equal(t, 5, config.AlertConfig.OutOfSyncBlocksThreshold)
-	assert.Equal(t, 5, config.AlertConfig.OutOfSyncCriticalNodesThreshold)
+	assert.Equal(t, 5, config.AlertConfig.OutOfSyncCriticalNodesThreshold.nodes(len(config.Nodes)))
 	assert.Equal(t, time.Duration(2*time.Hour), config.AlertConfig.getOfflineAlertRepeatInterval())
 	assert.Equal(t, time.Duration(5*time.Minute), config.AlertConfig.getOfflineDurationThreshold())
 	assert.Equal(t, time.Duration(2*time.Hour), config.AlertConfig.getSyncAlertRepeatInterval())
 	assert.Equal(t, time.Duration(10*time.Minute), config.AlertConfig.getStuckDurationThreshold())
-}
\ No newline at end of file
+}
+
+func TestNormalizeHeightCheckInterval(t *testing.T) {
+	config := &Config{HeightCheckInterval: 0}
+	config.normalize()
+	assert.Equal(t, uint64(DefaultHeightCheckInterval), config.HeightCheckInterval)
+
+	config = &Config{HeightCheckInterval: 5}
+	config.normalize()
+	assert.Equal(t, uint64(5), config.HeightCheckInterval)
+}
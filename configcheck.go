@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+)
+
+// ConfigWarning is a setting that loads fine but makes the alerts misbehave, e.g. never fire or repeat every cycle,
+// along with a suggested fix. Warnings are logged at startup rather than failing the config, as the checker still
+// runs with them.
+type ConfigWarning struct {
+	Setting string
+	Problem string
+	Hint    string
+}
+
+func (w ConfigWarning) String() string {
+	return fmt.Sprintf("%s: %s. Hint: %s", w.Setting, w.Problem, w.Hint)
+}
+
+// checkInterval is the expected time between two checks, one block time per block of the height check interval.
+func (c *Config) checkInterval() time.Duration {
+	interval := c.HeightCheckInterval
+	if interval == 0 {
+		interval = DefaultHeightCheckInterval
+	}
+
+	return time.Duration(interval) * health.DefaultAvgSecondsPerBlock
+}
+
+// alertConfigWarnings checks the alert config against the check cadence and the number of nodes.
+func (c *Config) alertConfigWarnings() []ConfigWarning {
+	var (
+		warnings []ConfigWarning
+		alerts   = c.AlertConfig
+		cycle    = c.checkInterval()
+	)
+
+	durations := []struct {
+		setting  string
+		value    string
+		fallback time.Duration
+		// tooShort describes what happens when the duration is shorter than a check cycle.
+		tooShort string
+		suggest  time.Duration
+	}{
+		{"offlineAlertRepeatInterval", alerts.OfflineAlertRepeatInterval, DefaultOfflineAlertRepeatInterval,
+			"the offline alert is repeated every check", DefaultOfflineAlertRepeatInterval},
+		{"syncAlertRepeatInterval", alerts.SyncAlertRepeatInterval, DefaultSyncAlertRepeatInterval,
+			"the sync alert is repeated every check", DefaultSyncAlertRepeatInterval},
+		{"offlineDurationThreshold", alerts.OfflineDurationThreshold, DefaultOfflineDurationThreshold,
+			"a node is reported offline after a single failed connection", DefaultOfflineDurationThreshold},
+		{"stuckDurationThreshold", alerts.StuckDurationThreshold, DefaultStuckDurationThreshold,
+			"the chain is reported stuck whenever one checkpoint is late", DefaultStuckDurationThreshold},
+		{"noPeersDurationThreshold", alerts.NoPeersDurationThreshold, DefaultNoPeersDurationThreshold,
+			"a single cycle without peers to compare hashes with is alerted", DefaultNoPeersDurationThreshold},
+	}
+
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		setting := "alertConfig." + d.setting
+
+		duration, err := time.ParseDuration(d.value)
+		switch {
+		case err != nil:
+			warnings = append(warnings, ConfigWarning{
+				Setting: setting,
+				Problem: fmt.Sprintf("%q is not a duration, so the default %s is used", d.value, d.fallback),
+				Hint:    fmt.Sprintf("use a Go duration such as %q", d.suggest.String()),
+			})
+		case duration <= 0:
+			warnings = append(warnings, ConfigWarning{
+				Setting: setting,
+				Problem: fmt.Sprintf("%s is not positive, so %s", d.value, d.tooShort),
+				Hint:    fmt.Sprintf("remove it to use the default %s, or set it to several check cycles", d.fallback),
+			})
+		case duration < cycle:
+			warnings = append(warnings, ConfigWarning{
+				Setting: setting,
+				Problem: fmt.Sprintf("%s is shorter than a check cycle of about %s, so %s", d.value, cycle, d.tooShort),
+				Hint:    fmt.Sprintf("set it to at least %s, e.g. %s", cycle, d.suggest),
+			})
+		}
+	}
+
+	if alerts.OutOfSyncBlocksThreshold <= 0 {
+		warnings = append(warnings, ConfigWarning{
+			Setting: "alertConfig.outOfSyncBlocksThreshold",
+			Problem: fmt.Sprintf("%d counts a node one block behind the checkpoint as out of sync", alerts.OutOfSyncBlocksThreshold),
+			Hint:    "set it to the lag worth alerting on, e.g. 5 blocks",
+		})
+	}
+
+	// New nodes are found against the ones saved with the state, so without a state every node is established.
+	if alerts.getProbationPeriod() > 0 && c.StateConfig.Backend == "" {
+		warnings = append(warnings, ConfigWarning{
+			Setting: "alertConfig.probationPeriod",
+			Problem: "no stateConfig is set, so added nodes are never found and no node is put on probation",
+			Hint:    "configure a stateConfig backend, or remove probationPeriod",
+		})
+	}
+
+	// Discovered nodes add to the configured ones, so a threshold above the configured count may still be reached.
+	if !c.Discover {
+		total := len(c.Nodes)
+		thresholds := []struct {
+			setting   string
+			threshold NodeThreshold
+			alert     string
+		}{
+			{"outOfSyncCriticalNodesThreshold", alerts.OutOfSyncCriticalNodesThreshold, "sync"},
+			{"offlineCriticalNodesThreshold", alerts.OfflineCriticalNodesThreshold, "offline"},
+		}
+
+		for _, t := range thresholds {
+			if nodes := t.threshold.nodes(total); nodes > total {
+				warnings = append(warnings, ConfigWarning{
+					Setting: "alertConfig." + t.setting,
+					Problem: fmt.Sprintf("%d nodes is more than the %d monitored, so the %s alert is never sent", nodes, total, t.alert),
+					Hint:    fmt.Sprintf("set it to at most %d, or to a percentage such as \"50%%\" that scales with the nodes", total),
+				})
+			}
+		}
+	}
+
+	return warnings
+}
+
+// logStartupSummary logs what the checker monitors and how often, followed by the config warnings.
+func (c *Config) logStartupSummary() {
+	log.Printf("Monitoring %d nodes (discovery %t) with %d API urls, checking every %d blocks (about %s)",
+		len(c.Nodes), c.Discover, len(c.ApiUrls), c.HeightCheckInterval, c.checkInterval())
+
+	for _, warning := range c.alertConfigWarnings() {
+		log.Printf("Config warning: %s", warning)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertConfigWarnings(t *testing.T) {
+	sample, err := LoadConfig("sample.config.json")
+	require.NoError(t, err)
+	config := *sample
+
+	settings := func(config Config) []string {
+		var settings []string
+		for _, warning := range config.alertConfigWarnings() {
+			settings = append(settings, warning.Setting)
+		}
+		return settings
+	}
+
+	t.Run("Sample config", func(t *testing.T) {
+		assert.Empty(t, config.alertConfigWarnings())
+	})
+
+	t.Run("Durations", func(t *testing.T) {
+		c := config
+		c.AlertConfig.OfflineAlertRepeatInterval = "2 hours"
+		c.AlertConfig.SyncAlertRepeatInterval = "0s"
+		c.AlertConfig.StuckDurationThreshold = "5s"
+
+		assert.Equal(t, []string{
+			"alertConfig.offlineAlertRepeatInterval",
+			"alertConfig.syncAlertRepeatInterval",
+			"alertConfig.stuckDurationThreshold",
+		}, settings(c))
+
+		// A longer height check interval makes every check cycle longer.
+		c = config
+		c.HeightCheckInterval = 30
+		assert.Equal(t, []string{"alertConfig.offlineDurationThreshold"}, settings(c))
+	})
+
+	t.Run("Thresholds", func(t *testing.T) {
+		c := config
+		c.AlertConfig.OutOfSyncBlocksThreshold = 0
+		assert.Equal(t, []string{"alertConfig.outOfSyncBlocksThreshold"}, settings(c))
+
+		c = config
+		c.Nodes = config.Nodes[:3]
+		c.AlertConfig.OfflineCriticalNodesThreshold = NodeThreshold{count: 4}
+		// Discovered nodes may still reach the thresholds.
+		assert.Empty(t, settings(c))
+
+		c.Discover = false
+		warnings := c.alertConfigWarnings()
+		require.Len(t, warnings, 2)
+		assert.Equal(t, "alertConfig.outOfSyncCriticalNodesThreshold", warnings[0].Setting)
+		assert.Contains(t, warnings[1].String(), "the offline alert is never sent")
+		assert.Contains(t, warnings[1].String(), "Hint: set it to at most 3")
+
+		c.AlertConfig.OutOfSyncCriticalNodesThreshold = NodeThreshold{percent: 100}
+		c.AlertConfig.OfflineCriticalNodesThreshold = NodeThreshold{percent: 50}
+		assert.Empty(t, settings(c))
+	})
+
+	t.Run("Probation without state", func(t *testing.T) {
+		c := config
+		c.AlertConfig.ProbationPeriod = "48h"
+		assert.Equal(t, []string{"alertConfig.probationPeriod"}, settings(c))
+
+		c.StateConfig = StateConfig{Backend: FileStateBackend, File: "state.json"}
+		assert.Empty(t, settings(c))
+	})
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+
+	"go-xpx-check-fork-util/internal/table"
+)
+
+var ErrInvalidDigestConfig = errors.New("invalid digest config")
+
+type (
+	// DigestCollector aggregates the statistics of the current reporting period and decides when the digest is due.
+	DigestCollector struct {
+		interval time.Duration
+		anchor   time.Time
+		chatID   int64
+
+		periodStart time.Time
+		nextDue     time.Time
+		startHeight uint64
+		endHeight   uint64
+		syncAlerts  int
+		forks       []uint64
+		nodes       map[string]*digestNodeStats
+	}
+
+	digestNodeStats struct {
+		node             health.NodeInfo
+		offlineIncidents int
+		offlineDuration  time.Duration
+		offlineSince     time.Time
+		lagSum           uint64
+		lagSamples       int
+		linkProbes       int
+		degradedProbes   int
+	}
+
+	DigestNode struct {
+		Node             health.NodeInfo
+		OfflineIncidents int
+		OfflineDuration  time.Duration
+		AverageLag       float64
+		LinkProbes       int
+		DegradedProbes   int
+	}
+
+	// DigestAlert is the periodic summary, sent even when nothing went wrong.
+	DigestAlert struct {
+		From        time.Time
+		To          time.Time
+		StartHeight uint64
+		EndHeight   uint64
+		SyncAlerts  int
+		Forks       []uint64
+		Nodes       []DigestNode
+	}
+)
+
+func (a DigestAlert) getType() AlertType {
+	return DigestAlertType
+}
+
+func (a DigestAlert) BlocksAdvanced() uint64 {
+	if a.EndHeight < a.StartHeight {
+		return 0
+	}
+
+	return a.EndHeight - a.StartHeight
+}
+
+func (a DigestAlert) createMessage() string {
+	var buf bytes.Buffer
+
+	const timeLayout = "2006-01-02 15:04"
+
+	fmt.Fprintf(&buf, "<b>📊 Summary </b>\n\n")
+	fmt.Fprintf(&buf, "%s - %s UTC\n\n", a.From.UTC().Format(timeLayout), a.To.UTC().Format(timeLayout))
+	fmt.Fprintf(&buf, "Blocks advanced: <b>%d</b> (%d → %d)\n", a.BlocksAdvanced(), a.StartHeight, a.EndHeight)
+	fmt.Fprintf(&buf, "Sync alerts: <b>%d</b>\n", a.SyncAlerts)
+
+	if len(a.Forks) == 0 {
+		fmt.Fprintf(&buf, "Forks: <b>none</b>")
+	} else {
+		heights := make([]string, 0, len(a.Forks))
+		for _, height := range a.Forks {
+			heights = append(heights, strconv.FormatUint(height, 10))
+		}
+		fmt.Fprintf(&buf, "Forks: <b>%d</b> at %s", len(a.Forks), strings.Join(heights, ", "))
+	}
+
+	if len(a.Nodes) == 0 {
+		return buf.String()
+	}
+
+	// The link column is only shown when link probing is enabled.
+	header := []string{"Node", "Offline", "Downtime", "Lag"}
+	withLinks := false
+	for _, node := range a.Nodes {
+		if node.LinkProbes > 0 {
+			withLinks = true
+			header = append(header, "Degraded")
+			break
+		}
+	}
+
+	var rows [][]string
+	for _, node := range a.Nodes {
+		row := []string{
+			nodeName(node.Node),
+			strconv.Itoa(node.OfflineIncidents),
+			node.OfflineDuration.Round(time.Minute).String(),
+			strconv.FormatFloat(node.AverageLag, 'f', 1, 64),
+		}
+
+		if withLinks {
+			row = append(row, fmt.Sprintf("%d/%d", node.DegradedProbes, node.LinkProbes))
+		}
+
+		rows = append(rows, row)
+	}
+
+	fmt.Fprintf(&buf, "\n\n<pre>%s</pre>", table.Render(rows, table.Options{Header: header}))
+
+	return buf.String()
+}
+
+func (a DigestAlert) redact(r *Redactor) Alert {
+	nodes := make([]DigestNode, 0, len(a.Nodes))
+	for _, node := range a.Nodes {
+		node.Node = r.node(node.Node)
+		nodes = append(nodes, node)
+	}
+
+	a.Nodes = nodes
+	return a
+}
+
+func parseDigestConfig(config DigestConfig) (interval time.Duration, anchor time.Time, err error) {
+	if config.Interval == "" {
+		return 0, time.Time{}, nil
+	}
+
+	interval, err = time.ParseDuration(config.Interval)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("%w: interval: %v", ErrInvalidDigestConfig, err)
+	}
+
+	if interval < time.Minute {
+		return 0, time.Time{}, fmt.Errorf("%w: interval must be at least 1m", ErrInvalidDigestConfig)
+	}
+
+	if config.Start != "" {
+		anchor, err = time.Parse(time.RFC3339, config.Start)
+		if err != nil {
+			return 0, time.Time{}, fmt.Errorf("%w: start: %v", ErrInvalidDigestConfig, err)
+		}
+	}
+
+	return interval, anchor, nil
+}
+
+// NewDigestCollector returns nil if no digest interval is configured.
+func NewDigestCollector(config DigestConfig, now time.Time) (*DigestCollector, error) {
+	interval, anchor, err := parseDigestConfig(config)
+	if err != nil || interval == 0 {
+		return nil, err
+	}
+
+	d := &DigestCollector{
+		interval: interval,
+		anchor:   anchor,
+		chatID:   config.ChatID,
+	}
+	d.reset(now)
+
+	return d, nil
+}
+
+func (d *DigestCollector) reset(now time.Time) {
+	d.periodStart = now
+	d.nextDue = d.nextDueAfter(now)
+	d.startHeight = d.endHeight
+	d.syncAlerts = 0
+	d.forks = nil
+
+	nodes := make(map[string]*digestNodeStats)
+	for key, stats := range d.nodes {
+		// Nodes that are still offline start the new period with an open incident.
+		if !stats.offlineSince.IsZero() {
+			nodes[key] = &digestNodeStats{node: stats.node, offlineIncidents: 1, offlineSince: now}
+		}
+	}
+	d.nodes = nodes
+}
+
+// nextDueAfter aligns the reports to the configured start time, so that e.g. a daily digest is always sent at the same hour.
+func (d *DigestCollector) nextDueAfter(now time.Time) time.Time {
+	if d.anchor.IsZero() {
+		return now.Add(d.interval)
+	}
+
+	if d.anchor.After(now) {
+		return d.anchor
+	}
+
+	periods := now.Sub(d.anchor)/d.interval + 1
+	return d.anchor.Add(periods * d.interval)
+}
+
+func (d *DigestCollector) nodeStats(node health.NodeInfo) *digestNodeStats {
+	key := node.IdentityKey.String()
+
+	stats, exists := d.nodes[key]
+	if !exists {
+		stats = &digestNodeStats{node: node}
+		d.nodes[key] = stats
+	}
+
+	return stats
+}
+
+func (d *DigestCollector) observeOffline(now time.Time, nodeInfos []*health.NodeInfo, failedConnectionsNodes map[string]*health.NodeInfo) {
+	if d == nil {
+		return
+	}
+
+	for _, info := range nodeInfos {
+		_, offline := failedConnectionsNodes[info.IdentityKey.String()]
+		if offline {
+			stats := d.nodeStats(*info)
+			if stats.offlineSince.IsZero() {
+				stats.offlineIncidents++
+				stats.offlineSince = now
+			}
+			continue
+		}
+
+		if stats, exists := d.nodes[info.IdentityKey.String()]; exists && !stats.offlineSince.IsZero() {
+			stats.offlineDuration += now.Sub(stats.offlineSince)
+			stats.offlineSince = time.Time{}
+		}
+	}
+}
+
+func (d *DigestCollector) observeHeights(checkpoint uint64, nodes map[health.NodeInfo]uint64) {
+	if d == nil {
+		return
+	}
+
+	for node, height := range nodes {
+		var lag uint64
+		if height < checkpoint {
+			lag = checkpoint - height
+		}
+
+		stats := d.nodeStats(node)
+		stats.lagSum += lag
+		stats.lagSamples++
+
+		if height > d.endHeight {
+			d.endHeight = height
+		}
+	}
+
+	if d.startHeight == 0 {
+		d.startHeight = d.endHeight
+	}
+}
+
+// observeLinks records the link probes of the configured nodes.
+func (d *DigestCollector) observeLinks(nodeInfos []*health.NodeInfo, probes map[string]LinkQuality) {
+	if d == nil {
+		return
+	}
+
+	for _, info := range nodeInfos {
+		quality, exists := probes[info.IdentityKey.String()]
+		if !exists {
+			continue
+		}
+
+		stats := d.nodeStats(*info)
+		stats.linkProbes++
+		if quality.Degraded {
+			stats.degradedProbes++
+		}
+	}
+}
+
+func (d *DigestCollector) observeSyncAlert() {
+	if d == nil {
+		return
+	}
+
+	d.syncAlerts++
+}
+
+func (d *DigestCollector) observeFork(height uint64) {
+	if d == nil {
+		return
+	}
+
+	d.forks = append(d.forks, height)
+}
+
+func (d *DigestCollector) due(now time.Time) bool {
+	return d != nil && !now.Before(d.nextDue)
+}
+
+// report summarizes the period ending now and starts a new one.
+func (d *DigestCollector) report(now time.Time) DigestAlert {
+	alert := DigestAlert{
+		From:        d.periodStart,
+		To:          now,
+		StartHeight: d.startHeight,
+		EndHeight:   d.endHeight,
+		SyncAlerts:  d.syncAlerts,
+		Forks:       d.forks,
+	}
+
+	for _, stats := range d.nodes {
+		node := DigestNode{
+			Node:             stats.node,
+			OfflineIncidents: stats.offlineIncidents,
+			OfflineDuration:  stats.offlineDuration,
+			LinkProbes:       stats.linkProbes,
+			DegradedProbes:   stats.degradedProbes,
+		}
+
+		if !stats.offlineSince.IsZero() {
+			node.OfflineDuration += now.Sub(stats.offlineSince)
+		}
+
+		if stats.lagSamples > 0 {
+			node.AverageLag = float64(stats.lagSum) / float64(stats.lagSamples)
+		}
+
+		alert.Nodes = append(alert.Nodes, node)
+	}
+
+	sort.Slice(alert.Nodes, func(i, j int) bool {
+		return nodeName(alert.Nodes[i].Node) < nodeName(alert.Nodes[j].Node)
+	})
+
+	d.reset(now)
+
+	return alert
+}
+
+// handleDigest sends the periodic summary once it is due.
+func (am *AlertManager) handleDigest() {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	now := time.Now()
+	if !am.digest.due(now) {
+		return
+	}
+
+	alert := am.digest.report(now)
+	log.Printf("Sending summary: %d blocks advanced, %d sync alerts, %d forks", alert.BlocksAdvanced(), alert.SyncAlerts, len(alert.Forks))
+
+	// Like the alerts, the summary is held back while alerts are paused or another instance holds the lease.
+	if !am.notifier.enabled || am.paused(alert) {
+		return
+	}
+
+	chatID := am.digest.chatID
+	if chatID == 0 {
+		chatID = am.notifier.chatID
+	}
+
+	if err := am.notifier.sendToChat(chatID, am.createMessage(am.redactor.redact(alert))); err != nil {
+		log.Println(err)
+	}
+}
+
+// observeDigestHeights records the lag of the configured nodes; discovered peers would swamp the summary.
+func (am *AlertManager) observeDigestHeights(checkpoint uint64, notReached, reached map[health.NodeInfo]uint64) {
+	if am.digest == nil {
+		return
+	}
+
+	nodes := make(map[health.NodeInfo]uint64)
+	for _, heights := range []map[health.NodeInfo]uint64{notReached, reached} {
+		for node, height := range heights {
+			if am.isConfiguredNode(node) {
+				nodes[node] = height
+			}
+		}
+	}
+
+	am.digest.observeHeights(checkpoint, nodes)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDigestConfig(t *testing.T) {
+	interval, _, err := parseDigestConfig(DigestConfig{})
+	require.NoError(t, err)
+	assert.Zero(t, interval)
+
+	interval, anchor, err := parseDigestConfig(DigestConfig{Interval: "24h", Start: "2024-09-01T09:00:00Z"})
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, interval)
+	assert.Equal(t, 9, anchor.Hour())
+
+	_, _, err = parseDigestConfig(DigestConfig{Interval: "daily"})
+	require.ErrorIs(t, err, ErrInvalidDigestConfig)
+
+	_, _, err = parseDigestConfig(DigestConfig{Interval: "10s"})
+	require.ErrorIs(t, err, ErrInvalidDigestConfig)
+
+	_, _, err = parseDigestConfig(DigestConfig{Interval: "24h", Start: "09:00"})
+	require.ErrorIs(t, err, ErrInvalidDigestConfig)
+}
+
+func TestDigestCollector(t *testing.T) {
+	start := time.Date(2024, 9, 1, 8, 30, 0, 0, time.UTC)
+
+	nodeInfos, err := parseNodes([]Node{
+		{Endpoint: "10.0.0.1:7900", IdentityKey: "AF7A80E9D6C2A4F5B46B90A1D16E95D4C1B8A3E8D5D1479D7C802C475D70A2E", FriendlyName: "nodeA"},
+		{Endpoint: "10.0.0.2:7900", IdentityKey: "BF7A80E9D6C2A4F5B46B90A1D16E95D4C1B8A3E8D5D1479D7C802C475D70A2E", FriendlyName: "nodeB"},
+	})
+	require.NoError(t, err)
+	nodeA, nodeB := nodeInfos[0], nodeInfos[1]
+
+	t.Run("Aligned to start", func(t *testing.T) {
+		digest, err := NewDigestCollector(DigestConfig{Interval: "24h", Start: "2024-08-01T09:00:00Z"}, start)
+		require.NoError(t, err)
+
+		assert.False(t, digest.due(start.Add(29*time.Minute)))
+		assert.True(t, digest.due(start.Add(30*time.Minute)))
+
+		digest.report(start.Add(30 * time.Minute))
+		assert.False(t, digest.due(start.Add(24*time.Hour)))
+		assert.True(t, digest.due(start.Add(24*time.Hour+30*time.Minute)))
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		digest, err := NewDigestCollector(DigestConfig{}, start)
+		require.NoError(t, err)
+		assert.Nil(t, digest)
+		assert.False(t, digest.due(start.Add(time.Hour)))
+
+		// Observations are ignored without a collector.
+		digest.observeFork(10)
+		digest.observeSyncAlert()
+	})
+
+	t.Run("Statistics", func(t *testing.T) {
+		digest, err := NewDigestCollector(DigestConfig{Interval: "1h"}, start)
+		require.NoError(t, err)
+
+		digest.observeHeights(100, map[health.NodeInfo]uint64{*nodeA: 100, *nodeB: 96})
+		digest.observeOffline(start.Add(10*time.Minute), nodeInfos, map[string]*health.NodeInfo{nodeB.IdentityKey.String(): nodeB})
+		digest.observeOffline(start.Add(15*time.Minute), nodeInfos, map[string]*health.NodeInfo{nodeB.IdentityKey.String(): nodeB})
+		digest.observeOffline(start.Add(20*time.Minute), nodeInfos, nil)
+		digest.observeOffline(start.Add(50*time.Minute), nodeInfos, map[string]*health.NodeInfo{nodeB.IdentityKey.String(): nodeB})
+		digest.observeHeights(150, map[health.NodeInfo]uint64{*nodeA: 150})
+		digest.observeSyncAlert()
+		digest.observeFork(120)
+
+		require.True(t, digest.due(start.Add(time.Hour)))
+		alert := digest.report(start.Add(time.Hour))
+
+		assert.Equal(t, uint64(50), alert.BlocksAdvanced())
+		assert.Equal(t, 1, alert.SyncAlerts)
+		assert.Equal(t, []uint64{120}, alert.Forks)
+		require.Len(t, alert.Nodes, 2)
+
+		assert.Equal(t, "nodeA", alert.Nodes[0].Node.FriendlyName)
+		assert.Zero(t, alert.Nodes[0].OfflineIncidents)
+		assert.Zero(t, alert.Nodes[0].AverageLag)
+
+		assert.Equal(t, "nodeB", alert.Nodes[1].Node.FriendlyName)
+		assert.Equal(t, 2, alert.Nodes[1].OfflineIncidents)
+		assert.Equal(t, 20*time.Minute, alert.Nodes[1].OfflineDuration)
+		assert.Equal(t, 4.0, alert.Nodes[1].AverageLag)
+
+		msg := alert.createMessage()
+		assert.Contains(t, msg, "Blocks advanced: <b>50</b>")
+		assert.Contains(t, msg, "Forks: <b>1</b> at 120")
+		assert.Contains(t, msg, "nodeB(10.0.0.2)")
+
+		// nodeB is still offline, so the next period starts with an open incident.
+		alert = digest.report(start.Add(2 * time.Hour))
+		assert.Zero(t, alert.BlocksAdvanced())
+		assert.Empty(t, alert.Forks)
+		require.Len(t, alert.Nodes, 1)
+		assert.Equal(t, 1, alert.Nodes[0].OfflineIncidents)
+		assert.Equal(t, time.Hour, alert.Nodes[0].OfflineDuration)
+		assert.Contains(t, alert.createMessage(), "Forks: <b>none</b>")
+	})
+
+	t.Run("Held back while paused", func(t *testing.T) {
+		bot, telegram := newTestBot(t)
+
+		am := newIncidentTestAlertManager(t, nil)
+		am.notifier = &Notifier{bot: bot, enabled: true, chatID: 1}
+		am.pause = NewPause(PauseConfig{})
+		am.digest, err = NewDigestCollector(DigestConfig{Interval: "1h"}, time.Now().Add(-2*time.Hour))
+		require.NoError(t, err)
+
+		_, err = am.pauseMonitoring("@ops", "upgrade", time.Hour)
+		require.NoError(t, err)
+		sent := len(telegram.replies)
+
+		am.handleDigest()
+		assert.Len(t, telegram.replies, sent)
+
+		// Nor is it sent by an instance standing by.
+		am.pause = NewPause(PauseConfig{})
+		am.standby.Store(true)
+		am.digest, err = NewDigestCollector(DigestConfig{Interval: "1h"}, time.Now().Add(-2*time.Hour))
+		require.NoError(t, err)
+
+		am.handleDigest()
+		assert.Len(t, telegram.replies, sent)
+
+		am.standby.Store(false)
+		am.digest, err = NewDigestCollector(DigestConfig{Interval: "1h"}, time.Now().Add(-2*time.Hour))
+		require.NoError(t, err)
+
+		am.handleDigest()
+		assert.Len(t, telegram.replies, sent+1)
+	})
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+)
+
+var (
+	ErrInvalidDiscoveryConfig = errors.New("invalid discovery config")
+
+	identityKeyPattern = regexp.MustCompile(`^[0-9A-Fa-f]{64}$`)
+)
+
+type (
+	// DiscoveryFilter decides which discovered peers join the pool. A rule is a CIDR matched against the host of the
+	// endpoint, an identity key, or otherwise a pattern matched against the friendly name.
+	DiscoveryFilter struct {
+		include        []discoveryRule
+		exclude        []discoveryRule
+		maxNodes       int
+		hashSampleSize int
+	}
+
+	discoveryRule struct {
+		network     *net.IPNet
+		identityKey string
+		namePattern string
+	}
+
+	// DiscoveryTracker remembers the discovered nodes seen so far, to report the ones that newly appear. The nodes
+	// seen in the first cycle are taken as known, so a restart does not report the whole network.
+	DiscoveryTracker struct {
+		seeded bool
+		known  map[string]struct{}
+	}
+
+	// DiscoveredNodesAlert lists discovered nodes that were not seen before, as candidates for the monitored list.
+	DiscoveredNodesAlert struct {
+		Nodes    []*health.NodeInfo
+		Redacted bool
+	}
+)
+
+func parseDiscoveryRule(value string) (discoveryRule, error) {
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return discoveryRule{}, fmt.Errorf("%w: %v", ErrInvalidDiscoveryConfig, err)
+		}
+		return discoveryRule{network: network}, nil
+	}
+
+	if identityKeyPattern.MatchString(value) {
+		return discoveryRule{identityKey: strings.ToUpper(value)}, nil
+	}
+
+	if _, err := path.Match(value, ""); err != nil {
+		return discoveryRule{}, fmt.Errorf("%w: name pattern %s: %v", ErrInvalidDiscoveryConfig, value, err)
+	}
+
+	return discoveryRule{namePattern: value}, nil
+}
+
+func parseDiscoveryRules(values []string) ([]discoveryRule, error) {
+	rules := make([]discoveryRule, 0, len(values))
+	for _, value := range values {
+		rule, err := parseDiscoveryRule(value)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// NewDiscoveryFilter returns nil if no rules, limit or hash sample are configured.
+func NewDiscoveryFilter(config DiscoveryConfig) (*DiscoveryFilter, error) {
+	if config.MaxNodes < 0 {
+		return nil, fmt.Errorf("%w: negative max nodes", ErrInvalidDiscoveryConfig)
+	}
+
+	if config.HashSampleSize < 0 {
+		return nil, fmt.Errorf("%w: negative hash sample size", ErrInvalidDiscoveryConfig)
+	}
+
+	include, err := parseDiscoveryRules(config.Include)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude, err := parseDiscoveryRules(config.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(include) == 0 && len(exclude) == 0 && config.MaxNodes == 0 && config.HashSampleSize == 0 {
+		return nil, nil
+	}
+
+	return &DiscoveryFilter{
+		include:        include,
+		exclude:        exclude,
+		maxNodes:       config.MaxNodes,
+		hashSampleSize: config.HashSampleSize,
+	}, nil
+}
+
+func (r discoveryRule) matches(info *health.NodeInfo) bool {
+	switch {
+	case r.network != nil:
+		host, _, err := net.SplitHostPort(info.Endpoint)
+		if err != nil {
+			host = info.Endpoint
+		}
+
+		// Endpoints announced by DNS name are not resolved, so they never match a CIDR.
+		ip := net.ParseIP(host)
+		return ip != nil && r.network.Contains(ip)
+	case r.identityKey != "":
+		return strings.EqualFold(info.IdentityKey.String(), r.identityKey)
+	default:
+		matched, _ := path.Match(r.namePattern, info.FriendlyName)
+		return matched
+	}
+}
+
+// allows reports whether a discovered node may join the pool. An exclude rule always wins; with include rules,
+// a node must match at least one of them.
+func (f *DiscoveryFilter) allows(info *health.NodeInfo) bool {
+	if f == nil {
+		return true
+	}
+
+	for _, rule := range f.exclude {
+		if rule.matches(info) {
+			return false
+		}
+	}
+
+	if len(f.include) == 0 {
+		return true
+	}
+
+	for _, rule := range f.include {
+		if rule.matches(info) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// limit returns the maximum number of discovered nodes in the pool, 0 meaning unlimited.
+func (f *DiscoveryFilter) limit() int {
+	if f == nil {
+		return 0
+	}
+	return f.maxNodes
+}
+
+// sampleSize returns the number of discovered nodes whose hashes are compared in every cycle, 0 meaning that all
+// discovered nodes take part in both the sync and the hash checks.
+func (f *DiscoveryFilter) sampleSize() int {
+	if f == nil {
+		return 0
+	}
+	return f.hashSampleSize
+}
+
+// NewDiscoveryTracker returns nil if new nodes are not reported.
+func NewDiscoveryTracker(config DiscoveryConfig) *DiscoveryTracker {
+	if !config.NotifyNew {
+		return nil
+	}
+
+	return &DiscoveryTracker{known: make(map[string]struct{})}
+}
+
+// update records the discovered nodes and returns the ones not seen before.
+func (t *DiscoveryTracker) update(nodes []*health.NodeInfo) []*health.NodeInfo {
+	var fresh []*health.NodeInfo
+	for _, info := range nodes {
+		if _, exists := t.known[info.IdentityKey.String()]; exists {
+			continue
+		}
+
+		t.known[info.IdentityKey.String()] = struct{}{}
+		fresh = append(fresh, info)
+	}
+
+	if !t.seeded {
+		t.seeded = true
+		return nil
+	}
+
+	sort.Slice(fresh, func(i, j int) bool {
+		return fresh[i].Endpoint < fresh[j].Endpoint
+	})
+
+	return fresh
+}
+
+func (a DiscoveredNodesAlert) getType() AlertType {
+	return DiscoveredNodesAlertType
+}
+
+func (a DiscoveredNodesAlert) createMessage() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "<b>🔎 New nodes discovered </b>\n\n")
+	fmt.Fprintf(&buf, "Not seen before (%d), consider adding them to the monitored nodes:\n<pre>", len(a.Nodes))
+	for _, node := range a.Nodes {
+		if a.Redacted {
+			fmt.Fprintln(&buf, node.Endpoint)
+			continue
+		}
+		fmt.Fprintf(&buf, "%s\n  %s\n", nodeName(*node), node.IdentityKey)
+	}
+	fmt.Fprintf(&buf, "</pre>")
+
+	return buf.String()
+}
+
+// redact leaves out the identity keys as well, as they identify the nodes just as well as the endpoints.
+func (a DiscoveredNodesAlert) redact(r *Redactor) Alert {
+	nodes := make([]*health.NodeInfo, 0, len(a.Nodes))
+	for _, node := range a.Nodes {
+		redacted := r.node(*node)
+		nodes = append(nodes, &redacted)
+	}
+
+	a.Nodes = nodes
+	a.Redacted = true
+	return a
+}
+
+// handleDiscoveredNodes reports the discovered nodes that newly appeared in the pool.
+func (am *AlertManager) handleDiscoveredNodes(nodes []*health.NodeInfo) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if am.discovery == nil {
+		return
+	}
+
+	fresh := am.discovery.update(nodes)
+	if len(fresh) == 0 {
+		return
+	}
+
+	log.Printf("Discovered %d new nodes", len(fresh))
+	am.sendUntracked(DiscoveredNodesAlert{Nodes: fresh})
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoveryFilter(t *testing.T) {
+	node := &health.NodeInfo{
+		IdentityKey:  getPublicKey("E8D4B7BEB2A531ECA8CC7FD93F79A4C828C24BE33F99CF7C5609FF5CE14605F4"),
+		Endpoint:     "10.1.2.3:7900",
+		FriendlyName: "api-1",
+	}
+
+	t.Run("Nothing configured", func(t *testing.T) {
+		filter, err := NewDiscoveryFilter(DiscoveryConfig{})
+		require.NoError(t, err)
+		assert.Nil(t, filter)
+		assert.True(t, filter.allows(node))
+		assert.Zero(t, filter.limit())
+	})
+
+	t.Run("Invalid rules", func(t *testing.T) {
+		_, err := NewDiscoveryFilter(DiscoveryConfig{Include: []string{"10.0.0.0/33"}})
+		require.ErrorIs(t, err, ErrInvalidDiscoveryConfig)
+
+		_, err = NewDiscoveryFilter(DiscoveryConfig{Exclude: []string{"api-["}})
+		require.ErrorIs(t, err, ErrInvalidDiscoveryConfig)
+
+		_, err = NewDiscoveryFilter(DiscoveryConfig{MaxNodes: -1})
+		require.ErrorIs(t, err, ErrInvalidDiscoveryConfig)
+
+		_, err = NewDiscoveryFilter(DiscoveryConfig{HashSampleSize: -1})
+		require.ErrorIs(t, err, ErrInvalidDiscoveryConfig)
+	})
+
+	t.Run("Rules", func(t *testing.T) {
+		tests := []struct {
+			rule    string
+			matches bool
+		}{
+			{"10.0.0.0/8", true},
+			{"192.168.0.0/16", false},
+			{"e8d4b7beb2a531eca8cc7fd93f79a4c828c24be33f99cf7c5609ff5ce14605f4", true},
+			{"A8D4B7BEB2A531ECA8CC7FD93F79A4C828C24BE33F99CF7C5609FF5CE14605F4", false},
+			{"api-*", true},
+			{"peer-*", false},
+		}
+
+		for _, test := range tests {
+			rule, err := parseDiscoveryRule(test.rule)
+			require.NoError(t, err)
+			assert.Equal(t, test.matches, rule.matches(node), test.rule)
+		}
+	})
+
+	t.Run("CIDR does not match DNS names", func(t *testing.T) {
+		rule, err := parseDiscoveryRule("10.0.0.0/8")
+		require.NoError(t, err)
+		assert.False(t, rule.matches(&health.NodeInfo{Endpoint: "api.example.com:7900"}))
+	})
+
+	t.Run("Exclude wins", func(t *testing.T) {
+		filter, err := NewDiscoveryFilter(DiscoveryConfig{Include: []string{"10.0.0.0/8"}, Exclude: []string{"api-*"}})
+		require.NoError(t, err)
+		assert.False(t, filter.allows(node))
+
+		filter, err = NewDiscoveryFilter(DiscoveryConfig{Include: []string{"peer-*"}})
+		require.NoError(t, err)
+		assert.False(t, filter.allows(node))
+
+		filter, err = NewDiscoveryFilter(DiscoveryConfig{Exclude: []string{"peer-*"}})
+		require.NoError(t, err)
+		assert.True(t, filter.allows(node))
+	})
+}
+
+func TestDiscoveryTracker(t *testing.T) {
+	assert.Nil(t, NewDiscoveryTracker(DiscoveryConfig{}))
+
+	nodeA := newTestNode(t, 10, sdk.Hash{1}).info()
+	nodeB := newTestNode(t, 10, sdk.Hash{1}).info()
+	nodeC := newTestNode(t, 10, sdk.Hash{1}).info()
+
+	tracker := NewDiscoveryTracker(DiscoveryConfig{NotifyNew: true})
+
+	// The first cycle only seeds the known nodes.
+	assert.Empty(t, tracker.update([]*health.NodeInfo{nodeA}))
+	assert.Empty(t, tracker.update([]*health.NodeInfo{nodeA}))
+	assert.Equal(t, []*health.NodeInfo{nodeB}, tracker.update([]*health.NodeInfo{nodeA, nodeB}))
+
+	// A node that drops out and comes back is not new.
+	assert.Empty(t, tracker.update([]*health.NodeInfo{nodeA}))
+	assert.Equal(t, []*health.NodeInfo{nodeC}, tracker.update([]*health.NodeInfo{nodeA, nodeB, nodeC}))
+}
+
+func TestDiscoveredNodesAlert(t *testing.T) {
+	node := newTestNode(t, 10, sdk.Hash{1}).info()
+	node.FriendlyName = "api-1"
+
+	message := DiscoveredNodesAlert{Nodes: []*health.NodeInfo{node}}.createMessage()
+	assert.Contains(t, message, "api-1")
+	assert.Contains(t, message, node.IdentityKey.String())
+
+	redacted := DiscoveredNodesAlert{Nodes: []*health.NodeInfo{node}}.redact(NewRedactor(FriendlyPrivacyMode, nil))
+	message = redacted.createMessage()
+	assert.NotContains(t, message, node.Endpoint)
+	assert.NotContains(t, message, node.IdentityKey.String())
+}
+
+func TestNodePoolDiscovery(t *testing.T) {
+	configured := newTestNode(t, 10, sdk.Hash{1})
+	excluded := newTestNode(t, 10, sdk.Hash{1})
+	configured.peers = append(configured.peers, excluded)
+	for i := 0; i < 3; i++ {
+		configured.peers = append(configured.peers, newTestNode(t, 10, sdk.Hash{1}))
+	}
+
+	pool := newTestNodePool(t)
+	filter, err := NewDiscoveryFilter(DiscoveryConfig{
+		Include:  []string{"127.0.0.0/8"},
+		Exclude:  []string{excluded.keyPair.PublicKey.String()},
+		MaxNodes: 2,
+	})
+	require.NoError(t, err)
+	pool.discovery = filter
+
+	nodeInfos := []*health.NodeInfo{configured.info()}
+	_, err = pool.ConnectToNodes(nodeInfos, true)
+	require.NoError(t, err)
+	assert.Len(t, pool.connections(), 3)
+
+	discovered := pool.discoveredNodes(nodeInfos)
+	require.Len(t, discovered, 2)
+	for _, info := range discovered {
+		assert.NotEqual(t, excluded.info().Endpoint, info.Endpoint)
+		assert.NotEqual(t, configured.info().Endpoint, info.Endpoint)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+const (
+	BotApiKeyEnv   = "FORKCHECK_BOT_API_KEY"
+	ChatIdEnv      = "FORKCHECK_CHAT_ID"
+	SecretsFileEnv = "FORKCHECK_SECRETS_FILE"
+)
+
+var (
+	ErrUndefinedEnvVar    = errors.New("undefined environment variable")
+	ErrInvalidEnvOverride = errors.New("invalid environment override")
+	ErrInvalidSecretsFile = errors.New("invalid secrets file")
+
+	envReferencePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+)
+
+// resolveConfig merges the secrets file into the raw config and expands the environment variable references in its
+// string values. The secrets file is named by FORKCHECK_SECRETS_FILE or the secretsFile field, relative to the config.
+func resolveConfig(fileName string, content []byte) ([]byte, error) {
+	raw, err := decodeRaw(content)
+	if err != nil {
+		return nil, err
+	}
+
+	secretsFile := os.Getenv(SecretsFileEnv)
+	if secretsFile == "" {
+		secretsFile, _ = raw["secretsFile"].(string)
+		if secretsFile != "" && !filepath.IsAbs(secretsFile) {
+			secretsFile = filepath.Join(filepath.Dir(fileName), secretsFile)
+		}
+	}
+
+	if secretsFile != "" {
+		secrets, err := os.ReadFile(secretsFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w '%s': %v", ErrInvalidSecretsFile, secretsFile, err)
+		}
+
+		rawSecrets, err := decodeRaw(secrets)
+		if err != nil {
+			return nil, fmt.Errorf("%w '%s': %v", ErrInvalidSecretsFile, secretsFile, err)
+		}
+
+		mergeConfig(raw, rawSecrets)
+	}
+
+	expanded, err := expandEnv(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(expanded)
+}
+
+// decodeRaw decodes a JSON object, keeping numbers as json.Number so that 64-bit values such as the checkpoint and
+// chat IDs survive encoding it again.
+func decodeRaw(content []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var raw map[string]interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	return raw, nil
+}
+
+// mergeConfig overwrites the values in dst with those in src, descending into objects present in both.
+func mergeConfig(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcObject, srcIsObject := value.(map[string]interface{})
+		dstObject, dstIsObject := dst[key].(map[string]interface{})
+
+		if srcIsObject && dstIsObject {
+			mergeConfig(dstObject, srcObject)
+			continue
+		}
+
+		dst[key] = value
+	}
+}
+
+// expandEnv replaces ${VAR} references in string values with the value of the environment variable.
+// Referencing an undefined variable is an error rather than silently producing an empty value.
+func expandEnv(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		var missing string
+		expanded := envReferencePattern.ReplaceAllStringFunc(v, func(reference string) string {
+			name := envReferencePattern.FindStringSubmatch(reference)[1]
+			value, exists := os.LookupEnv(name)
+			if !exists && missing == "" {
+				missing = name
+			}
+			return value
+		})
+
+		if missing != "" {
+			return nil, fmt.Errorf("%w: %s", ErrUndefinedEnvVar, missing)
+		}
+
+		return expanded, nil
+	case map[string]interface{}:
+		for key, item := range v {
+			expanded, err := expandEnv(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = expanded
+		}
+	case []interface{}:
+		for i, item := range v {
+			expanded, err := expandEnv(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+	}
+
+	return value, nil
+}
+
+// applyEnvOverrides replaces the Telegram settings with FORKCHECK_BOT_API_KEY and FORKCHECK_CHAT_ID, if set.
+func (c *Config) applyEnvOverrides() error {
+	if botApiKey := os.Getenv(BotApiKeyEnv); botApiKey != "" {
+		c.BotAPIKey = botApiKey
+	}
+
+	if chatID := os.Getenv(ChatIdEnv); chatID != "" {
+		id, err := strconv.ParseInt(chatID, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidEnvOverride, ChatIdEnv, err)
+		}
+		c.ChatID = id
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestConfig writes the sample config with the given replacements applied into a temporary directory.
+func writeTestConfig(t *testing.T, replacements ...string) string {
+	content, err := os.ReadFile("sample.config.json")
+	require.NoError(t, err)
+
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	content = []byte(strings.NewReplacer(replacements...).Replace(string(content)))
+	require.NoError(t, os.WriteFile(configFile, content, 0644))
+
+	return configFile
+}
+
+func TestConfigEnv(t *testing.T) {
+	t.Run("Overrides", func(t *testing.T) {
+		t.Setenv(BotApiKeyEnv, "123:override")
+		t.Setenv(ChatIdEnv, "-1001")
+
+		config, err := LoadConfig("sample.config.json")
+		require.NoError(t, err)
+		assert.Equal(t, "123:override", config.BotAPIKey)
+		assert.Equal(t, int64(-1001), config.ChatID)
+
+		t.Setenv(ChatIdEnv, "chat")
+		_, err = LoadConfig("sample.config.json")
+		require.ErrorIs(t, err, ErrInvalidEnvOverride)
+	})
+
+	t.Run("Expansion", func(t *testing.T) {
+		configFile := writeTestConfig(t, `"<TELEGRAM_BOT_API_KEY>"`, `"${TEST_BOT_ID}:${TEST_BOT_SECRET}"`)
+
+		t.Setenv("TEST_BOT_ID", "123")
+		t.Setenv("TEST_BOT_SECRET", "secret")
+		config, err := LoadConfig(configFile)
+		require.NoError(t, err)
+		assert.Equal(t, "123:secret", config.BotAPIKey)
+
+		os.Unsetenv("TEST_BOT_SECRET")
+		_, err = LoadConfig(configFile)
+		require.ErrorIs(t, err, ErrUndefinedEnvVar)
+		assert.Contains(t, err.Error(), "TEST_BOT_SECRET")
+	})
+
+	t.Run("Secrets file", func(t *testing.T) {
+		configFile := writeTestConfig(t, `"notify"`, `"secretsFile": "secrets.json", "notify"`)
+
+		secrets := `{"botApiKey": "123:secret", "alertConfig": {"privacyMode": "friendly"}}`
+		require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(configFile), "secrets.json"), []byte(secrets), 0600))
+
+		config, err := LoadConfig(configFile)
+		require.NoError(t, err)
+		assert.Equal(t, "123:secret", config.BotAPIKey)
+		assert.Equal(t, FriendlyPrivacyMode, config.AlertConfig.PrivacyMode)
+		// Objects are merged rather than replaced.
+		assert.Equal(t, 5, config.AlertConfig.OutOfSyncBlocksThreshold)
+
+		t.Setenv(SecretsFileEnv, filepath.Join(t.TempDir(), "missing.json"))
+		_, err = LoadConfig(configFile)
+		require.ErrorIs(t, err, ErrInvalidSecretsFile)
+	})
+
+	t.Run("Large numbers", func(t *testing.T) {
+		configFile := writeTestConfig(t, `"checkpoint": 0`, `"checkpoint": 18014398509481985`, `"notify"`, `"secretsFile": "secrets.json", "notify"`)
+
+		// Values above 2^53 do not fit a float64 and must not be rounded when the config is re-encoded.
+		secrets := `{"chatID": -9007199254740993}`
+		require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(configFile), "secrets.json"), []byte(secrets), 0600))
+
+		config, err := LoadConfig(configFile)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(18014398509481985), config.Checkpoint)
+		assert.Equal(t, int64(-9007199254740993), config.ChatID)
+	})
+}
@@ -4,13 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"math"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
 	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
-	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health/packets"
-	crypto "github.com/proximax-storage/go-xpx-crypto"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -19,12 +20,42 @@ type ForkChecker struct {
 	cfg            Config
 	alertManager   *AlertManager
 	catapultClient *sdk.Client
-	nodePool       *health.NodeHealthCheckerPool
+	nodePool       *NodePool
 	checkpoint     uint64
+	audit          *AuditLog
+	status         *StatusServer
+	gateways       *ApiGatewayMonitor
+	peerLists      *PeerListMonitor
+	anchors        *AnchorMonitor
+	scores         *ScoreTracker
+	state          StateStore
+
+	// Highest height reported by the nodes in the last check cycle.
+	peerHeight uint64
+	// Downtime found in the restored state, reported after the first check cycle.
+	gap *CoverageGap
+	// lease is held by a long-lived instance with a state store, so that a duplicate one stands by.
+	lease *InstanceLease
+	// stateMu serializes saving the state with releasing the lease on shutdown, after which released is set.
+	stateMu  sync.Mutex
+	released bool
+	// stalled is set while the chain is stuck at the checkpoint.
+	stalled bool
+	// burstUntil is the height up to which every height is checked after a stall, 0 outside of burst mode.
+	burstUntil uint64
+	// lastChecked is the last height whose hashes were compared since the start, 0 before the first one.
+	lastChecked uint64
 }
 
 func NewForkChecker(config Config) (*ForkChecker, error) {
-	fc := &ForkChecker{cfg: config}
+	fc := &ForkChecker{
+		cfg:    config,
+		audit:  NewAuditLog(config.AuditLogFile),
+		status: &StatusServer{},
+	}
+
+	fc.cfg.logStartupSummary()
+	fc.cfg.warnIncompatibleApiUrls()
 
 	if err := fc.initCatapultClient(); err != nil {
 		return nil, fmt.Errorf("failed to initialize catapult client: %v", err)
@@ -34,6 +65,22 @@ func NewForkChecker(config Config) (*ForkChecker, error) {
 		return nil, fmt.Errorf("failed to initialize alert manager: %v", err)
 	}
 
+	fc.scores = NewScoreTracker(fc.cfg.ScoreConfig, fc.alertManager.nodeInfos)
+	fc.anchors = NewAnchorMonitor(fc.cfg.AnchorConfig)
+
+	if fc.cfg.ApiGatewayConfig.Enabled {
+		fc.gateways = NewApiGatewayMonitor(fc.cfg.ApiGatewayConfig, fc.cfg.apiUrlsFor(ChainApiCapability), fc.alertManager)
+	}
+
+	if fc.cfg.PeerListConfig.Enabled {
+		nodes, err := criticalNodes(fc.alertManager.nodeInfos, fc.cfg.PeerListConfig.CriticalNodes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize peer list monitor: %v", err)
+		}
+
+		fc.peerLists = NewPeerListMonitor(fc.cfg.PeerListConfig, fc.cfg.apiUrlsFor(PeersApiCapability), nodes, fc.alertManager)
+	}
+
 	if err := fc.initPool(); err != nil {
 		return nil, fmt.Errorf("failed to initialize node health checker pool: %v", err)
 	}
@@ -42,6 +89,10 @@ func NewForkChecker(config Config) (*ForkChecker, error) {
 		return nil, fmt.Errorf("failed to initialize checkpoint: %v", err)
 	}
 
+	if err := fc.initState(); err != nil {
+		return nil, fmt.Errorf("failed to restore state: %v", err)
+	}
+
 	return fc, nil
 }
 
@@ -62,16 +113,24 @@ func (fc *ForkChecker) initCheckpoint() error {
 }
 
 func (fc *ForkChecker) initPool() error {
-	clientKeyPair, err := crypto.NewRandomKeyPair()
+	mode, clientKeyPair, err := parsePoolSecurity(fc.cfg.PoolConfig)
 	if err != nil {
-		return fmt.Errorf("error generating random keypair: %s", err)
+		return err
 	}
 
-	fc.nodePool = health.NewNodeHealthCheckerPool(
+	log.Printf("Connecting to nodes as %s", clientKeyPair.PublicKey)
+
+	fc.nodePool = NewNodePool(
 		clientKeyPair,
-		packets.NoneConnectionSecurity,
-		math.MaxInt,
+		mode,
+		fc.cfg.PoolConfig,
 	)
+	fc.nodePool.hashes = NewHashCache(fc.cfg.HashCacheConfig)
+
+	fc.nodePool.discovery, err = NewDiscoveryFilter(fc.cfg.DiscoveryConfig)
+	if err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -82,95 +141,397 @@ func (fc *ForkChecker) initAlertManager() error {
 		return fmt.Errorf("error parsing node info: %v", err)
 	}
 
-	bot, err := tgbotapi.NewBotAPI(fc.cfg.BotAPIKey)
+	templates, err := loadTemplates(fc.cfg.AlertConfig.Templates)
+	if err != nil {
+		return fmt.Errorf("error loading alert templates: %v", err)
+	}
+
+	maintenance, err := NewMaintenanceSchedule(fc.cfg.MaintenanceWindows)
+	if err != nil {
+		return fmt.Errorf("error parsing maintenance windows: %v", err)
+	}
+
+	digest, err := NewDigestCollector(fc.cfg.DigestConfig, time.Now())
+	if err != nil {
+		return fmt.Errorf("error parsing digest config: %v", err)
+	}
+
+	identities, err := NewIdentityMonitor(fc.cfg.IdentityConfig, fc.catapultClient)
+	if err != nil {
+		return fmt.Errorf("error initializing identity monitor: %v", err)
+	}
+
+	addresses, err := NewAddressMonitor(fc.cfg.Nodes, nodeInfos)
 	if err != nil {
-		return fmt.Errorf("failed to initialize telegram bot: %w", err)
+		return fmt.Errorf("error parsing allowed IPs: %v", err)
 	}
 
-	bot.Debug = false
+	notifier := &Notifier{
+		chatID:  fc.cfg.ChatID,
+		enabled: fc.cfg.Notify,
+	}
+
+	if fc.cfg.AlertConfig.ThreadIncidents {
+		notifier.threads = make(map[AlertType]int)
+	}
+
+	pager := newPager(fc.cfg.IncidentConfig)
+
+	// A dry run logs every alert that would be sent, so it needs neither a working bot nor the pager.
+	if fc.cfg.dryRun {
+		log.Printf("Dry run: alerts are logged instead of being sent")
+		notifier.enabled = true
+		notifier.dryRun = true
+		pager = nil
+	} else {
+		notifier.bot, err = tgbotapi.NewBotAPI(fc.cfg.BotAPIKey)
+		if err != nil {
+			return fmt.Errorf("failed to initialize telegram bot: %w", err)
+		}
+
+		notifier.bot.Debug = false
+	}
 
 	fc.alertManager = &AlertManager{
 		config:           fc.cfg.AlertConfig,
 		lastAlertTimes:   make(map[AlertType]time.Time),
 		offlineNodeStats: make(map[string]NodeStatus),
 		nodeInfos:        nodeInfos,
-		notifier: &Notifier{
-			bot:     bot,
-			chatID:  fc.cfg.ChatID,
-			enabled: fc.cfg.Notify,
-		},
+		pager:            pager,
+		openIncidents:    make(map[AlertType]string),
+		templates:        templates,
+		maintenance:      maintenance,
+		hashStreaks:      NewHashStreakTracker(),
+		redactor:         NewRedactor(fc.cfg.AlertConfig.PrivacyMode, nodeInfos),
+		digest:           digest,
+		incidentMode:     NewIncidentMode(fc.cfg.IncidentModeConfig),
+		identities:       identities,
+		addresses:        addresses,
+		links:            NewLinkMonitor(fc.cfg.LinkQualityConfig),
+		pause:            NewPause(fc.cfg.PauseConfig),
+		discovery:        NewDiscoveryTracker(fc.cfg.DiscoveryConfig),
+		heights:          NewHeightTracker(fc.cfg.AlertConfig.getHeightRegressionTolerance()),
+		noPeers:          NewNoPeersTracker(fc.cfg.AlertConfig.getNoPeersDurationThreshold()),
+		probation:        NewProbation(fc.cfg.AlertConfig.getProbationPeriod()),
+		timedOffline:     fc.cfg.PoolConfig.getPingInterval() > 0,
+		notifier:         notifier,
 	}
 
 	return nil
 }
 
 func (fc *ForkChecker) initCatapultClient() error {
-	var conf *sdk.Config
-	var err error
-
-	for _, url := range fc.cfg.ApiUrls {
-		conf, err = sdk.NewConfig(context.Background(), []string{url})
-		if err == nil {
-			log.Printf("Initialized client on URL: %s", url)
-			fc.catapultClient = sdk.NewClient(nil, conf)
-			return nil
-		}
+	urls := fc.cfg.apiUrlsFor(ChainApiCapability)
+	if len(urls) == 0 {
+		return fmt.Errorf("no API url serves the %s capability", ChainApiCapability)
+	}
+
+	conf, err := fc.cfg.newClientConfig(urls)
+	if err != nil {
+		return err
+	}
+
+	fc.catapultClient = sdk.NewClient(nil, conf)
+	return nil
+}
+
+// reloadConfig re-reads the config file and applies the settings that can be changed without a restart.
+func (fc *ForkChecker) reloadConfig() {
+	config, err := LoadConfig(fc.cfg.fileName)
+	if err != nil {
+		log.Printf("failed to reload config: %v", err)
+		return
+	}
+
+	if err := fc.setHeightCheckInterval(config.HeightCheckInterval, "config reload"); err != nil {
+		log.Printf("failed to apply reloaded config: %v", err)
+	}
+}
+
+func (fc *ForkChecker) setHeightCheckInterval(interval uint64, actor string) error {
+	if interval == 0 {
+		return ErrZeroHeightCheckInterval
 	}
 
-	return fmt.Errorf("all provided URLs failed: %v", err)
+	if interval == fc.cfg.HeightCheckInterval {
+		return nil
+	}
+
+	fc.audit.Record(actor, "set heightCheckInterval", fmt.Sprintf("%d -> %d", fc.cfg.HeightCheckInterval, interval))
+	fc.cfg.HeightCheckInterval = interval
+
+	return nil
 }
 
 func (fc *ForkChecker) Start() error {
+	// The config is reloaded on SIGHUP, between iterations so the loop never sees a half-applied change.
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+
+	if fc.state != nil {
+		fc.lease = newInstanceLease(fc.cfg.StateConfig.InstanceID, time.Now())
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			sig := <-stop
+			log.Printf("Received %s, releasing the instance lease", sig)
+			if err := fc.releaseLease(); err != nil {
+				log.Printf("failed to release the instance lease: %v", err)
+			}
+			os.Exit(0)
+		}()
+	}
+
+	// A duplicate instance standing by sends no test message either.
+	if fc.checkLease(time.Now()) && !fc.cfg.SelfTestConfig.Disabled {
+		if err := fc.alertManager.selfTest(fc.cfg.SelfTestConfig); err != nil {
+			return err
+		}
+	}
+
+	if fc.cfg.StatusAddress != "" {
+		fc.status.alertManager = fc.alertManager
+		fc.status.apiToken = fc.cfg.PauseConfig.ApiToken
+		fc.status.nodeInfos = fc.alertManager.nodeInfos
+		go fc.status.listen(fc.cfg.StatusAddress)
+	}
+
+	if fc.gateways != nil {
+		go fc.gateways.Run(context.Background())
+	}
+
+	if fc.peerLists != nil {
+		go fc.peerLists.Run(context.Background())
+	}
+
+	if pings := NewPingMonitor(fc.cfg.PoolConfig, fc.nodePool, fc.alertManager); pings != nil {
+		go pings.Run(context.Background())
+	}
+
+	if fc.cfg.BotCommands && !fc.cfg.dryRun {
+		go NewCommandListener(fc.alertManager.notifier.bot, fc.cfg.ChatID, fc.alertManager).Run(context.Background())
+	}
+
 	for {
-		failedConnectionsNodes, err := fc.nodePool.ConnectToNodes(fc.alertManager.nodeInfos, fc.cfg.Discover)
-		if err != nil {
-			log.Printf("error connecting to nodes: %s", err)
+		select {
+		case <-reload:
+			fc.reloadConfig()
+		default:
+		}
+
+		// A standby instance keeps polling the lease until the active one stops renewing it.
+		if !fc.checkLease(time.Now()) {
+			fc.status.update(fc.buildStatus())
+			time.Sleep(pausePollInterval)
 			continue
 		}
-		
-		// Trigger alert if offline nodes include bootstrap nodes or API nodes.
-		fc.alertManager.handleOfflineAlert(failedConnectionsNodes)
 
-		notReached, reached, err := fc.nodePool.WaitHeight(fc.checkpoint)
-		if err != nil {
-			log.Printf("error waiting for connected nodes to reach %d height: %s", fc.checkpoint, err)
+		fc.alertManager.handlePause()
+		if fc.alertManager.pause.checksPaused(time.Now()) {
+			fc.renewLease(time.Now())
+			fc.status.update(fc.buildStatus())
+			time.Sleep(pausePollInterval)
 			continue
 		}
-		
-		// Trigger alert if the following conditions are met:
-		//   - No nodes have synced to the checkpoint height for X minutes (stuck alert) 
-		//   - Among the out-of-sync nodes, there are Y or more bootstrap or API nodes that are Z blocks or more behind the chain's highest height.
-		// X, Y, Z values are configurable in the config.json file:
-		//   X - stuckDurationThreshold
-		//   Y - outOfSyncCriticalNodesThreshold
-		//   Z - outOfSyncBlocksThreshold
-		fc.alertManager.handleSyncAlert(fc.checkpoint, notReached, reached)
-
-		// Skip incrementing checkpoint if the chain is stuck.
-		if len(reached) == 0 {
-			log.Printf("Chain is stuck! No nodes  reached height: %d", fc.checkpoint)
+
+		fc.runCycle()
+
+		if fc.gap != nil {
+			go fc.reportCoverageGap(*fc.gap, fc.peerHeight)
+			fc.gap = nil
+		}
+
+		status := fc.buildStatus()
+		fc.status.update(status)
+		fc.publishScores(status.Scores)
+
+		// The lease is checked again right before saving, as another instance that saved since the last check
+		// would otherwise be overwritten every cycle without either noticing the other.
+		if !fc.checkLease(time.Now()) {
 			continue
 		}
 
-		log.Printf("Checking block hash at %d height", fc.checkpoint)
-		hashes, err := fc.nodePool.CompareHashes(fc.checkpoint)
+		if err := fc.saveState(); err != nil {
+			log.Printf("failed to save state: %v", err)
+		}
+	}
+}
 
-		// Trigger alert if the hashes of the last confirmed block are not the same.
-		if err != nil {
-			switch err {
-			case health.ErrHashesAreNotTheSame:
-				log.Printf("hashes are not the same at %d height: %v", fc.checkpoint, hashes)
-				fc.alertManager.handleHashAlert(fc.checkpoint, hashes)
-			case health.ErrNoConnectedPeers:
-				log.Printf("error comparing hashes for connected nodes at %d height: %s", fc.checkpoint, err)
-				continue
-			default:
-				log.Printf("unexpected error when comparing hashes at %d height: %s", fc.checkpoint, err)
-				continue
-			}
+// checkOnce runs check cycles until the checkpoint has caught up with the nodes or the context expires,
+// then saves the state for the next run. It is meant for scheduled runs rather than a long-lived process.
+func (fc *ForkChecker) checkOnce(ctx context.Context) error {
+	for ctx.Err() == nil {
+		checkpoint := fc.checkpoint
+		fc.checkCycle(ctx)
+
+		// Stop rather than wait for blocks that have not been produced yet.
+		if fc.checkpoint == checkpoint || fc.checkpoint > fc.peerHeight {
+			break
 		}
+	}
+
+	return fc.saveState()
+}
+
+// runOnce runs a single check cycle for the -once flag and saves the state, so that scheduled runs continue from
+// where the previous one stopped.
+func (fc *ForkChecker) runOnce() (*CheckReport, error) {
+	report := fc.checkCycle(context.Background())
+	return report, fc.saveState()
+}
+
+// checkCycle runs a single pass of the checks, advancing the checkpoint once the nodes have agreed on its hash.
+// A cancelled cycle stops after the current step, without alerting on the failures caused by its connections being
+// closed.
+func (fc *ForkChecker) checkCycle(ctx context.Context) *CheckReport {
+	report := &CheckReport{Time: time.Now(), Checkpoint: fc.checkpoint}
+
+	fc.alertManager.handleMaintenanceWindows()
+	fc.alertManager.handleDigest()
+	fc.alertManager.handleIncidentMode(fc.checkpoint)
+
+	failedConnectionsNodes, err := fc.nodePool.ConnectToNodes(fc.alertManager.nodeInfos, fc.cfg.Discover)
+	if err != nil {
+		log.Printf("error connecting to nodes: %s", err)
+		report.Error = err.Error()
+		return report
+	}
 
-		// Update checkpoint
-		fc.checkpoint += fc.cfg.HeightCheckInterval
+	if ctx.Err() != nil {
+		report.Error = ctx.Err().Error()
+		return report
 	}
+
+	report.Connected = len(fc.nodePool.connections())
+
+	fc.alertManager.handleRemoteAddresses(fc.nodePool.RemoteAddresses())
+
+	if fc.cfg.Discover {
+		fc.alertManager.handleDiscoveredNodes(fc.nodePool.discoveredNodes(fc.alertManager.nodeInfos))
+	}
+
+	if fc.alertManager.identities.due(time.Now()) {
+		fc.alertManager.handleIdentities(fc.nodePool.Identities())
+	}
+
+	if fc.alertManager.links.due(time.Now()) {
+		fc.alertManager.handleLinkProbes(fc.nodePool.ProbeLinks(fc.alertManager.links.probeHashes))
+	}
+
+	notReached, reached, failedHeightNodes, err := fc.nodePool.WaitHeight(fc.checkpoint)
+	if ctx.Err() != nil {
+		report.Error = ctx.Err().Error()
+		return report
+	}
+
+	// A node that connected but failed before reporting any height is offline rather than out of sync.
+	for key, info := range failedHeightNodes {
+		failedConnectionsNodes[key] = info
+	}
+
+	report.observeOffline(fc.alertManager.nodeInfos, fc.alertManager.withoutMaintenanceOfflineNodes(failedConnectionsNodes))
+
+	fc.scores.observeOffline(report.Time, failedConnectionsNodes)
+
+	// Trigger alert if offline nodes include bootstrap nodes or API nodes.
+	fc.alertManager.handleOfflineAlert(failedConnectionsNodes, maxHeight(notReached, reached))
+
+	if err != nil {
+		log.Printf("error waiting for connected nodes to reach %d height: %s", fc.checkpoint, err)
+		report.Error = err.Error()
+		return report
+	}
+
+	fc.scores.observeSync(report.Time, notReached, reached)
+	fc.alertManager.handleHeights(notReached, reached)
+
+	fc.peerHeight = maxHeight(notReached, reached)
+	report.PeerHeight = fc.peerHeight
+	report.observeNotReached(notReached)
+	fc.gateways.observePeerHeight(fc.peerHeight)
+
+	// Trigger alert if the following conditions are met:
+	//   - No nodes have synced to the checkpoint height for X minutes (stuck alert)
+	//   - Among the out-of-sync nodes, there are Y or more bootstrap or API nodes that are Z blocks or more behind the chain's highest height.
+	// X, Y, Z values are configurable in the config.json file:
+	//   X - stuckDurationThreshold
+	//   Y - outOfSyncCriticalNodesThreshold
+	//   Z - outOfSyncBlocksThreshold
+	fc.alertManager.handleSyncAlert(fc.checkpoint, notReached, reached)
+
+	// Skip incrementing checkpoint if the chain is stuck.
+	if len(reached) == 0 {
+		log.Printf("Chain is stuck! No nodes  reached height: %d", fc.checkpoint)
+		report.Stuck = true
+		fc.observeStall(true)
+		return report
+	}
+
+	fc.observeStall(false)
+	report.Checkpoint = fc.checkpoint
+
+	log.Printf("Checking block hash at %d height", fc.checkpoint)
+
+	// The anchors are queried first, so that a fork alerted before the slowest node answers already states them.
+	fc.alertManager.observeAnchor(fc.anchors.reference(fc.checkpoint))
+
+	// Trigger alert as soon as the hashes of the last confirmed block differ, without waiting for the slowest nodes.
+	alerted := 0
+	hashes, noData, err := fc.nodePool.CompareHashes(fc.checkpoint, func(hashes map[string]sdk.Hash, pending []*health.NodeInfo) {
+		log.Printf("hashes are not the same at %d height: %v", fc.checkpoint, hashes)
+		fc.alertManager.handleHashAlert(fc.checkpoint, hashes, pending)
+		alerted = len(hashes)
+	})
+	if ctx.Err() != nil {
+		report.Error = ctx.Err().Error()
+		return report
+	}
+
+	// The heights were collected, so no hash at all means the connections were lost mid-cycle.
+	fc.alertManager.handleNoPeers(fc.checkpoint, len(hashes) == 0)
+	fc.alertManager.observeHashes(fc.checkpoint, hashes, fc.nodePool.discoveredNodes(fc.alertManager.nodeInfos))
+	fc.scores.observeHashes(report.Time, hashes)
+
+	report.Hashes = make(map[string]string, len(hashes))
+	for endpoint, hash := range hashes {
+		report.Hashes[endpoint] = hash.String()
+	}
+
+	for _, info := range noData {
+		report.NoData = append(report.NoData, newStatusNode(*info, 0, ""))
+	}
+
+	if err != nil {
+		switch err {
+		case health.ErrHashesAreNotTheSame:
+			log.Printf("hashes at %d height after all nodes answered: %v", fc.checkpoint, hashes)
+			report.Fork = true
+
+			// The nodes that answered after the fork was alerted were listed without data.
+			if len(hashes) > alerted {
+				fc.alertManager.handleFinalHashes(fc.checkpoint, hashes, noData)
+			}
+		case health.ErrNoConnectedPeers, ErrNoBlockHashes:
+			// The checkpoint is only advanced once some node has verified it; alerted by handleNoPeers.
+			log.Printf("no connected peers left to compare hashes at %d height, %d nodes without data", fc.checkpoint, len(noData))
+			report.Error = err.Error()
+			return report
+		default:
+			log.Printf("unexpected error when comparing hashes at %d height: %s", fc.checkpoint, err)
+			report.Error = err.Error()
+			return report
+		}
+	} else if fc.alertManager.handleAnchorDivergence(fc.checkpoint, hashes) {
+		report.Fork = true
+	} else {
+		fc.alertManager.handleHashRecovery()
+	}
+
+	// Update checkpoint
+	fc.lastChecked = fc.checkpoint
+	fc.checkpoint = fc.nextCheckpoint()
+
+	return report
 }
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -89,6 +92,10 @@ func TestInitAlertManager(t *testing.T){
 	})
 
 	t.Run("Valid config", func(t *testing.T) {
+		if os.Getenv(BotApiKeyEnv) == "" {
+			t.Skipf("%s is not set", BotApiKeyEnv)
+		}
+
 		config, err := LoadConfig("sample.config.json")
 		require.NoError(t, err)
 
@@ -99,3 +106,31 @@ func TestInitAlertManager(t *testing.T){
 
 }
 
+func TestReloadConfig(t *testing.T) {
+	content, err := os.ReadFile("sample.config.json")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(configFile, content, 0644))
+
+	config, err := LoadConfig(configFile)
+	require.NoError(t, err)
+
+	auditFile := filepath.Join(dir, "audit.log")
+	fc := &ForkChecker{cfg: *config, audit: NewAuditLog(auditFile)}
+
+	updated := strings.Replace(string(content), `"heightCheckInterval": 1`, `"heightCheckInterval": 10`, 1)
+	require.NoError(t, os.WriteFile(configFile, []byte(updated), 0644))
+
+	fc.reloadConfig()
+	assert.Equal(t, uint64(10), fc.cfg.HeightCheckInterval)
+
+	audit, err := os.ReadFile(auditFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(audit), `"details":"1 -> 10"`)
+
+	err = fc.setHeightCheckInterval(0, "test")
+	require.ErrorIs(t, err, ErrZeroHeightCheckInterval)
+	assert.Equal(t, uint64(10), fc.cfg.HeightCheckInterval)
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health/packets"
+	"go-xpx-check-fork-util/internal/table"
+)
+
+const (
+	DefaultGapReportThreshold = 30 * time.Minute
+
+	// gapBatchSize is the number of block hashes pulled per request, well under what a node returns at once.
+	gapBatchSize = 100
+	// gapMaxHeights caps the heights checked after a downtime, so a long one does not keep the nodes busy.
+	gapMaxHeights = 20000
+	// gapMaxRanges is the number of height ranges listed in the report.
+	gapMaxRanges = 10
+	// gapTipMargin is the distance below the height reached by the nodes up to which the gap is checked, as the latest
+	// blocks may still differ between nodes that are not forked.
+	gapTipMargin = 10
+)
+
+type (
+	// CoverageGap is a downtime of the checker found on startup: the heights produced from the saved checkpoint on
+	// were not verified when they were produced.
+	CoverageGap struct {
+		DownSince time.Time
+		UpSince   time.Time
+		From      uint64
+	}
+
+	// CoverageGapReport is the result of checking a coverage gap retroactively. Only a divergence that is still
+	// present on the nodes can be found: a fork that was resolved during the downtime leaves no trace.
+	CoverageGapReport struct {
+		CoverageGap
+		To uint64
+		// Nodes is the number of nodes that returned block hashes.
+		Nodes     int
+		Checked   int
+		Divergent []uint64
+		Unchecked []HeightRange
+		// Minority are the nodes that disagreed with the majority at any divergent height.
+		Minority []health.NodeInfo
+	}
+
+	CoverageGapAlert struct {
+		Report CoverageGapReport
+	}
+
+	// HeightRange is the heights from one to another, both included.
+	HeightRange struct {
+		From uint64
+		To   uint64
+	}
+)
+
+// newCoverageGap returns nil unless the state was saved longer than the threshold ago.
+func newCoverageGap(state *CheckerState, checkpoint uint64, threshold time.Duration, now time.Time) *CoverageGap {
+	if state == nil || state.SavedAt.IsZero() || now.Sub(state.SavedAt) < threshold {
+		return nil
+	}
+
+	return &CoverageGap{DownSince: state.SavedAt, UpSince: now, From: checkpoint}
+}
+
+func (g CoverageGap) Downtime() time.Duration {
+	return g.UpSince.Sub(g.DownSince)
+}
+
+// reportCoverageGap checks the heights of the gap up to a safe distance below the height reached by the nodes and
+// sends the report. It runs in the background, next to the check cycles, over dedicated connections to the nodes whose hashes are
+// compared: pulling thousands of hashes over the pooled ones would delay the requests of the cycles past their
+// deadlines, and a cycle that reconnects would cut the pull short.
+func (fc *ForkChecker) reportCoverageGap(gap CoverageGap, peerHeight uint64) {
+	if peerHeight < gap.From+gapTipMargin {
+		return
+	}
+	to := peerHeight - gapTipMargin
+
+	conns := fc.nodePool.hashConnections()
+	nodes := make([]*health.NodeInfo, 0, len(conns))
+	for _, conn := range conns {
+		nodes = append(nodes, conn.info)
+	}
+
+	pool := fc.nodePool.dedicated()
+	defer pool.reset()
+
+	if _, err := pool.ConnectToNodes(nodes, false); err != nil {
+		log.Printf("Coverage gap: failed to connect to the nodes: %v", err)
+	}
+
+	report := checkCoverageGap(pool, gap, to)
+	log.Printf("Coverage gap of %s: heights %d-%d, %d checked on %d nodes, %d divergent",
+		gap.Downtime().Round(time.Second), report.From, report.To, report.Checked, report.Nodes, len(report.Divergent))
+
+	fc.alertManager.sendUntracked(CoverageGapAlert{Report: report})
+}
+
+// checkCoverageGap pulls the block hashes of the gap from the connections of the pool and compares them height by
+// height. The heights past gapMaxHeights are reported unchecked as a single range.
+func checkCoverageGap(pool *NodePool, gap CoverageGap, to uint64) CoverageGapReport {
+	report := CoverageGapReport{CoverageGap: gap, To: to}
+
+	last := to
+	if to-gap.From >= gapMaxHeights {
+		last = gap.From + gapMaxHeights - 1
+	}
+
+	conns := pool.hashConnections()
+	hashes := make(map[*health.NodeInfo][]sdk.Hash, len(conns))
+	var mu sync.Mutex
+
+	pool.forEach(len(conns), func(i int) {
+		nodeHashes, err := conns[i].blockHashesRange(gap.From, last)
+		if err != nil {
+			log.Printf("Coverage gap: %s returned %d of %d hashes: %v", conns[i].info.Endpoint, len(nodeHashes), last-gap.From+1, err)
+		}
+		if len(nodeHashes) == 0 {
+			return
+		}
+
+		mu.Lock()
+		hashes[conns[i].info] = nodeHashes
+		mu.Unlock()
+	})
+
+	report.Nodes = len(hashes)
+	minority := make(map[*health.NodeInfo]bool)
+
+	for height := gap.From; height <= last; height++ {
+		heightHashes := make(map[string]sdk.Hash)
+		infos := make(map[string]*health.NodeInfo)
+		for info, nodeHashes := range hashes {
+			if offset := height - gap.From; offset < uint64(len(nodeHashes)) {
+				heightHashes[info.IdentityKey.String()] = nodeHashes[offset]
+				infos[info.IdentityKey.String()] = info
+			}
+		}
+
+		// A height only one node returned has nothing to be compared with.
+		if len(heightHashes) < 2 {
+			report.addUnchecked(height, height)
+			continue
+		}
+		report.Checked++
+
+		majority, exists := majorityHash(heightHashes)
+		diverged := false
+		for key, hash := range heightHashes {
+			if !exists || hash != majority {
+				minority[infos[key]] = true
+				diverged = true
+			}
+		}
+
+		if diverged {
+			report.Divergent = append(report.Divergent, height)
+		}
+	}
+
+	if last < to {
+		report.addUnchecked(last+1, to)
+	}
+
+	for info := range minority {
+		report.Minority = append(report.Minority, *info)
+	}
+	sort.Slice(report.Minority, func(i, j int) bool {
+		return nodeName(report.Minority[i]) < nodeName(report.Minority[j])
+	})
+
+	return report
+}
+
+// addUnchecked adds the heights to the unchecked ones, extending the last range if they follow it.
+func (r *CoverageGapReport) addUnchecked(from, to uint64) {
+	if n := len(r.Unchecked); n > 0 && r.Unchecked[n-1].To+1 == from {
+		r.Unchecked[n-1].To = to
+		return
+	}
+
+	r.Unchecked = append(r.Unchecked, HeightRange{From: from, To: to})
+}
+
+// uncheckedHeights is the number of heights in the unchecked ranges.
+func (r *CoverageGapReport) uncheckedHeights() uint64 {
+	var heights uint64
+	for _, unchecked := range r.Unchecked {
+		heights += unchecked.To - unchecked.From + 1
+	}
+
+	return heights
+}
+
+// blockHashesRange pulls the hashes of the blocks from one height to another in batches. It stops early if the node
+// returns fewer hashes than asked, e.g. because it has not reached the last height, and returns the hashes pulled so
+// far along with any error.
+func (c *nodeConn) blockHashesRange(from, to uint64) ([]sdk.Hash, error) {
+	hashes := make([]sdk.Hash, 0, to-from+1)
+
+	for height := from; height <= to; {
+		count := to - height + 1
+		if count > gapBatchSize {
+			count = gapBatchSize
+		}
+
+		c.mu.Lock()
+		resp := &packets.BlockHashesResponse{}
+		err := c.handler.CommonHandle(packets.NewBlockHashesRequest(height, uint32(count)), resp)
+		if err == nil {
+			c.lastActive = time.Now()
+		}
+		c.mu.Unlock()
+
+		if err != nil {
+			return hashes, err
+		}
+
+		if uint64(len(resp.Hashes)) > count {
+			resp.Hashes = resp.Hashes[:count]
+		}
+		hashes = append(hashes, resp.Hashes...)
+
+		if uint64(len(resp.Hashes)) < count {
+			break
+		}
+		height += count
+	}
+
+	return hashes, nil
+}
+
+// formatHeightRanges joins consecutive heights into ranges, e.g. "10-12, 15", listing at most max ranges.
+func formatHeightRanges(heights []uint64, max int) string {
+	var ranges []HeightRange
+	for i := 0; i < len(heights); {
+		j := i
+		for j+1 < len(heights) && heights[j+1] == heights[j]+1 {
+			j++
+		}
+
+		ranges = append(ranges, HeightRange{From: heights[i], To: heights[j]})
+		i = j + 1
+	}
+
+	return formatRanges(ranges, max)
+}
+
+// formatRanges lists at most max height ranges.
+func formatRanges(ranges []HeightRange, max int) string {
+	formatted := make([]string, 0, len(ranges))
+	for _, heights := range ranges {
+		if len(formatted) == max {
+			formatted = append(formatted, "…")
+			break
+		}
+
+		if heights.From == heights.To {
+			formatted = append(formatted, fmt.Sprintf("%d", heights.From))
+		} else {
+			formatted = append(formatted, fmt.Sprintf("%d-%d", heights.From, heights.To))
+		}
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+func (a CoverageGapAlert) getType() AlertType {
+	return CoverageGapAlertType
+}
+
+func (a CoverageGapAlert) createMessage() string {
+	var buf bytes.Buffer
+	report := a.Report
+
+	fmt.Fprintf(&buf, "<b>⚠️ Coverage gap report </b>\n\n")
+	fmt.Fprintf(&buf, "The checker was down for %s, since %s. Heights %d-%d (%d) were not verified when they were produced.\n\n",
+		report.Downtime().Round(time.Second), report.DownSince.UTC().Format(time.RFC3339), report.From, report.To, report.To-report.From+1)
+
+	fmt.Fprintf(&buf, "Checked afterwards: %d heights on %d nodes.\n", report.Checked, report.Nodes)
+	if len(report.Divergent) == 0 {
+		if report.Checked > 0 {
+			fmt.Fprintf(&buf, "No divergence is detectable: the nodes agree on every checked height.\n")
+		}
+	} else {
+		fmt.Fprintf(&buf, "<b>Divergence still detectable</b> at %d heights: %s\n", len(report.Divergent), formatHeightRanges(report.Divergent, gapMaxRanges))
+		rows := make([][]string, 0, len(report.Minority))
+		for _, node := range report.Minority {
+			rows = append(rows, []string{nodeName(node)})
+		}
+		fmt.Fprintf(&buf, "Nodes off the majority (%d):<pre>%s</pre>", len(report.Minority), table.Render(rows, table.Options{}))
+	}
+
+	if len(report.Unchecked) > 0 {
+		fmt.Fprintf(&buf, "Never verified (%d): %s\n", report.uncheckedHeights(), formatRanges(report.Unchecked, gapMaxRanges))
+	}
+
+	fmt.Fprintf(&buf, "\nA fork that was resolved during the downtime leaves no trace and cannot be detected.")
+
+	return buf.String()
+}
+
+func (a CoverageGapAlert) redact(r *Redactor) Alert {
+	minority := make([]health.NodeInfo, 0, len(a.Report.Minority))
+	for _, node := range a.Report.Minority {
+		minority = append(minority, r.node(node))
+	}
+
+	a.Report.Minority = minority
+	return a
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCoverageGap(t *testing.T) {
+	now := time.Now()
+
+	assert.Nil(t, newCoverageGap(nil, 100, time.Hour, now))
+	assert.Nil(t, newCoverageGap(&CheckerState{SavedAt: now.Add(-time.Minute)}, 100, time.Hour, now))
+
+	gap := newCoverageGap(&CheckerState{SavedAt: now.Add(-2 * time.Hour)}, 100, time.Hour, now)
+	require.NotNil(t, gap)
+	assert.Equal(t, uint64(100), gap.From)
+	assert.Equal(t, 2*time.Hour, gap.Downtime())
+}
+
+func TestCheckCoverageGap(t *testing.T) {
+	gap := CoverageGap{DownSince: time.Now().Add(-time.Hour), UpSince: time.Now(), From: 100}
+
+	connect := func(t *testing.T, nodes ...*testNode) *NodePool {
+		infos := make([]*health.NodeInfo, 0, len(nodes))
+		for _, node := range nodes {
+			infos = append(infos, node.info())
+		}
+
+		pool := newTestNodePool(t)
+		_, err := pool.ConnectToNodes(infos, false)
+		require.NoError(t, err)
+		return pool
+	}
+
+	t.Run("No divergence", func(t *testing.T) {
+		pool := connect(t, newTestNode(t, 400, sdk.Hash{1}), newTestNode(t, 400, sdk.Hash{1}))
+
+		// More heights than fit in a single request.
+		report := checkCoverageGap(pool, gap, 349)
+		assert.Equal(t, 2, report.Nodes)
+		assert.Equal(t, 250, report.Checked)
+		assert.Empty(t, report.Divergent)
+		assert.Empty(t, report.Unchecked)
+		assert.Contains(t, CoverageGapAlert{Report: report}.createMessage(), "No divergence is detectable")
+	})
+
+	t.Run("Divergence", func(t *testing.T) {
+		minority := newTestNode(t, 400, sdk.Hash{2})
+		pool := connect(t, newTestNode(t, 400, sdk.Hash{1}), newTestNode(t, 400, sdk.Hash{1}), minority)
+
+		report := checkCoverageGap(pool, gap, 109)
+		assert.Len(t, report.Divergent, 10)
+		require.Len(t, report.Minority, 1)
+		assert.Equal(t, minority.info().Endpoint, report.Minority[0].Endpoint)
+
+		message := CoverageGapAlert{Report: report}.createMessage()
+		assert.Contains(t, message, "Divergence still detectable</b> at 10 heights: 100-109")
+		assert.Contains(t, message, nodeName(*minority.info()))
+	})
+
+	t.Run("Single node", func(t *testing.T) {
+		pool := connect(t, newTestNode(t, 400, sdk.Hash{1}))
+
+		report := checkCoverageGap(pool, gap, 104)
+		assert.Zero(t, report.Checked)
+		assert.Equal(t, []HeightRange{{From: 100, To: 104}}, report.Unchecked)
+		assert.Contains(t, CoverageGapAlert{Report: report}.createMessage(), "Never verified (5): 100-104")
+	})
+
+	t.Run("Dedicated connections", func(t *testing.T) {
+		nodeA := newTestNode(t, 400, sdk.Hash{1})
+		nodeB := newTestNode(t, 400, sdk.Hash{1})
+
+		fc := newReportTestForkChecker(t, nodeA, nodeB)
+		_, err := fc.nodePool.ConnectToNodes(fc.alertManager.nodeInfos, false)
+		require.NoError(t, err)
+		conns := fc.nodePool.connections()
+		lastActive := make(map[*nodeConn]time.Time, len(conns))
+		for _, conn := range conns {
+			lastActive[conn] = conn.lastActive
+		}
+
+		fc.reportCoverageGap(gap, 349)
+
+		// The connections of the check cycles were neither used nor closed.
+		assert.ElementsMatch(t, conns, fc.nodePool.connections())
+		for _, conn := range conns {
+			assert.Equal(t, lastActive[conn], conn.lastActive)
+			_, err := conn.chainInfo()
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("Beyond the checked heights", func(t *testing.T) {
+		report := checkCoverageGap(newTestNodePool(t), CoverageGap{From: 1}, gapMaxHeights+9)
+		assert.Equal(t, []HeightRange{{From: 1, To: gapMaxHeights + 9}}, report.Unchecked)
+
+		// The heights past the cap are reported as a single range rather than one by one.
+		pool := connect(t, newTestNode(t, 400000, sdk.Hash{1}), newTestNode(t, 400000, sdk.Hash{1}))
+		report = checkCoverageGap(pool, CoverageGap{From: 1}, 300000)
+		assert.Equal(t, gapMaxHeights, report.Checked)
+		assert.Equal(t, []HeightRange{{From: gapMaxHeights + 1, To: 300000}}, report.Unchecked)
+		assert.Contains(t, CoverageGapAlert{Report: report}.createMessage(), fmt.Sprintf("Never verified (%d): %d-300000", 300000-gapMaxHeights, gapMaxHeights+1))
+	})
+
+	t.Run("Below the tip", func(t *testing.T) {
+		nodeA := newTestNode(t, 400, sdk.Hash{1})
+		nodeB := newTestNode(t, 400, sdk.Hash{1})
+
+		fc := newReportTestForkChecker(t, nodeA, nodeB)
+		_, err := fc.nodePool.ConnectToNodes(fc.alertManager.nodeInfos, false)
+		require.NoError(t, err)
+
+		bot, telegram := newTestBot(t)
+		fc.alertManager.notifier = &Notifier{bot: bot, enabled: true}
+
+		// The latest heights may still differ between nodes that are not forked, so they are left out.
+		fc.reportCoverageGap(gap, 100+gapTipMargin-1)
+		assert.Empty(t, telegram.messages)
+
+		fc.reportCoverageGap(gap, 109+gapTipMargin)
+		require.Len(t, telegram.messages, 1)
+		assert.Contains(t, telegram.messages[0], "Heights 100-109 (10)")
+	})
+}
+
+func TestFormatHeightRanges(t *testing.T) {
+	assert.Equal(t, "", formatHeightRanges(nil, 3))
+	assert.Equal(t, "5", formatHeightRanges([]uint64{5}, 3))
+	assert.Equal(t, "10-12, 15, 17-18", formatHeightRanges([]uint64{10, 11, 12, 15, 17, 18}, 3))
+	assert.Equal(t, "1, 3, …", formatHeightRanges([]uint64{1, 3, 5, 7}, 2))
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	MainnetConfigTemplate = "mainnet"
+	TestnetConfigTemplate = "testnet"
+	PrivateConfigTemplate = "private"
+
+	DefaultGeneratedNodes = 5
+
+	// configCommentPrefix marks the keys that explain the setting following them. The checker ignores them like any
+	// other unknown key.
+	configCommentPrefix = "//"
+)
+
+var ErrInvalidConfigTemplate = errors.New("invalid config template")
+
+type (
+	// configField is a setting of a generated config. A non-empty comment is written as a key of its own right
+	// before the setting, since JSON has no comments.
+	configField struct {
+		key     string
+		comment string
+		value   interface{}
+	}
+
+	// configObject is a JSON object that keeps the order of its fields, so each comment stays next to its setting.
+	configObject []configField
+
+	// configTopology holds the settings that differ between the templates.
+	configTopology struct {
+		apiUrl                     string
+		discover                   bool
+		offlineAlertRepeatInterval string
+		offlineDurationThreshold   string
+		syncAlertRepeatInterval    string
+		stuckDurationThreshold     string
+		outOfSyncBlocksThreshold   int
+		criticalNodes              func(nodes int) int
+		concurrency                int
+		private                    bool
+	}
+)
+
+// configTopologies are tuned to the block time and the size of the networks: mainnet alerts early, the testnet
+// tolerates the restarts and lagging nodes common there, and a private chain has few nodes, each of them critical.
+var configTopologies = map[string]configTopology{
+	MainnetConfigTemplate: {
+		apiUrl:                     "https://<API_HOST>",
+		discover:                   true,
+		offlineAlertRepeatInterval: "2h",
+		offlineDurationThreshold:   "5m",
+		syncAlertRepeatInterval:    "2h",
+		stuckDurationThreshold:     "10m",
+		outOfSyncBlocksThreshold:   5,
+		criticalNodes:              func(nodes int) int { return (nodes + 2) / 3 },
+		concurrency:                16,
+	},
+	TestnetConfigTemplate: {
+		apiUrl:                     "https://<API_HOST>",
+		discover:                   true,
+		offlineAlertRepeatInterval: "6h",
+		offlineDurationThreshold:   "15m",
+		syncAlertRepeatInterval:    "6h",
+		stuckDurationThreshold:     "30m",
+		outOfSyncBlocksThreshold:   10,
+		criticalNodes:              func(nodes int) int { return (nodes + 1) / 2 },
+		concurrency:                16,
+	},
+	PrivateConfigTemplate: {
+		apiUrl:                     "http://<API_HOST>:3000",
+		discover:                   false,
+		offlineAlertRepeatInterval: "1h",
+		offlineDurationThreshold:   "5m",
+		syncAlertRepeatInterval:    "1h",
+		stuckDurationThreshold:     "5m",
+		outOfSyncBlocksThreshold:   3,
+		criticalNodes:              func(nodes int) int { return 1 },
+		concurrency:                4,
+		private:                    true,
+	},
+}
+
+func (o configObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		if field.comment != "" {
+			if err := writeConfigPair(&buf, configCommentPrefix+field.key, field.comment); err != nil {
+				return nil, err
+			}
+			buf.WriteByte(',')
+		}
+
+		if err := writeConfigPair(&buf, field.key, field.value); err != nil {
+			return nil, err
+		}
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func writeConfigPair(buf *bytes.Buffer, key string, value interface{}) error {
+	if err := encodeConfigValue(buf, key); err != nil {
+		return err
+	}
+
+	buf.WriteByte(':')
+	return encodeConfigValue(buf, value)
+}
+
+// encodeConfigValue leaves the angle brackets of the placeholders unescaped.
+func encodeConfigValue(buf *bytes.Buffer, value interface{}) error {
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+
+	// Encode ends the value with a newline.
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// generateConfig returns a commented config for the topology, with placeholders in angle brackets for the node
+// details and secrets. The placeholders of the nodes and the chat ID do not pass validation, so a config that was
+// not filled in does not start.
+func generateConfig(template string, nodeCount int) ([]byte, error) {
+	topology, exists := configTopologies[template]
+	if !exists {
+		return nil, fmt.Errorf("%w: unknown template %s", ErrInvalidConfigTemplate, template)
+	}
+
+	if nodeCount < 1 {
+		return nil, fmt.Errorf("%w: at least one node is required", ErrInvalidConfigTemplate)
+	}
+
+	nodes := make([]configObject, 0, nodeCount)
+	for i := 1; i <= nodeCount; i++ {
+		nodes = append(nodes, configObject{
+			{key: "endpoint", value: fmt.Sprintf("<NODE_%d_HOST>:7900", i)},
+			{key: "IdentityKey", value: fmt.Sprintf("<NODE_%d_IDENTITY_KEY>", i)},
+			{key: "friendlyName", value: fmt.Sprintf("node%d", i)},
+		})
+	}
+
+	config := configObject{
+		{key: "nodes", comment: "Nodes whose block hashes are compared: host and port, the node's public key and a name for the alerts.", value: nodes},
+		{key: "apiUrls", comment: "REST gateways used for the chain height and the nemesis block.", value: []string{topology.apiUrl}},
+	}
+
+	if topology.private {
+		config = append(config,
+			configField{key: "networkType", comment: "Network type of the chain (private, privateTest, mijin or mijinTest), so it need not be discovered from the gateways.", value: "private"},
+			configField{key: "generationHash", comment: "Generation hash of the chain, found in its nemesis block.", value: "<GENERATION_HASH>"},
+		)
+	}
+
+	config = append(config,
+		configField{key: "discover", comment: "Also compare the peers found through the nodes, not only the listed ones.", value: topology.discover},
+		configField{key: "checkpoint", comment: "Height to start from; 0 starts from the current chain height.", value: 0},
+		configField{key: "heightCheckInterval", comment: "Blocks between hash checks.", value: DefaultHeightCheckInterval},
+		configField{key: "botApiKey", comment: "Telegram bot token. Prefer the FORKCHECK_BOT_API_KEY environment variable or a secrets file.", value: "<TELEGRAM_BOT_API_KEY>"},
+		configField{key: "chatID", comment: "Telegram chat the alerts are sent to, or the FORKCHECK_CHAT_ID environment variable.", value: 0},
+		configField{key: "notify", comment: "Send alerts to Telegram.", value: true},
+		configField{key: "alertConfig", comment: "When the offline and sync alerts are sent.", value: configObject{
+			{key: "offlineAlertRepeatInterval", comment: "Minimum time between offline alerts.", value: topology.offlineAlertRepeatInterval},
+			{key: "offlineDurationThreshold", comment: "Time a node must be offline before it is alerted.", value: topology.offlineDurationThreshold},
+			{key: "syncAlertRepeatInterval", comment: "Minimum time between sync alerts.", value: topology.syncAlertRepeatInterval},
+			{key: "stuckDurationThreshold", comment: "Time without any node reaching the next height before the chain is reported stuck.", value: topology.stuckDurationThreshold},
+			{key: "outOfSyncBlocksThreshold", comment: "Blocks a node must lag behind to count as out of sync.", value: topology.outOfSyncBlocksThreshold},
+			{key: "outOfSyncCriticalNodesThreshold", comment: "Listed nodes that must be out of sync before it is alerted.", value: topology.criticalNodes(nodeCount)},
+		}},
+		configField{key: "poolConfig", comment: "Connections to the nodes.", value: configObject{
+			{key: "concurrency", comment: "Nodes connected and queried at once.", value: topology.concurrency},
+		}},
+		configField{key: "statusAddress", comment: "Address of the status API; leave empty to disable.", value: ":8080"},
+	)
+
+	content, err := config.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, content, "", "    "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+// runGenerateConfig implements the generate-config command. Without -output, the config is written to stdout; an
+// existing output file is never overwritten.
+func runGenerateConfig(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("generate-config", flag.ContinueOnError)
+	template := flags.String("template", MainnetConfigTemplate, "Topology of the network: mainnet, testnet or private")
+	nodeCount := flags.Int("nodes", DefaultGeneratedNodes, "Number of node entries to fill in")
+	output := flags.String("output", "", "File to write the config to instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	content, err := generateConfig(*template, *nodeCount)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = stdout.Write(content)
+		return err
+	}
+
+	return writeNewFile(*output, content)
+}
+
+// writeNewFile fails instead of overwriting an existing file.
+func writeNewFile(name string, content []byte) error {
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
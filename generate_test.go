@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fillGeneratedConfig replaces the placeholders as a user would.
+func fillGeneratedConfig(content []byte, nodeCount int) []byte {
+	filled := string(content)
+	for i := 1; i <= nodeCount; i++ {
+		filled = strings.ReplaceAll(filled, fmt.Sprintf("<NODE_%d_HOST>", i), fmt.Sprintf("127.0.0.%d", i))
+		filled = strings.ReplaceAll(filled, fmt.Sprintf("<NODE_%d_IDENTITY_KEY>", i), fmt.Sprintf("%X%s", i, strings.Repeat("A", 63)))
+	}
+
+	filled = strings.ReplaceAll(filled, "<API_HOST>", "localhost")
+	filled = strings.ReplaceAll(filled, "<GENERATION_HASH>", strings.Repeat("B", 64))
+	filled = strings.ReplaceAll(filled, `"chatID": 0`, `"chatID": -100`)
+	return []byte(filled)
+}
+
+func TestGenerateConfig(t *testing.T) {
+	for _, template := range []string{MainnetConfigTemplate, TestnetConfigTemplate, PrivateConfigTemplate} {
+		t.Run(template, func(t *testing.T) {
+			content, err := generateConfig(template, 5)
+			require.NoError(t, err)
+			assert.Contains(t, string(content), `"//stuckDurationThreshold": `)
+			assert.Contains(t, string(content), `"<TELEGRAM_BOT_API_KEY>"`)
+
+			dir := t.TempDir()
+			file := filepath.Join(dir, "config.json")
+
+			// A config with the placeholders left in does not start.
+			require.NoError(t, os.WriteFile(file, content, 0600))
+			_, err = LoadConfig(file)
+			require.Error(t, err)
+
+			require.NoError(t, os.WriteFile(file, fillGeneratedConfig(content, 5), 0600))
+			config, err := LoadConfig(file)
+			require.NoError(t, err)
+			assert.Len(t, config.Nodes, 5)
+
+			topology := configTopologies[template]
+			assert.Equal(t, topology.discover, config.Discover)
+			assert.Equal(t, topology.stuckDurationThreshold, config.AlertConfig.StuckDurationThreshold)
+			assert.Equal(t, topology.criticalNodes(5), config.AlertConfig.OutOfSyncCriticalNodesThreshold.nodes(5))
+			assert.Equal(t, topology.private, config.GenerationHash != "")
+		})
+	}
+
+	t.Run("Comments precede their settings", func(t *testing.T) {
+		content, err := generateConfig(MainnetConfigTemplate, 1)
+		require.NoError(t, err)
+
+		lines := strings.Split(string(content), "\n")
+		comments := 0
+		for i, line := range lines {
+			line = strings.TrimSpace(line)
+			if !strings.HasPrefix(line, `"`+configCommentPrefix) {
+				continue
+			}
+
+			key := line[len(configCommentPrefix)+1 : strings.Index(line, `":`)]
+			assert.True(t, strings.HasPrefix(strings.TrimSpace(lines[i+1]), `"`+key+`":`), key)
+			comments++
+		}
+		assert.Greater(t, comments, 10)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := generateConfig("devnet", 5)
+		require.ErrorIs(t, err, ErrInvalidConfigTemplate)
+
+		_, err = generateConfig(MainnetConfigTemplate, 0)
+		require.ErrorIs(t, err, ErrInvalidConfigTemplate)
+	})
+}
+
+func TestRunGenerateConfig(t *testing.T) {
+	var stdout bytes.Buffer
+	require.NoError(t, runGenerateConfig([]string{"--template", "testnet", "--nodes", "2"}, &stdout))
+
+	expected, err := generateConfig(TestnetConfigTemplate, 2)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), stdout.String())
+
+	// An existing file is never overwritten.
+	output := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, runGenerateConfig([]string{"-output", output}, &stdout))
+	require.Error(t, runGenerateConfig([]string{"-output", output}, &stdout))
+}
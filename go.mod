@@ -4,7 +4,6 @@ go 1.20
 
 require (
 	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
-	github.com/olekukonko/tablewriter v0.0.5
 	github.com/proximax-storage/go-xpx-chain-sdk v0.7.5-0.20240902102220-b05f83921bde
 	github.com/proximax-storage/go-xpx-crypto v0.1.0
 	github.com/stretchr/testify v1.8.1
@@ -15,7 +14,6 @@ require (
 	github.com/google/flatbuffers v1.11.0 // indirect
 	github.com/google/go-querystring v1.0.0 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
-	github.com/mattn/go-runewidth v0.0.9 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/pkg/errors v0.8.1 // indirect
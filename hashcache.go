@@ -0,0 +1,121 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+)
+
+const (
+	DefaultHashCacheSize = 4096
+	DefaultHashCacheTtl  = time.Minute
+)
+
+type (
+	// HashCache keeps the block hashes fetched from the nodes, so that checks asking for the same hash again
+	// (retries of a failed cycle, the nemesis block of the identity check) don't refetch it. Entries expire after
+	// the TTL, as a node that switched forks may return a different hash for the same height.
+	HashCache struct {
+		mu      sync.Mutex
+		size    int
+		ttl     time.Duration
+		entries map[hashCacheKey]*list.Element
+		order   *list.List // least recently used at the back
+
+		hits   uint64
+		misses uint64
+	}
+
+	hashCacheKey struct {
+		node   string
+		height uint64
+	}
+
+	hashCacheEntry struct {
+		key     hashCacheKey
+		hash    sdk.Hash
+		expires time.Time
+	}
+)
+
+// NewHashCache returns nil if the cache is disabled.
+func NewHashCache(config HashCacheConfig) *HashCache {
+	if !config.Enabled {
+		return nil
+	}
+
+	return &HashCache{
+		size:    config.getSize(),
+		ttl:     config.getTtl(),
+		entries: make(map[hashCacheKey]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (c *HashCache) get(node string, height uint64, now time.Time) (sdk.Hash, bool) {
+	if c == nil {
+		return sdk.Hash{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, exists := c.entries[hashCacheKey{node: node, height: height}]
+	if !exists {
+		c.misses++
+		return sdk.Hash{}, false
+	}
+
+	entry := elem.Value.(*hashCacheEntry)
+	if now.After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, entry.key)
+		c.misses++
+		return sdk.Hash{}, false
+	}
+
+	c.order.MoveToFront(elem)
+	c.hits++
+
+	return entry.hash, true
+}
+
+func (c *HashCache) put(node string, height uint64, hash sdk.Hash, now time.Time) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := hashCacheKey{node: node, height: height}
+	if elem, exists := c.entries[key]; exists {
+		entry := elem.Value.(*hashCacheEntry)
+		entry.hash = hash
+		entry.expires = now.Add(c.ttl)
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&hashCacheEntry{key: key, hash: hash, expires: now.Add(c.ttl)})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*hashCacheEntry).key)
+	}
+}
+
+// stats returns the number of cache hits and misses so far.
+func (c *HashCache) stats() (hits, misses uint64) {
+	if c == nil {
+		return 0, 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.hits, c.misses
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashCache(t *testing.T) {
+	now := time.Now()
+
+	t.Run("Eviction and expiry", func(t *testing.T) {
+		cache := NewHashCache(HashCacheConfig{Enabled: true, Size: 2, Ttl: "1m"})
+
+		cache.put("A", 10, sdk.Hash{1}, now)
+		cache.put("B", 10, sdk.Hash{2}, now)
+
+		hash, exists := cache.get("A", 10, now)
+		require.True(t, exists)
+		assert.Equal(t, sdk.Hash{1}, hash)
+
+		// B is the least recently used entry.
+		cache.put("A", 11, sdk.Hash{3}, now)
+		_, exists = cache.get("B", 10, now)
+		assert.False(t, exists)
+
+		_, exists = cache.get("A", 10, now.Add(2*time.Minute))
+		assert.False(t, exists)
+
+		hits, misses := cache.stats()
+		assert.Equal(t, uint64(1), hits)
+		assert.Equal(t, uint64(2), misses)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		cache := NewHashCache(HashCacheConfig{})
+		assert.Nil(t, cache)
+
+		cache.put("A", 10, sdk.Hash{1}, now)
+		_, exists := cache.get("A", 10, now)
+		assert.False(t, exists)
+	})
+
+	t.Run("Pool", func(t *testing.T) {
+		node := newTestNode(t, 10, sdk.Hash{1})
+
+		pool := newTestNodePool(t)
+		pool.hashes = NewHashCache(HashCacheConfig{Enabled: true, Ttl: "1h"})
+		_, err := pool.ConnectToNodes([]*health.NodeInfo{node.info()}, false)
+		require.NoError(t, err)
+
+		hashes, _, err := pool.CompareHashes(10, nil)
+		require.NoError(t, err)
+		assert.Equal(t, sdk.Hash{1}, hashes[node.info().Endpoint])
+
+		// The node is not asked again within the TTL.
+		node.delay.Store(int64(10 * time.Second))
+		start := time.Now()
+		hashes, _, err = pool.CompareHashes(10, nil)
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), 100*time.Millisecond)
+		assert.Equal(t, sdk.Hash{1}, hashes[node.info().Endpoint])
+
+		hits, _ := pool.hashes.stats()
+		assert.Equal(t, uint64(1), hits)
+	})
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"go-xpx-check-fork-util/internal/table"
+)
+
+const (
+	DefaultIdentityCheckInterval       = time.Minute * 10
+	DefaultIdentityAlertRepeatInterval = time.Hour * 2
+)
+
+var ErrInvalidIdentityConfig = errors.New("invalid identity config")
+
+type (
+	// NodeIdentity is what a node reports about its software and the network it belongs to.
+	NodeIdentity struct {
+		Version           uint32
+		NetworkIdentifier uint8
+		NemesisHash       sdk.Hash
+	}
+
+	// IdentityMonitor checks the versions and nemesis block hashes reported by the nodes. Nodes do not exchange the
+	// generation hash itself, but a node generated from a different nemesis block reports a different hash at height 1.
+	IdentityMonitor struct {
+		minVersion          uint32
+		nemesisHash         *sdk.Hash
+		checkInterval       time.Duration
+		alertRepeatInterval time.Duration
+
+		lastCheck      time.Time
+		identities     map[string]NodeIdentity
+		lastAlertTimes map[string]time.Time
+	}
+
+	IdentityMismatch struct {
+		Node        health.NodeInfo
+		Version     string
+		NemesisHash sdk.Hash
+
+		Outdated     bool
+		WrongNetwork bool
+	}
+
+	// IdentityAlert reports nodes running an outdated version or belonging to a different network.
+	IdentityAlert struct {
+		MinVersion  string
+		NemesisHash sdk.Hash
+		Nodes       []IdentityMismatch
+	}
+)
+
+func (a IdentityAlert) getType() AlertType {
+	return IdentityAlertType
+}
+
+func (a IdentityAlert) createMessage() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "<b>⚠️ Warning - Node identity </b>")
+
+	var outdated [][]string
+	var wrongNetwork []string
+	for _, node := range a.Nodes {
+		if node.Outdated {
+			outdated = append(outdated, []string{nodeName(node.Node), node.Version})
+		}
+		if node.WrongNetwork {
+			wrongNetwork = append(wrongNetwork, fmt.Sprintf("%s\n  %s", table.Escape(nodeName(node.Node)), node.NemesisHash))
+		}
+	}
+
+	if len(outdated) > 0 {
+		fmt.Fprintf(&buf, "\n\nBelow minimum version %s (%d):<pre>%s</pre>", a.MinVersion, len(outdated), table.Render(outdated, table.Options{}))
+	}
+
+	if len(wrongNetwork) > 0 {
+		fmt.Fprintf(&buf, "\n\nNemesis block differs from %s (%d):<pre>%s</pre>", a.NemesisHash, len(wrongNetwork), strings.Join(wrongNetwork, "\n"))
+	}
+
+	return buf.String()
+}
+
+func (a IdentityAlert) redact(r *Redactor) Alert {
+	nodes := make([]IdentityMismatch, 0, len(a.Nodes))
+	for _, node := range a.Nodes {
+		node.Node = r.node(node.Node)
+		nodes = append(nodes, node)
+	}
+
+	a.Nodes = nodes
+	return a
+}
+
+// formatVersion formats a packed node version, one byte per component from the most significant one.
+func formatVersion(version uint32) string {
+	return fmt.Sprintf("%d.%d.%d.%d", version>>24, version>>16&0xff, version>>8&0xff, version&0xff)
+}
+
+// parseVersion parses a version of up to four dot-separated components, e.g. "1.2" or "1.2.0.3".
+func parseVersion(version string) (uint32, error) {
+	parts := strings.Split(version, ".")
+	if len(parts) > 4 {
+		return 0, fmt.Errorf("%w: version %s has more than 4 components", ErrInvalidIdentityConfig, version)
+	}
+
+	var packed uint32
+	for i := 0; i < 4; i++ {
+		var component uint64
+		if i < len(parts) {
+			var err error
+			component, err = strconv.ParseUint(parts[i], 10, 8)
+			if err != nil {
+				return 0, fmt.Errorf("%w: version %s: %v", ErrInvalidIdentityConfig, version, err)
+			}
+		}
+
+		packed = packed<<8 | uint32(component)
+	}
+
+	return packed, nil
+}
+
+func parseIdentityConfig(config IdentityConfig) (minVersion uint32, nemesisHash *sdk.Hash, err error) {
+	if config.MinVersion != "" {
+		if minVersion, err = parseVersion(config.MinVersion); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if config.NemesisHash != "" {
+		if nemesisHash, err = sdk.StringToHash(config.NemesisHash); err != nil {
+			return 0, nil, fmt.Errorf("%w: nemesis hash: %v", ErrInvalidIdentityConfig, err)
+		}
+	}
+
+	return minVersion, nemesisHash, nil
+}
+
+// NewIdentityMonitor returns nil if identity monitoring is disabled. Without a configured nemesis hash, the one
+// returned by the REST gateway is expected.
+func NewIdentityMonitor(config IdentityConfig, client *sdk.Client) (*IdentityMonitor, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	minVersion, nemesisHash, err := parseIdentityConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if nemesisHash == nil {
+		block, err := client.Blockchain.GetBlockByHeight(context.Background(), sdk.Height(1))
+		if err != nil {
+			return nil, fmt.Errorf("error getting nemesis block: %v", err)
+		}
+		nemesisHash = block.BlockHash
+	}
+
+	return &IdentityMonitor{
+		minVersion:          minVersion,
+		nemesisHash:         nemesisHash,
+		checkInterval:       config.getCheckInterval(),
+		alertRepeatInterval: config.getAlertRepeatInterval(),
+		identities:          make(map[string]NodeIdentity),
+		lastAlertTimes:      make(map[string]time.Time),
+	}, nil
+}
+
+func (m *IdentityMonitor) due(now time.Time) bool {
+	return m != nil && now.Sub(m.lastCheck) >= m.checkInterval
+}
+
+// update records the identities reported in a check. Nodes that did not answer keep their previous identity.
+func (m *IdentityMonitor) update(now time.Time, identities map[string]NodeIdentity) {
+	m.lastCheck = now
+	for key, identity := range identities {
+		m.identities[key] = identity
+	}
+}
+
+// mismatches returns the nodes whose identity does not match the expectations.
+func (m *IdentityMonitor) mismatches(nodeInfos []*health.NodeInfo) []IdentityMismatch {
+	var result []IdentityMismatch
+	for _, info := range nodeInfos {
+		identity, exists := m.identities[info.IdentityKey.String()]
+		if !exists {
+			continue
+		}
+
+		mismatch := IdentityMismatch{
+			Node:         *info,
+			Version:      formatVersion(identity.Version),
+			NemesisHash:  identity.NemesisHash,
+			Outdated:     identity.Version < m.minVersion,
+			WrongNetwork: identity.NemesisHash != *m.nemesisHash,
+		}
+
+		if mismatch.Outdated || mismatch.WrongNetwork {
+			result = append(result, mismatch)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return nodeName(result[i].Node) < nodeName(result[j].Node)
+	})
+
+	return result
+}
+
+// versions returns the last reported version of every node, keyed by identity key.
+func (m *IdentityMonitor) versions() map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	versions := make(map[string]string, len(m.identities))
+	for key, identity := range m.identities {
+		versions[key] = formatVersion(identity.Version)
+	}
+
+	return versions
+}
+
+func (m *IdentityMonitor) version(node health.NodeInfo) string {
+	if m == nil {
+		return ""
+	}
+
+	identity, exists := m.identities[node.IdentityKey.String()]
+	if !exists {
+		return ""
+	}
+
+	return formatVersion(identity.Version)
+}
+
+// handleIdentities alerts on configured nodes that report an outdated version or a different nemesis block.
+// Nodes under maintenance are skipped, as they are expected to be upgraded there.
+func (am *AlertManager) handleIdentities(identities map[string]NodeIdentity) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	now := time.Now()
+	m := am.identities
+	m.update(now, identities)
+
+	alert := IdentityAlert{NemesisHash: *m.nemesisHash}
+	if m.minVersion != 0 {
+		alert.MinVersion = formatVersion(m.minVersion)
+	}
+
+	for _, mismatch := range m.mismatches(am.nodeInfos) {
+		if am.maintenance.inMaintenance(mismatch.Node.Endpoint, now) {
+			continue
+		}
+
+		log.Printf("node %s reports version %s and nemesis hash %s", mismatch.Node.Endpoint, mismatch.Version, mismatch.NemesisHash)
+
+		key := mismatch.Node.IdentityKey.String()
+		if now.Sub(m.lastAlertTimes[key]) > am.incidentMode.repeatInterval(m.alertRepeatInterval) {
+			alert.Nodes = append(alert.Nodes, mismatch)
+			m.lastAlertTimes[key] = now
+		}
+	}
+
+	if len(alert.Nodes) > 0 {
+		am.sendToTelegram(alert)
+	}
+}
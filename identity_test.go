@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	version, err := parseVersion("1.2.0.3")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0x01020003), version)
+	assert.Equal(t, "1.2.0.3", formatVersion(version))
+
+	version, err = parseVersion("1.2")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0.0", formatVersion(version))
+
+	_, err = parseVersion("1.256")
+	require.ErrorIs(t, err, ErrInvalidIdentityConfig)
+
+	_, err = parseVersion("1.2.3.4.5")
+	require.ErrorIs(t, err, ErrInvalidIdentityConfig)
+
+	_, _, err = parseIdentityConfig(IdentityConfig{NemesisHash: "abc"})
+	require.ErrorIs(t, err, ErrInvalidIdentityConfig)
+}
+
+func TestIdentityMonitor(t *testing.T) {
+	nemesis := sdk.Hash{9}
+
+	t.Run("Pool identities", func(t *testing.T) {
+		node := newTestNode(t, 10, sdk.Hash{1})
+		node.nemesis = nemesis
+		node.version = 0x01020003
+
+		pool := newTestNodePool(t)
+		_, err := pool.ConnectToNodes([]*health.NodeInfo{node.info()}, false)
+		require.NoError(t, err)
+
+		identities := pool.Identities()
+		assert.Equal(t, NodeIdentity{Version: 0x01020003, NetworkIdentifier: 0xb8, NemesisHash: nemesis}, identities[node.keyPair.PublicKey.String()])
+	})
+
+	t.Run("Mismatches", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		am.identities = &IdentityMonitor{
+			minVersion:          0x01020000,
+			nemesisHash:         &nemesis,
+			alertRepeatInterval: time.Hour,
+			identities:          make(map[string]NodeIdentity),
+			lastAlertTimes:      make(map[string]time.Time),
+		}
+
+		nodeA, nodeB, nodeC := am.nodeInfos[0], am.nodeInfos[1], am.nodeInfos[2]
+		am.handleIdentities(map[string]NodeIdentity{
+			nodeA.IdentityKey.String(): {Version: 0x01020000, NemesisHash: nemesis},
+			nodeB.IdentityKey.String(): {Version: 0x01010000, NemesisHash: nemesis},
+			nodeC.IdentityKey.String(): {Version: 0x01030000, NemesisHash: sdk.Hash{8}},
+		})
+
+		mismatches := am.identities.mismatches(am.nodeInfos)
+		require.Len(t, mismatches, 2)
+		for _, mismatch := range mismatches {
+			switch mismatch.Node.IdentityKey.String() {
+			case nodeB.IdentityKey.String():
+				assert.True(t, mismatch.Outdated)
+				assert.False(t, mismatch.WrongNetwork)
+			case nodeC.IdentityKey.String():
+				assert.False(t, mismatch.Outdated)
+				assert.True(t, mismatch.WrongNetwork)
+			default:
+				t.Errorf("unexpected mismatch for %s", mismatch.Node.Endpoint)
+			}
+		}
+
+		// Both were alerted on and are not repeated within the repeat interval.
+		assert.Len(t, am.identities.lastAlertTimes, 2)
+
+		alert := IdentityAlert{MinVersion: "1.2.0.0", NemesisHash: nemesis, Nodes: mismatches}
+		msg := alert.createMessage()
+		assert.Contains(t, msg, "Below minimum version 1.2.0.0 (1)")
+		assert.Contains(t, msg, "1.1.0.0")
+		assert.Contains(t, msg, "Nemesis block differs from "+nemesis.String()+" (1)")
+
+		assert.Equal(t, "1.2.0.0", am.identities.version(*nodeA))
+	})
+
+	t.Run("Version column", func(t *testing.T) {
+		nodeInfos, err := parseNodes([]Node{
+			{Endpoint: "10.0.0.1:7900", IdentityKey: "AF7A80E9D6C2A4F5B46B90A1D16E95D4C1B8A3E8D5D1479D7C802C475D70A2E", FriendlyName: "nodeA"},
+			{Endpoint: "10.0.0.2:7900", IdentityKey: "BF7A80E9D6C2A4F5B46B90A1D16E95D4C1B8A3E8D5D1479D7C802C475D70A2E", FriendlyName: "nodeB"},
+		})
+		require.NoError(t, err)
+
+		alert := SyncAlert{
+			Height:     100,
+			Reached:    map[health.NodeInfo]uint64{*nodeInfos[0]: 100},
+			NotReached: map[health.NodeInfo]uint64{*nodeInfos[1]: 90},
+		}
+		assert.NotContains(t, alert.createMessage(), "1.2.0.0")
+
+		alert.Versions = map[string]string{
+			nodeInfos[0].IdentityKey.String(): "1.2.0.0",
+			nodeInfos[1].IdentityKey.String(): "1.1.0.0",
+		}
+		msg := alert.createMessage()
+		assert.Contains(t, msg, "1.2.0.0")
+		assert.Contains(t, msg, "1.1.0.0")
+	})
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	PagerDutyProvider = "pagerduty"
+	OpsgenieProvider  = "opsgenie"
+
+	DefaultPagerDutyApiUrl = "https://events.pagerduty.com/v2/enqueue"
+	DefaultOpsgenieApiUrl  = "https://api.opsgenie.com/v2/alerts"
+
+	incidentSource = "go-xpx-check-fork-util"
+)
+
+type (
+	// Pager opens and resolves incidents on an external paging service.
+	Pager interface {
+		trigger(dedupKey, summary string) error
+		resolve(dedupKey string) error
+	}
+
+	PagerDutyPager struct {
+		routingKey string
+		url        string
+		client     *http.Client
+	}
+
+	OpsgeniePager struct {
+		apiKey string
+		url    string
+		client *http.Client
+	}
+
+	pagerDutyEvent struct {
+		RoutingKey  string            `json:"routing_key"`
+		EventAction string            `json:"event_action"`
+		DedupKey    string            `json:"dedup_key"`
+		Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	}
+
+	pagerDutyPayload struct {
+		Summary  string `json:"summary"`
+		Source   string `json:"source"`
+		Severity string `json:"severity"`
+	}
+
+	opsgenieAlert struct {
+		Message  string `json:"message"`
+		Alias    string `json:"alias"`
+		Priority string `json:"priority"`
+		Source   string `json:"source"`
+	}
+
+	opsgenieClose struct {
+		Source string `json:"source"`
+	}
+)
+
+func newPager(config IncidentConfig) Pager {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	switch config.Provider {
+	case PagerDutyProvider:
+		apiUrl := config.ApiUrl
+		if apiUrl == "" {
+			apiUrl = DefaultPagerDutyApiUrl
+		}
+
+		return &PagerDutyPager{routingKey: config.RoutingKey, url: apiUrl, client: client}
+	case OpsgenieProvider:
+		apiUrl := config.ApiUrl
+		if apiUrl == "" {
+			apiUrl = DefaultOpsgenieApiUrl
+		}
+
+		return &OpsgeniePager{apiKey: config.ApiKey, url: apiUrl, client: client}
+	default:
+		return nil
+	}
+}
+
+// incidentKey builds the deduplication key used to match a resolve with its trigger.
+func incidentKey(kind string, height uint64) string {
+	return fmt.Sprintf("%s-%s-%d", incidentSource, kind, height)
+}
+
+func (p *PagerDutyPager) trigger(dedupKey, summary string) error {
+	return p.send(pagerDutyEvent{
+		RoutingKey:  p.routingKey,
+		EventAction: "trigger",
+		DedupKey:    dedupKey,
+		Payload: &pagerDutyPayload{
+			Summary:  summary,
+			Source:   incidentSource,
+			Severity: "critical",
+		},
+	})
+}
+
+func (p *PagerDutyPager) resolve(dedupKey string) error {
+	return p.send(pagerDutyEvent{
+		RoutingKey:  p.routingKey,
+		EventAction: "resolve",
+		DedupKey:    dedupKey,
+	})
+}
+
+func (p *PagerDutyPager) send(event pagerDutyEvent) error {
+	return postJSON(p.client, p.url, nil, event)
+}
+
+func (p *OpsgeniePager) trigger(dedupKey, summary string) error {
+	// Opsgenie rejects messages longer than 130 characters.
+	if len(summary) > 130 {
+		summary = summary[:130]
+	}
+
+	return postJSON(p.client, p.url, p.headers(), opsgenieAlert{
+		Message:  summary,
+		Alias:    dedupKey,
+		Priority: "P1",
+		Source:   incidentSource,
+	})
+}
+
+func (p *OpsgeniePager) resolve(dedupKey string) error {
+	closeUrl := fmt.Sprintf("%s/%s/close?identifierType=alias", p.url, url.PathEscape(dedupKey))
+	return postJSON(p.client, closeUrl, p.headers(), opsgenieClose{Source: incidentSource})
+}
+
+func (p *OpsgeniePager) headers() map[string]string {
+	return map[string]string{"Authorization": "GenieKey " + p.apiKey}
+}
+
+func postJSON(client *http.Client, url string, headers map[string]string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request to %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d from %s: %s", resp.StatusCode, url, respBody)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePager struct {
+	triggered []string
+	resolved  []string
+}
+
+func (p *fakePager) trigger(dedupKey, summary string) error {
+	p.triggered = append(p.triggered, dedupKey)
+	return nil
+}
+
+func (p *fakePager) resolve(dedupKey string) error {
+	p.resolved = append(p.resolved, dedupKey)
+	return nil
+}
+
+func newIncidentTestAlertManager(t *testing.T, pager Pager) *AlertManager {
+	config, err := LoadConfig("sample.config.json")
+	require.NoError(t, err)
+
+	nodeInfos, err := parseNodes(config.Nodes)
+	require.NoError(t, err)
+
+	return &AlertManager{
+		config:           config.AlertConfig,
+		lastAlertTimes:   make(map[AlertType]time.Time),
+		offlineNodeStats: make(map[string]NodeStatus),
+		nodeInfos:        nodeInfos,
+		notifier:         &Notifier{enabled: false},
+		pager:            pager,
+		openIncidents:    make(map[AlertType]string),
+		heights:          NewHeightTracker(DefaultHeightRegressionTolerance),
+	}
+}
+
+func TestPagerDutyPager(t *testing.T) {
+	var events []pagerDutyEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event pagerDutyEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	pager := newPager(IncidentConfig{Provider: PagerDutyProvider, RoutingKey: "routing-key", ApiUrl: server.URL})
+
+	require.NoError(t, pager.trigger("fork-100", "Fork detected"))
+	require.NoError(t, pager.resolve("fork-100"))
+
+	require.Len(t, events, 2)
+	assert.Equal(t, "trigger", events[0].EventAction)
+	assert.Equal(t, "routing-key", events[0].RoutingKey)
+	assert.Equal(t, "fork-100", events[0].DedupKey)
+	assert.Equal(t, "Fork detected", events[0].Payload.Summary)
+	assert.Equal(t, "resolve", events[1].EventAction)
+	assert.Equal(t, "fork-100", events[1].DedupKey)
+	assert.Nil(t, events[1].Payload)
+}
+
+func TestOpsgeniePager(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GenieKey api-key", r.Header.Get("Authorization"))
+		paths = append(paths, r.URL.RequestURI())
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	pager := newPager(IncidentConfig{Provider: OpsgenieProvider, ApiKey: "api-key", ApiUrl: server.URL})
+
+	require.NoError(t, pager.trigger("stuck-100", "Chain is stuck"))
+	require.NoError(t, pager.resolve("stuck-100"))
+
+	assert.Equal(t, []string{"/", "/stuck-100/close?identifierType=alias"}, paths)
+}
+
+func TestPagerErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	pager := newPager(IncidentConfig{Provider: PagerDutyProvider, RoutingKey: "routing-key", ApiUrl: server.URL})
+	require.Error(t, pager.trigger("fork-100", "Fork detected"))
+}
+
+func TestIncidentLifecycle(t *testing.T) {
+	t.Run("Fork incident", func(t *testing.T) {
+		pager := &fakePager{}
+		am := newIncidentTestAlertManager(t, pager)
+
+		am.handleHashAlert(100, map[string]sdk.Hash{}, nil)
+		am.handleHashAlert(101, map[string]sdk.Hash{}, nil)
+		assert.Equal(t, []string{incidentKey("fork", 100)}, pager.triggered)
+
+		am.handleHashRecovery()
+		am.handleHashRecovery()
+		assert.Equal(t, []string{incidentKey("fork", 100)}, pager.resolved)
+	})
+
+	t.Run("Stuck incident", func(t *testing.T) {
+		pager := &fakePager{}
+		am := newIncidentTestAlertManager(t, pager)
+
+		checkpoint := uint64(1000)
+		notReached := map[health.NodeInfo]uint64{
+			*am.nodeInfos[0]: 999,
+		}
+
+		am.lastStuckHeight = checkpoint
+		am.lastStuckTime = time.Now().Add(-am.config.getStuckDurationThreshold() * 2)
+
+		am.handleSyncAlert(checkpoint, notReached, map[health.NodeInfo]uint64{})
+		assert.Equal(t, []string{incidentKey("stuck", checkpoint)}, pager.triggered)
+
+		am.handleSyncAlert(checkpoint, map[health.NodeInfo]uint64{}, map[health.NodeInfo]uint64{*am.nodeInfos[0]: checkpoint})
+		assert.Equal(t, []string{incidentKey("stuck", checkpoint)}, pager.resolved)
+	})
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"go-xpx-check-fork-util/internal/table"
+)
+
+const (
+	DefaultIncidentModeAlertRepeatInterval = time.Minute * 10
+	DefaultIncidentModeUpdateInterval      = time.Minute * 5
+	DefaultIncidentModeMaxDuration         = time.Hour * 6
+)
+
+type (
+	// IncidentMode relaxes alert throttling while an incident is in progress. It is entered automatically when a fork
+	// is detected and left once the hashes agree again, or declared and resolved manually through bot commands.
+	// It is shared with the command listener, so all access goes through its mutex.
+	IncidentMode struct {
+		config IncidentModeConfig
+
+		mu         sync.Mutex
+		active     bool
+		manual     bool
+		reason     string
+		since      time.Time
+		lastUpdate time.Time
+	}
+
+	IncidentModeStatus struct {
+		Reason string    `json:"reason"`
+		Manual bool      `json:"manual"`
+		Since  time.Time `json:"since"`
+	}
+
+	// IncidentModeAlert announces that incident mode was entered or left.
+	IncidentModeAlert struct {
+		Entered        bool
+		Manual         bool
+		Reason         string
+		Duration       time.Duration
+		RepeatInterval time.Duration
+		UpdateInterval time.Duration
+	}
+
+	// IncidentUpdateAlert is the periodic update streamed while incident mode is active.
+	IncidentUpdateAlert struct {
+		Reason    string
+		Since     time.Time
+		Height    uint64
+		Offline   []*health.NodeInfo
+		OutOfSync map[health.NodeInfo]uint64
+	}
+)
+
+func (a IncidentModeAlert) getType() AlertType {
+	return IncidentModeAlertType
+}
+
+func (a IncidentModeAlert) createMessage() string {
+	var buf bytes.Buffer
+
+	if !a.Entered {
+		fmt.Fprintf(&buf, "<b>✅ Incident mode ended </b>\n\n%s", a.Reason)
+		fmt.Fprintf(&buf, "\nLasted %s. Normal alert throttling is restored.", a.Duration.Round(time.Second))
+		return buf.String()
+	}
+
+	fmt.Fprintf(&buf, "<b>🚨 Incident mode </b>\n\n")
+	if a.Manual {
+		fmt.Fprintf(&buf, "Declared: %s", a.Reason)
+	} else {
+		fmt.Fprintf(&buf, "Entered automatically: %s", a.Reason)
+	}
+	fmt.Fprintf(&buf, "\nAlerts repeat every %s and updates are sent every %s until it is resolved.", a.RepeatInterval, a.UpdateInterval)
+
+	return buf.String()
+}
+
+func (a IncidentUpdateAlert) getType() AlertType {
+	return IncidentUpdateAlertType
+}
+
+func (a IncidentUpdateAlert) createMessage() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "<b>🚨 Incident update </b>\n\n%s", a.Reason)
+	fmt.Fprintf(&buf, "\nOngoing for %s, checkpoint <b>%d</b>", time.Since(a.Since).Round(time.Second), a.Height)
+
+	if len(a.Offline) > 0 {
+		names := make([][]string, 0, len(a.Offline))
+		for _, node := range a.Offline {
+			names = append(names, []string{nodeName(*node)})
+		}
+
+		fmt.Fprintf(&buf, "\n\nOffline (%d):<pre>%s</pre>", len(names), table.Render(names, table.Options{Sort: true}))
+	}
+
+	if len(a.OutOfSync) > 0 {
+		var lines [][]string
+		for _, node := range sortedNodes(a.OutOfSync) {
+			lines = append(lines, []string{node.Name, strconv.FormatUint(node.Height, 10)})
+		}
+
+		fmt.Fprintf(&buf, "\n\nOut-of-sync (%d):<pre>%s</pre>", len(lines), table.Render(lines, table.Options{Align: []table.Align{table.AlignLeft, table.AlignRight}}))
+	}
+
+	return buf.String()
+}
+
+func (a IncidentUpdateAlert) redact(r *Redactor) Alert {
+	offline := make([]*health.NodeInfo, 0, len(a.Offline))
+	for _, node := range a.Offline {
+		redacted := r.node(*node)
+		offline = append(offline, &redacted)
+	}
+
+	a.Offline = offline
+	a.OutOfSync = r.nodeHeights(a.OutOfSync)
+	return a
+}
+
+func NewIncidentMode(config IncidentModeConfig) *IncidentMode {
+	return &IncidentMode{config: config}
+}
+
+// enter activates incident mode and reports whether it was inactive before. A manual declaration takes over an
+// automatic one, so that it is no longer left when the fork resolves.
+func (m *IncidentMode) enter(reason string, manual bool, now time.Time) bool {
+	if m == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.active {
+		if manual && !m.manual {
+			m.manual = true
+			m.reason = reason
+		}
+		return false
+	}
+
+	m.active = true
+	m.manual = manual
+	m.reason = reason
+	m.since = now
+	m.lastUpdate = now
+
+	return true
+}
+
+// exit deactivates incident mode and returns how long it lasted. An automatic exit leaves a manually declared
+// incident mode alone.
+func (m *IncidentMode) exit(manual bool, now time.Time) (time.Duration, bool) {
+	if m == nil {
+		return 0, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.active || (m.manual && !manual) {
+		return 0, false
+	}
+
+	m.active = false
+	m.manual = false
+
+	return now.Sub(m.since), true
+}
+
+func (m *IncidentMode) isActive() bool {
+	if m == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.active
+}
+
+// repeatInterval shortens the normal repeat interval of an alert while incident mode is active.
+func (m *IncidentMode) repeatInterval(normal time.Duration) time.Duration {
+	if !m.isActive() {
+		return normal
+	}
+
+	if shortened := m.config.getAlertRepeatInterval(); shortened < normal {
+		return shortened
+	}
+
+	return normal
+}
+
+// updateDue reports whether the next periodic update should be sent, and marks it as sent if so.
+func (m *IncidentMode) updateDue(now time.Time) bool {
+	if m == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.active || now.Sub(m.lastUpdate) < m.config.getUpdateInterval() {
+		return false
+	}
+
+	m.lastUpdate = now
+	return true
+}
+
+// expired reports whether a manually declared incident mode has been active for longer than the maximum duration.
+func (m *IncidentMode) expired(now time.Time) bool {
+	if m == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.active && m.manual && now.Sub(m.since) > m.config.getMaxDuration()
+}
+
+func (m *IncidentMode) snapshot() *IncidentModeStatus {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.active {
+		return nil
+	}
+
+	return &IncidentModeStatus{Reason: m.reason, Manual: m.manual, Since: m.since}
+}
+
+// enterIncidentMode activates incident mode and announces it. It only touches the incident mode and sends untracked
+// alerts, so the command listener may call it as well.
+func (am *AlertManager) enterIncidentMode(reason string, manual bool) {
+	if !am.incidentMode.enter(reason, manual, time.Now()) {
+		return
+	}
+
+	log.Printf("Entered incident mode: %s", reason)
+	am.sendUntracked(IncidentModeAlert{
+		Entered:        true,
+		Manual:         manual,
+		Reason:         reason,
+		RepeatInterval: am.incidentMode.config.getAlertRepeatInterval(),
+		UpdateInterval: am.incidentMode.config.getUpdateInterval(),
+	})
+}
+
+func (am *AlertManager) exitIncidentMode(reason string, manual bool) {
+	duration, exited := am.incidentMode.exit(manual, time.Now())
+	if !exited {
+		return
+	}
+
+	log.Printf("Left incident mode: %s", reason)
+	am.sendUntracked(IncidentModeAlert{Reason: reason, Duration: duration})
+}
+
+// handleIncidentMode streams updates while incident mode is active, based on the results of the last check cycle.
+func (am *AlertManager) handleIncidentMode(checkpoint uint64) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	now := time.Now()
+
+	if am.incidentMode.expired(now) {
+		am.exitIncidentMode(fmt.Sprintf("Not resolved within %s", am.incidentMode.config.getMaxDuration()), true)
+		return
+	}
+
+	if !am.incidentMode.updateDue(now) {
+		return
+	}
+
+	status := am.incidentMode.snapshot()
+	if status == nil {
+		return
+	}
+
+	update := IncidentUpdateAlert{
+		Reason:    status.Reason,
+		Since:     status.Since,
+		Height:    checkpoint,
+		OutOfSync: am.lastNotReached,
+	}
+
+	for _, node := range am.lastFailedConnections {
+		update.Offline = append(update.Offline, node)
+	}
+
+	am.sendToTelegram(update)
+}
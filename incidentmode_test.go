@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncidentMode(t *testing.T) {
+	now := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Repeat interval", func(t *testing.T) {
+		mode := NewIncidentMode(IncidentModeConfig{AlertRepeatInterval: "10m"})
+		assert.Equal(t, 2*time.Hour, mode.repeatInterval(2*time.Hour))
+
+		require.True(t, mode.enter("fork", false, now))
+		assert.Equal(t, 10*time.Minute, mode.repeatInterval(2*time.Hour))
+		assert.Equal(t, 5*time.Minute, mode.repeatInterval(5*time.Minute))
+
+		var disabled *IncidentMode
+		assert.Equal(t, 2*time.Hour, disabled.repeatInterval(2*time.Hour))
+	})
+
+	t.Run("Automatic exit leaves manual mode alone", func(t *testing.T) {
+		mode := NewIncidentMode(IncidentModeConfig{})
+		require.True(t, mode.enter("fork", false, now))
+		require.False(t, mode.enter("declared", true, now))
+
+		_, exited := mode.exit(false, now.Add(time.Minute))
+		assert.False(t, exited)
+		assert.Equal(t, &IncidentModeStatus{Reason: "declared", Manual: true, Since: now}, mode.snapshot())
+
+		duration, exited := mode.exit(true, now.Add(time.Hour))
+		require.True(t, exited)
+		assert.Equal(t, time.Hour, duration)
+		assert.Nil(t, mode.snapshot())
+	})
+
+	t.Run("Updates and expiry", func(t *testing.T) {
+		mode := NewIncidentMode(IncidentModeConfig{UpdateInterval: "5m", MaxDuration: "1h"})
+		assert.False(t, mode.updateDue(now))
+
+		mode.enter("declared", true, now)
+		assert.False(t, mode.updateDue(now.Add(time.Minute)))
+		assert.True(t, mode.updateDue(now.Add(6*time.Minute)))
+		assert.False(t, mode.updateDue(now.Add(7*time.Minute)))
+
+		assert.False(t, mode.expired(now.Add(30*time.Minute)))
+		assert.True(t, mode.expired(now.Add(2*time.Hour)))
+	})
+
+	t.Run("Fork enters and recovery leaves", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		am.incidentMode = NewIncidentMode(IncidentModeConfig{})
+
+		am.handleHashAlert(10, map[string]sdk.Hash{"a": {1}, "b": {2}}, nil)
+		status := am.incidentMode.snapshot()
+		require.NotNil(t, status)
+		assert.False(t, status.Manual)
+		assert.Contains(t, status.Reason, "height 10")
+
+		am.handleHashRecovery()
+		assert.False(t, am.incidentMode.isActive())
+	})
+
+	t.Run("Commands", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		am.incidentMode = NewIncidentMode(IncidentModeConfig{})
+		listener := NewCommandListener(nil, -1, am)
+
+		assert.Equal(t, "Incident mode is not active.", listener.handle("resolve", "", "@ops"))
+
+		assert.Empty(t, listener.handle("incident", "API nodes down", "@ops"))
+		assert.Equal(t, "API nodes down (by @ops)", am.incidentMode.snapshot().Reason)
+		assert.Contains(t, listener.handle("incident", "again", "@ops"), "already active")
+
+		// A fork recovering does not end a declared incident.
+		am.handleHashRecovery()
+		assert.True(t, am.incidentMode.isActive())
+
+		assert.Empty(t, listener.handle("resolve", "", "@ops"))
+		assert.False(t, am.incidentMode.isActive())
+	})
+}
@@ -0,0 +1,219 @@
+// Package table renders the aligned plain-text tables and node names of Telegram HTML messages, which are shown in
+// <pre> blocks with a monospace font.
+package table
+
+import (
+	"html"
+	"net"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignRight
+)
+
+// ellipsis marks a truncated cell.
+const ellipsis = "…"
+
+// Options controls how a table is rendered. The zero value renders the rows as given, HTML-escaped, with columns
+// as wide as their widest cell.
+type Options struct {
+	// Header is an optional first row, never sorted or truncated.
+	Header []string
+	// MaxWidth is the maximum width of a column in characters. Longer cells are truncated with an ellipsis, or
+	// wrapped if Wrap is set. 0 means no limit.
+	MaxWidth int
+	Wrap     bool
+	// Sort sorts the rows by their cells, first column first.
+	Sort bool
+	// Align is the alignment of each column. Columns without one are left-aligned.
+	Align []Align
+	// Raw leaves the cells unescaped, for output that is not sent as HTML.
+	Raw bool
+}
+
+// Render renders the rows as lines of columns separated by a space, with trailing spaces removed and no final line
+// break. Widths are counted in characters before escaping, so the columns line up once Telegram has unescaped them.
+func Render(rows [][]string, opts Options) string {
+	if len(rows) == 0 && len(opts.Header) == 0 {
+		return ""
+	}
+
+	body := make([][]string, len(rows))
+	copy(body, rows)
+	if opts.Sort {
+		sort.SliceStable(body, func(i, j int) bool {
+			return less(body[i], body[j])
+		})
+	}
+
+	var lines [][]string
+	if len(opts.Header) > 0 {
+		lines = append(lines, opts.Header)
+	}
+	for _, row := range body {
+		lines = append(lines, fitRow(row, opts)...)
+	}
+
+	var widths []int
+	for _, line := range lines {
+		for i, cell := range line {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if width := Width(cell); width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	rendered := make([]string, 0, len(lines))
+	for _, line := range lines {
+		var cells []string
+		for i := range widths {
+			var cell string
+			if i < len(line) {
+				cell = line[i]
+			}
+
+			align := AlignLeft
+			if i < len(opts.Align) {
+				align = opts.Align[i]
+			}
+			cells = append(cells, pad(cell, widths[i], align, opts.Raw))
+		}
+
+		rendered = append(rendered, strings.TrimRight(strings.Join(cells, " "), " "))
+	}
+
+	return strings.Join(rendered, "\n")
+}
+
+// fitRow applies the maximum width to the cells of a row, returning more than one line if a cell is wrapped.
+func fitRow(row []string, opts Options) [][]string {
+	if opts.MaxWidth <= 0 {
+		return [][]string{row}
+	}
+
+	if !opts.Wrap {
+		fitted := make([]string, len(row))
+		for i, cell := range row {
+			fitted[i] = Truncate(cell, opts.MaxWidth)
+		}
+		return [][]string{fitted}
+	}
+
+	var lines [][]string
+	for i, cell := range row {
+		for j, part := range split(cell, opts.MaxWidth) {
+			if j == len(lines) {
+				lines = append(lines, make([]string, len(row)))
+			}
+			lines[j][i] = part
+		}
+	}
+
+	return lines
+}
+
+func less(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+func pad(cell string, width int, align Align, raw bool) string {
+	padding := strings.Repeat(" ", width-Width(cell))
+	if !raw {
+		cell = Escape(cell)
+	}
+
+	if align == AlignRight {
+		return padding + cell
+	}
+	return cell + padding
+}
+
+// split cuts a string into parts of at most width characters.
+func split(s string, width int) []string {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return []string{""}
+	}
+
+	var parts []string
+	for len(runes) > width {
+		parts = append(parts, string(runes[:width]))
+		runes = runes[width:]
+	}
+
+	return append(parts, string(runes))
+}
+
+// Width returns the number of characters in a string.
+func Width(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// Truncate shortens a string to at most width characters, ending it with an ellipsis if anything was cut.
+func Truncate(s string, width int) string {
+	if width <= 0 || Width(s) <= width {
+		return s
+	}
+
+	runes := []rune(s)
+	return string(runes[:width-1]) + ellipsis
+}
+
+// PadRight pads a string with spaces to the given width in characters.
+func PadRight(width int, s string) string {
+	if n := width - Width(s); n > 0 {
+		return s + strings.Repeat(" ", n)
+	}
+	return s
+}
+
+// PadLeft pads a string with leading spaces to the given width in characters.
+func PadLeft(width int, s string) string {
+	if n := width - Width(s); n > 0 {
+		return strings.Repeat(" ", n) + s
+	}
+	return s
+}
+
+// Escape escapes the characters that Telegram's HTML parse mode would take for markup.
+func Escape(s string) string {
+	return html.EscapeString(s)
+}
+
+// Abbreviate shortens a DNS name to its first label, dropping the port. IP addresses are kept whole.
+func Abbreviate(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return host
+	}
+
+	return strings.Split(host, ".")[0]
+}
+
+// NodeName formats a node as "friendlyName(host)", or just the host if the node has no distinct friendly name.
+func NodeName(friendlyName, endpoint string) string {
+	host := Abbreviate(endpoint)
+	if friendlyName != "" && strings.TrimSpace(friendlyName) != strings.TrimSpace(host) {
+		return friendlyName + "(" + host + ")"
+	}
+
+	return host
+}
@@ -0,0 +1,195 @@
+package table
+
+import (
+	"html"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cells cleans generated rows so every row has the same number of cells without line breaks or surrounding spaces,
+// which the renderer does not claim to handle.
+func cells(rows [][]string) [][]string {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	columns := len(rows[0])%4 + 1
+	cleaned := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		clean := make([]string, columns)
+		for i := range clean {
+			if i < len(row) {
+				clean[i] = strings.TrimSpace(strings.NewReplacer("\n", "", "\r", "").Replace(row[i]))
+			}
+		}
+		cleaned = append(cleaned, clean)
+	}
+
+	return cleaned
+}
+
+func lines(rendered string) []string {
+	return strings.Split(rendered, "\n")
+}
+
+func TestRenderProperties(t *testing.T) {
+	config := &quick.Config{MaxCount: 500}
+
+	t.Run("Columns line up", func(t *testing.T) {
+		property := func(rows [][]string) bool {
+			rows = cells(rows)
+			rendered := Render(rows, Options{Raw: true})
+			if len(rows) == 0 {
+				return rendered == ""
+			}
+
+			widths := make([]int, len(rows[0]))
+			for _, row := range rows {
+				for i, cell := range row {
+					if Width(cell) > widths[i] {
+						widths[i] = Width(cell)
+					}
+				}
+			}
+
+			for i, line := range lines(rendered) {
+				runes := []rune(line)
+				offset := 0
+				for j, cell := range rows[i] {
+					// Trailing spaces are trimmed, so the line may end before the last cells.
+					start, end := offset, offset+widths[j]
+					if start > len(runes) {
+						start = len(runes)
+					}
+					if end > len(runes) {
+						end = len(runes)
+					}
+					if strings.TrimRight(string(runes[start:end]), " ") != cell {
+						return false
+					}
+					offset += widths[j] + 1
+				}
+			}
+			return true
+		}
+		require.NoError(t, quick.Check(property, config))
+	})
+
+	t.Run("Escaped output unescapes to the raw output", func(t *testing.T) {
+		property := func(rows [][]string, sorted bool, width uint8) bool {
+			rows = cells(rows)
+			options := Options{Sort: sorted, MaxWidth: int(width % 16)}
+
+			escaped := Render(rows, options)
+			options.Raw = true
+			return !strings.ContainsAny(escaped, "<>") && html.UnescapeString(escaped) == Render(rows, options)
+		}
+		require.NoError(t, quick.Check(property, config))
+	})
+
+	t.Run("Truncated cells fit the width", func(t *testing.T) {
+		property := func(rows [][]string, width uint8) bool {
+			rows = cells(rows)
+			maxWidth := int(width%16) + 1
+			rendered := Render(rows, Options{MaxWidth: maxWidth, Raw: true})
+			if !utf8.ValidString(rendered) {
+				return false
+			}
+
+			for _, line := range lines(rendered) {
+				if len(rows) > 0 && Width(line) > len(rows[0])*(maxWidth+1)-1 {
+					return false
+				}
+			}
+			return true
+		}
+		require.NoError(t, quick.Check(property, config))
+	})
+
+	t.Run("Wrapped cells keep their content", func(t *testing.T) {
+		property := func(cell string, width uint8) bool {
+			cell = cells([][]string{{cell}})[0][0]
+			maxWidth := int(width%16) + 1
+			rendered := Render([][]string{{cell}}, Options{MaxWidth: maxWidth, Wrap: true, Raw: true})
+
+			// Trailing spaces of the lines are trimmed, so spaces are left out of the comparison.
+			var joined strings.Builder
+			for _, line := range lines(rendered) {
+				if Width(line) > maxWidth {
+					return false
+				}
+				joined.WriteString(line)
+			}
+			return strings.ReplaceAll(joined.String(), " ", "") == strings.ReplaceAll(cell, " ", "")
+		}
+		require.NoError(t, quick.Check(property, config))
+	})
+
+	t.Run("Sorting ignores the input order", func(t *testing.T) {
+		property := func(rows [][]string, seed int64) bool {
+			rows = cells(rows)
+			shuffled := make([][]string, len(rows))
+			copy(shuffled, rows)
+			rand.New(rand.NewSource(seed)).Shuffle(len(shuffled), func(i, j int) {
+				shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+			})
+
+			return Render(rows, Options{Sort: true}) == Render(shuffled, Options{Sort: true})
+		}
+		require.NoError(t, quick.Check(property, config))
+	})
+}
+
+func TestRender(t *testing.T) {
+	t.Run("Alignment and header", func(t *testing.T) {
+		rows := [][]string{{"nodeB(10.0.0.2)", "7"}, {"a", "1200"}}
+		options := Options{Header: []string{"Node", "Lag"}, Align: []Align{AlignLeft, AlignRight}}
+
+		assert.Equal(t, "Node             Lag\nnodeB(10.0.0.2)    7\na               1200", Render(rows, options))
+	})
+
+	t.Run("Edge-case names", func(t *testing.T) {
+		rows := [][]string{{"<b>&co</b>", "1"}, {"ünïcødé", "22"}, {"", "3"}}
+		rendered := Render(rows, Options{Sort: true})
+
+		assert.Equal(t, "           3\n&lt;b&gt;&amp;co&lt;/b&gt; 1\nünïcødé    22", rendered)
+	})
+
+	t.Run("Truncation", func(t *testing.T) {
+		assert.Equal(t, "nodeA(10.…", Render([][]string{{"nodeA(10.0.0.1)"}}, Options{MaxWidth: 10}))
+		assert.Equal(t, "ünï…", Render([][]string{{"ünïcødé"}}, Options{MaxWidth: 4}))
+	})
+
+	t.Run("Wrapping", func(t *testing.T) {
+		rendered := Render([][]string{{"nodeA(10.0.0.1)", "5"}}, Options{MaxWidth: 10, Wrap: true})
+		assert.Equal(t, "nodeA(10.0 5\n.0.1)", rendered)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		assert.Empty(t, Render(nil, Options{}))
+		assert.Equal(t, "Node", Render(nil, Options{Header: []string{"Node"}}))
+	})
+}
+
+func TestNodeName(t *testing.T) {
+	assert.Equal(t, "10.0.0.1", Abbreviate("10.0.0.1:7900"))
+	assert.Equal(t, "::1", Abbreviate("[::1]:7900"))
+	assert.Equal(t, "api", Abbreviate("api.example.com:7900"))
+	assert.Equal(t, "api", Abbreviate("api.example.com"))
+
+	assert.Equal(t, "nodeA(api)", NodeName("nodeA", "api.example.com:7900"))
+	assert.Equal(t, "api", NodeName(" api ", "api.example.com:7900"))
+	assert.Equal(t, "10.0.0.1", NodeName("", "10.0.0.1:7900"))
+}
+
+func TestPad(t *testing.T) {
+	assert.Equal(t, "ünï  ", PadRight(5, "ünï"))
+	assert.Equal(t, "  ünï", PadLeft(5, "ünï"))
+	assert.Equal(t, "toolong", PadRight(3, "toolong"))
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	lambdaRuntimeApiVersion = "2018-06-01"
+
+	// lambdaDeadlineMargin leaves time to save the state before the invocation times out.
+	lambdaDeadlineMargin = 10 * time.Second
+)
+
+type lambdaError struct {
+	ErrorMessage string `json:"errorMessage"`
+	ErrorType    string `json:"errorType"`
+}
+
+// runLambda serves invocations through the AWS Lambda runtime API, so the binary can be deployed as a custom runtime
+// ("bootstrap") and triggered on a schedule. It only returns if the runtime API fails.
+func runLambda(runtimeApi string, handler func(ctx context.Context) (interface{}, error)) error {
+	baseUrl := fmt.Sprintf("http://%s/%s/runtime/invocation/", runtimeApi, lambdaRuntimeApiVersion)
+
+	// The next invocation is long-polled, so the client must not time out.
+	client := &http.Client{}
+
+	for {
+		resp, err := client.Get(baseUrl + "next")
+		if err != nil {
+			return fmt.Errorf("failed getting next invocation: %w", err)
+		}
+
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("failed getting next invocation: status %d", resp.StatusCode)
+		}
+
+		requestId := resp.Header.Get("Lambda-Runtime-Aws-Request-Id")
+
+		ctx, cancel := context.Background(), func() {}
+		if deadline, err := strconv.ParseInt(resp.Header.Get("Lambda-Runtime-Deadline-Ms"), 10, 64); err == nil {
+			ctx, cancel = context.WithDeadline(ctx, time.UnixMilli(deadline).Add(-lambdaDeadlineMargin))
+		}
+
+		result, err := handler(ctx)
+		cancel()
+
+		if err != nil {
+			log.Printf("invocation %s failed: %v", requestId, err)
+			err = postLambdaResult(client, baseUrl+requestId+"/error", lambdaError{ErrorMessage: err.Error(), ErrorType: "CheckError"})
+		} else {
+			err = postLambdaResult(client, baseUrl+requestId+"/response", result)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func postLambdaResult(client *http.Client, url string, result interface{}) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed posting invocation result: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed posting invocation result: status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// handleInvocation is the Lambda handler: a single catch-up run of the checks, reporting where it stopped.
+func (fc *ForkChecker) handleInvocation(ctx context.Context) (interface{}, error) {
+	if err := fc.checkOnce(ctx); err != nil {
+		return nil, fmt.Errorf("failed to save state: %w", err)
+	}
+
+	return map[string]uint64{"checkpoint": fc.checkpoint}, nil
+}
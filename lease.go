@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+const DefaultLeaseDuration = 5 * time.Minute
+
+type (
+	// InstanceLease is kept in the state by the long-lived checker instance that sends the alerts. A second instance
+	// with the same state, e.g. left over from a botched deployment, finds the lease held and stands by instead of
+	// sending every alert twice. It takes over once the lease is no longer renewed.
+	InstanceLease struct {
+		Instance  string    `json:"instance"`
+		Host      string    `json:"host"`
+		StartedAt time.Time `json:"startedAt"`
+		RenewedAt time.Time `json:"renewedAt"`
+	}
+
+	// DuplicateInstanceAlert reports that this instance found another one holding the lease and stands by, or that
+	// it took over after the other one stopped renewing it.
+	DuplicateInstanceAlert struct {
+		Holder   InstanceLease
+		This     InstanceLease
+		TookOver bool
+	}
+)
+
+// newInstanceLease returns the lease of this instance, under the configured instance ID or a random one.
+func newInstanceLease(instanceID string, now time.Time) *InstanceLease {
+	if instanceID == "" {
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			log.Printf("failed to generate instance ID: %v", err)
+		}
+		instanceID = hex.EncodeToString(id)
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	return &InstanceLease{Instance: instanceID, Host: host, StartedAt: now.UTC()}
+}
+
+func (l InstanceLease) expired(now time.Time, duration time.Duration) bool {
+	return now.Sub(l.RenewedAt) > duration
+}
+
+// wins reports whether the lease takes precedence over another live one: the instance that started first keeps
+// running, so two instances started together settle on the same one.
+func (l InstanceLease) wins(other InstanceLease) bool {
+	if !l.StartedAt.Equal(other.StartedAt) {
+		return l.StartedAt.Before(other.StartedAt)
+	}
+	return l.Instance < other.Instance
+}
+
+func (l InstanceLease) String() string {
+	return fmt.Sprintf("%s on %s, started %s", l.Instance, l.Host, l.StartedAt.Format(time.RFC3339))
+}
+
+// checkLease reads the lease from the state store and reports whether this instance is the active one. An instance
+// that loses the lease alerts once and stands by, with every notification suppressed; when it takes over, it resumes
+// from the state saved by the previous holder.
+func (fc *ForkChecker) checkLease(now time.Time) bool {
+	if fc.lease == nil {
+		return true
+	}
+
+	state, err := fc.state.load()
+	if err != nil {
+		// Without the store, keep the current role rather than go silent or start sending twice.
+		log.Printf("failed to check the instance lease: %v", err)
+		return !fc.alertManager.standby.Load()
+	}
+
+	var holder InstanceLease
+	if state != nil && state.Lease != nil {
+		holder = *state.Lease
+	}
+
+	duration := fc.cfg.StateConfig.getLeaseDuration()
+	active := holder.Instance == "" || holder.Instance == fc.lease.Instance ||
+		holder.expired(now, duration) || fc.lease.wins(holder)
+
+	switch standby := fc.alertManager.standby.Load(); {
+	case active && standby:
+		log.Printf("Instance lease of %s expired, taking over", holder)
+		if state != nil {
+			fc.restoreState(state)
+		}
+		fc.alertManager.standby.Store(false)
+		fc.alertManager.sendUntracked(DuplicateInstanceAlert{Holder: holder, This: *fc.lease, TookOver: true})
+	case !active && !standby:
+		log.Printf("Instance lease is held by %s, standing by", holder)
+		fc.alertManager.sendUntracked(DuplicateInstanceAlert{Holder: holder, This: *fc.lease})
+		fc.alertManager.standby.Store(true)
+	}
+
+	return active
+}
+
+// renewLease saves the state if the lease has not been renewed for a third of its duration, so that it stays held
+// while the checks are paused.
+func (fc *ForkChecker) renewLease(now time.Time) {
+	if fc.lease == nil || now.Sub(fc.lease.RenewedAt) < fc.cfg.StateConfig.getLeaseDuration()/3 {
+		return
+	}
+
+	if err := fc.saveState(); err != nil {
+		log.Printf("failed to save state: %v", err)
+	}
+}
+
+// releaseLease saves the state without the lease of this instance when it stops, so that the instance replacing it,
+// e.g. in a deployment, does not stand by until the lease expires. The state is not saved again afterwards.
+func (fc *ForkChecker) releaseLease() error {
+	if fc.lease == nil || fc.alertManager.standby.Load() {
+		return nil
+	}
+
+	fc.stateMu.Lock()
+	defer fc.stateMu.Unlock()
+
+	fc.released = true
+	state := fc.exportState()
+	state.Lease = nil
+	return fc.state.save(state)
+}
+
+// exportLease returns the lease to save with the state, renewed, or nil outside of a long-lived instance.
+func (fc *ForkChecker) exportLease(now time.Time) *InstanceLease {
+	if fc.lease == nil {
+		return nil
+	}
+
+	fc.lease.RenewedAt = now
+	lease := *fc.lease
+	return &lease
+}
+
+func (a DuplicateInstanceAlert) getType() AlertType {
+	return DuplicateInstanceAlertType
+}
+
+func (a DuplicateInstanceAlert) createMessage() string {
+	var buf bytes.Buffer
+
+	if a.TookOver {
+		fmt.Fprintf(&buf, "<b>ℹ️ Checker instance took over </b>\n\n")
+		fmt.Fprintf(&buf, "The instance %s stopped renewing its lease. This instance (%s) sends the alerts from now on.", a.Holder, a.This)
+		return buf.String()
+	}
+
+	fmt.Fprintf(&buf, "<b>⚠️ Duplicate checker instance </b>\n\n")
+	fmt.Fprintf(&buf, "Another instance with the same state is running: %s.\n\n", a.Holder)
+	fmt.Fprintf(&buf, "This instance (%s) stands by without sending alerts, and takes over if the other one stops.", a.This)
+
+	return buf.String()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceLease(t *testing.T) {
+	now := time.Now()
+	config := StateConfig{Backend: FileStateBackend, File: filepath.Join(t.TempDir(), "state.json")}
+
+	newInstance := func(instanceID string, startedAt time.Time) *ForkChecker {
+		am := newIncidentTestAlertManager(t, nil)
+		am.hashStreaks = NewHashStreakTracker()
+
+		fc := &ForkChecker{cfg: Config{StateConfig: config}, alertManager: am, state: newStateStore(config)}
+		fc.lease = newInstanceLease(instanceID, startedAt)
+		return fc
+	}
+
+	first := newInstance("", now.Add(-time.Hour))
+	second := newInstance("", now)
+
+	t.Run("First instance takes the lease", func(t *testing.T) {
+		assert.True(t, first.checkLease(now))
+		first.checkpoint = 100
+		require.NoError(t, first.saveState())
+	})
+
+	t.Run("Second instance stands by", func(t *testing.T) {
+		assert.False(t, second.checkLease(now))
+		assert.True(t, second.alertManager.standby.Load())
+		assert.True(t, second.alertManager.paused(SyncAlert{}))
+		assert.False(t, second.alertManager.paused(DuplicateInstanceAlert{}))
+	})
+
+	t.Run("Earlier instance wins a race", func(t *testing.T) {
+		// Both started before seeing each other, and the second one saved last.
+		second.alertManager.standby.Store(false)
+		require.NoError(t, second.saveState())
+
+		// The first instance keeps saving, and the second one notices before its next save.
+		assert.True(t, first.checkLease(now))
+		require.NoError(t, first.saveState())
+		assert.False(t, second.checkLease(now))
+	})
+
+	t.Run("Take over after the lease expires", func(t *testing.T) {
+		later := now.Add(DefaultLeaseDuration + time.Minute)
+		assert.True(t, second.checkLease(later))
+		assert.False(t, second.alertManager.standby.Load())
+		assert.Equal(t, uint64(100), second.checkpoint)
+	})
+
+	t.Run("Released on shutdown", func(t *testing.T) {
+		require.NoError(t, first.releaseLease())
+
+		// Nothing is saved after the release, so the lease is not taken again.
+		require.NoError(t, first.saveState())
+
+		next := newInstance("", now)
+		assert.True(t, next.checkLease(now))
+		assert.False(t, next.alertManager.standby.Load())
+	})
+
+	t.Run("Restart with the same instance ID", func(t *testing.T) {
+		previous := newInstance("checker-1", now)
+		assert.True(t, previous.checkLease(now))
+		require.NoError(t, previous.saveState())
+
+		// The lease of the previous process has not expired, but it is its own.
+		restarted := newInstance("checker-1", now.Add(time.Minute))
+		assert.True(t, restarted.checkLease(now.Add(time.Minute)))
+		assert.False(t, restarted.alertManager.standby.Load())
+
+		assert.False(t, newInstance("checker-2", now.Add(time.Minute)).checkLease(now.Add(time.Minute)))
+	})
+
+	t.Run("Without a state store", func(t *testing.T) {
+		fc := &ForkChecker{alertManager: newIncidentTestAlertManager(t, nil)}
+		assert.True(t, fc.checkLease(now))
+		assert.Nil(t, fc.exportLease(now))
+	})
+
+	t.Run("Alert", func(t *testing.T) {
+		alert := DuplicateInstanceAlert{Holder: *first.lease, This: *second.lease}
+		assert.Contains(t, alert.createMessage(), first.lease.Instance)
+		assert.Contains(t, alert.createMessage(), "stands by")
+
+		alert.TookOver = true
+		assert.Contains(t, alert.createMessage(), "took over")
+	})
+}
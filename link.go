@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+)
+
+const (
+	DefaultLinkCheckInterval = time.Minute * 5
+	DefaultLinkRttThreshold  = time.Millisecond * 500
+	DefaultLinkMinThroughput = 64 // KB/s
+	DefaultLinkProbeHashes   = 200
+
+	// linkRttSamples is the number of round trips measured per probe; the fastest one is kept.
+	linkRttSamples = 3
+)
+
+type (
+	// LinkQuality is the result of probing the health connection to a node.
+	LinkQuality struct {
+		Rtt        time.Duration `json:"rtt"`
+		Throughput float64       `json:"throughput"` // bytes per second
+		Bytes      int           `json:"bytes"`
+		Degraded   bool          `json:"degraded"`
+		Problem    string        `json:"problem,omitempty"`
+	}
+
+	// LinkMonitor probes the connections to the nodes from time to time and flags degraded ones, as a slow link
+	// shows up as sync lag without an obvious cause.
+	LinkMonitor struct {
+		rttThreshold  time.Duration
+		minThroughput float64
+		checkInterval time.Duration
+		probeHashes   int
+
+		lastCheck time.Time
+		latest    map[string]LinkQuality
+	}
+
+	DegradedLink struct {
+		Name        string `json:"name"`
+		Endpoint    string `json:"endpoint"`
+		IdentityKey string `json:"identityKey"`
+		LinkQuality
+	}
+)
+
+// NewLinkMonitor returns nil if link probing is disabled.
+func NewLinkMonitor(config LinkQualityConfig) *LinkMonitor {
+	if !config.Enabled {
+		return nil
+	}
+
+	return &LinkMonitor{
+		rttThreshold:  config.getRttThreshold(),
+		minThroughput: float64(config.getMinThroughput()) * 1024,
+		checkInterval: config.getCheckInterval(),
+		probeHashes:   config.getProbeHashes(),
+		latest:        make(map[string]LinkQuality),
+	}
+}
+
+func (m *LinkMonitor) due(now time.Time) bool {
+	return m != nil && now.Sub(m.lastCheck) >= m.checkInterval
+}
+
+// assess flags a probe as degraded if its round trip is too slow or its throughput too low.
+func (m *LinkMonitor) assess(quality LinkQuality) LinkQuality {
+	var problems []string
+	if quality.Rtt > m.rttThreshold {
+		problems = append(problems, fmt.Sprintf("round trip %s", quality.Rtt.Round(time.Millisecond)))
+	}
+
+	if quality.Throughput < m.minThroughput {
+		problems = append(problems, fmt.Sprintf("throughput %.1f KB/s", quality.Throughput/1024))
+	}
+
+	quality.Degraded = len(problems) > 0
+	quality.Problem = strings.Join(problems, ", ")
+
+	return quality
+}
+
+// update replaces the results of the previous probe. Nodes that could not be probed are dropped, as they show up
+// as offline instead.
+func (m *LinkMonitor) update(now time.Time, probes map[string]LinkQuality) map[string]LinkQuality {
+	m.lastCheck = now
+	m.latest = make(map[string]LinkQuality, len(probes))
+
+	for key, quality := range probes {
+		m.latest[key] = m.assess(quality)
+	}
+
+	return m.latest
+}
+
+// degraded returns the configured nodes whose link was degraded in the last probe.
+func (m *LinkMonitor) degraded(nodeInfos []*health.NodeInfo) []DegradedLink {
+	if m == nil {
+		return nil
+	}
+
+	var result []DegradedLink
+	for _, info := range nodeInfos {
+		quality, exists := m.latest[info.IdentityKey.String()]
+		if !exists || !quality.Degraded {
+			continue
+		}
+
+		result = append(result, DegradedLink{
+			Name:        nodeName(*info),
+			Endpoint:    info.Endpoint,
+			IdentityKey: info.IdentityKey.String(),
+			LinkQuality: quality,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// handleLinkProbes records the results of a probe for the status and the digest.
+func (am *AlertManager) handleLinkProbes(probes map[string]LinkQuality) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	probes = am.links.update(time.Now(), probes)
+
+	for _, link := range am.links.degraded(am.nodeInfos) {
+		log.Printf("degraded link to %s: %s", link.Endpoint, link.Problem)
+	}
+
+	am.digest.observeLinks(am.nodeInfos, probes)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkMonitor(t *testing.T) {
+	t.Run("Probe", func(t *testing.T) {
+		node := newTestNode(t, 500, sdk.Hash{1})
+
+		pool := newTestNodePool(t)
+		_, err := pool.ConnectToNodes([]*health.NodeInfo{node.info()}, false)
+		require.NoError(t, err)
+
+		probes := pool.ProbeLinks(100)
+		quality, exists := probes[node.keyPair.PublicKey.String()]
+		require.True(t, exists)
+		assert.Equal(t, 8+100*32, quality.Bytes)
+		assert.Positive(t, quality.Rtt)
+		assert.Positive(t, quality.Throughput)
+	})
+
+	t.Run("Assess", func(t *testing.T) {
+		monitor := NewLinkMonitor(LinkQualityConfig{Enabled: true, RttThreshold: "100ms", MinThroughput: 10})
+
+		quality := monitor.assess(LinkQuality{Rtt: 20 * time.Millisecond, Throughput: 20 * 1024})
+		assert.False(t, quality.Degraded)
+
+		quality = monitor.assess(LinkQuality{Rtt: 300 * time.Millisecond, Throughput: 5 * 1024})
+		assert.True(t, quality.Degraded)
+		assert.Equal(t, "round trip 300ms, throughput 5.0 KB/s", quality.Problem)
+
+		assert.Nil(t, NewLinkMonitor(LinkQualityConfig{}))
+	})
+
+	t.Run("Status and digest", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		am.links = NewLinkMonitor(LinkQualityConfig{Enabled: true, RttThreshold: "100ms", MinThroughput: 10})
+		am.digest, _ = NewDigestCollector(DigestConfig{Interval: "24h"}, time.Now())
+		nodeA, nodeB := am.nodeInfos[0], am.nodeInfos[1]
+
+		for i := 0; i < 2; i++ {
+			am.handleLinkProbes(map[string]LinkQuality{
+				nodeA.IdentityKey.String(): {Rtt: 20 * time.Millisecond, Throughput: 20 * 1024},
+				nodeB.IdentityKey.String(): {Rtt: time.Duration(i+1) * 100 * time.Millisecond, Throughput: 20 * 1024},
+			})
+		}
+
+		degraded := am.links.degraded(am.nodeInfos)
+		require.Len(t, degraded, 1)
+		assert.Equal(t, nodeB.Endpoint, degraded[0].Endpoint)
+		assert.Equal(t, "round trip 200ms", degraded[0].Problem)
+
+		report := am.digest.report(time.Now())
+		for _, node := range report.Nodes {
+			assert.Equal(t, 2, node.LinkProbes)
+			if node.Node.Endpoint == nodeB.Endpoint {
+				assert.Equal(t, 1, node.DegradedProbes)
+			} else {
+				assert.Zero(t, node.DegradedProbes)
+			}
+		}
+		assert.Contains(t, report.createMessage(), "1/2")
+	})
+}
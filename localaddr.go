@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"net"
+)
+
+// validateLocalAddresses checks the local address groups of the pool config. The addresses themselves are resolved
+// when dialing, as interfaces may come up after the checker starts.
+func validateLocalAddresses(config PoolConfig, nodes []Node) error {
+	grouped := make(map[string]string)
+	for _, group := range config.LocalAddresses {
+		if group.Address == "" {
+			return fmt.Errorf("%w: local address group without an address", ErrInvalidPoolConfig)
+		}
+
+		for _, endpoint := range group.Nodes {
+			found := false
+			for _, node := range nodes {
+				if node.Endpoint == endpoint {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return fmt.Errorf("%w: local address %s: unknown node %s", ErrInvalidPoolConfig, group.Address, endpoint)
+			}
+
+			if address, exists := grouped[endpoint]; exists && address != group.Address {
+				return fmt.Errorf("%w: node %s is in the groups of both %s and %s", ErrInvalidPoolConfig, endpoint, address, group.Address)
+			}
+			grouped[endpoint] = group.Address
+		}
+	}
+
+	return nil
+}
+
+// localAddresses maps the endpoints of the grouped nodes to their local address.
+func (p PoolConfig) localAddresses() map[string]string {
+	addresses := make(map[string]string)
+	for _, group := range p.LocalAddresses {
+		for _, endpoint := range group.Nodes {
+			addresses[endpoint] = group.Address
+		}
+	}
+
+	return addresses
+}
+
+// resolveLocalAddress returns the address to bind a connection to, given either as an IP or as the name of a network
+// interface. An interface is bound to its first IPv4 address, or its first IPv6 one if it has none; Go only dials
+// the addresses of a node of the same family.
+func resolveLocalAddress(address string) (*net.TCPAddr, error) {
+	if ip := net.ParseIP(address); ip != nil {
+		return &net.TCPAddr{IP: ip}, nil
+	}
+
+	iface, err := net.InterfaceByName(address)
+	if err != nil {
+		return nil, fmt.Errorf("local address %s is neither an IP nor a network interface: %v", address, err)
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("local address %s: %v", address, err)
+	}
+
+	var ipv6 net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+
+		if ipNet.IP.To4() != nil {
+			return &net.TCPAddr{IP: ipNet.IP}, nil
+		}
+		if ipv6 == nil && !ipNet.IP.IsLinkLocalUnicast() {
+			ipv6 = ipNet.IP
+		}
+	}
+
+	if ipv6 == nil {
+		return nil, fmt.Errorf("local address %s: interface has no usable IP address", address)
+	}
+
+	return &net.TCPAddr{IP: ipv6}, nil
+}
+
+// localAddress returns the local address to dial a node from, or nil for the OS default. Discovered nodes use the
+// default local address of the pool.
+func (p *NodePool) localAddress(endpoint string) (*net.TCPAddr, error) {
+	address, exists := p.localAddresses[endpoint]
+	if !exists {
+		address = p.defaultLocalAddress
+	}
+
+	if address == "" {
+		return nil, nil
+	}
+
+	return resolveLocalAddress(address)
+}
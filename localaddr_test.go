@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateLocalAddresses(t *testing.T) {
+	nodes := []Node{{Endpoint: "10.0.0.1:7900"}, {Endpoint: "10.0.0.2:7900"}}
+
+	valid := PoolConfig{LocalAddresses: []LocalAddressGroup{
+		{Address: "eth1", Nodes: []string{"10.0.0.1:7900"}},
+		{Address: "192.168.1.5", Nodes: []string{"10.0.0.2:7900"}},
+	}}
+	require.NoError(t, validateLocalAddresses(valid, nodes))
+	assert.Equal(t, map[string]string{"10.0.0.1:7900": "eth1", "10.0.0.2:7900": "192.168.1.5"}, valid.localAddresses())
+
+	for name, groups := range map[string][]LocalAddressGroup{
+		"No address":   {{Nodes: []string{"10.0.0.1:7900"}}},
+		"Unknown node": {{Address: "eth1", Nodes: []string{"10.0.0.9:7900"}}},
+		"Two groups":   {{Address: "eth1", Nodes: []string{"10.0.0.1:7900"}}, {Address: "eth2", Nodes: []string{"10.0.0.1:7900"}}},
+	} {
+		err := validateLocalAddresses(PoolConfig{LocalAddresses: groups}, nodes)
+		assert.ErrorIs(t, err, ErrInvalidPoolConfig, name)
+	}
+}
+
+func TestResolveLocalAddress(t *testing.T) {
+	addr, err := resolveLocalAddress("127.0.0.2")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.2", addr.IP.String())
+
+	loopback := ""
+	interfaces, err := net.Interfaces()
+	require.NoError(t, err)
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			loopback = iface.Name
+		}
+	}
+
+	if loopback != "" {
+		addr, err := resolveLocalAddress(loopback)
+		require.NoError(t, err)
+		assert.True(t, addr.IP.IsLoopback())
+	}
+
+	_, err = resolveLocalAddress("no-such-interface0")
+	assert.Error(t, err)
+}
+
+func TestNodePoolLocalAddress(t *testing.T) {
+	nodeA := newTestNode(t, 10, sdk.Hash{1})
+	nodeB := newTestNode(t, 10, sdk.Hash{1})
+
+	pool := newTestNodePool(t)
+	pool.defaultLocalAddress = "127.0.0.1"
+	// An address that is not on this machine cannot be bound, so the node fails to connect.
+	pool.localAddresses = map[string]string{nodeB.info().Endpoint: "192.0.2.1"}
+
+	failed, err := pool.ConnectToNodes([]*health.NodeInfo{nodeA.info(), nodeB.info()}, false)
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Contains(t, failed, nodeB.info().IdentityKey.String())
+	assert.Len(t, pool.connections(), 1)
+}
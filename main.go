@@ -1,26 +1,76 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"log"
+	"os"
 )
 
+// exitUnhealthy is the exit code of the -once mode when a fork, a stuck chain or an offline node is found.
+const exitUnhealthy = 2
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "generate-config" {
+		if err := runGenerateConfig(os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("Error generating config: %v", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "generate-monitoring" {
+		if err := runGenerateMonitoring(os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("Error generating monitoring files: %v", err)
+		}
+		return
+	}
+
 	fileName := flag.String("file", "config.json", "Name of file to load config from")
+	once := flag.Bool("once", false, "Run a single check, print a JSON report and exit non-zero if the network is unhealthy")
+	dryRun := flag.Bool("dry-run", false, "Log the alerts instead of sending them")
 	flag.Parse()
 
 	config, err := LoadConfig(*fileName)
 	if err != nil {
 		log.Fatalf("Error loading config: %v", err)
 	}
+	config.dryRun = *dryRun
 
 	fc, err := NewForkChecker(*config)
 	if err != nil {
 		log.Fatalf("Failed to setup fork checker: %v", err)
 	}
 
-	err = fc.Start()
+	if *once {
+		runOnce(fc)
+		return
+	}
+
+	// Inside AWS Lambda every invocation runs the checks once instead of looping forever.
+	if runtimeApi := os.Getenv("AWS_LAMBDA_RUNTIME_API"); runtimeApi != "" {
+		err = runLambda(runtimeApi, fc.handleInvocation)
+	} else {
+		err = fc.Start()
+	}
+
 	if err != nil {
 		log.Fatalf("Error running fork checker: %v", err)
 	}
 }
+
+func runOnce(fc *ForkChecker) {
+	report, err := fc.runOnce()
+	if err != nil {
+		log.Printf("failed to save state: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+
+	if !report.healthy() {
+		os.Exit(exitUnhealthy)
+	}
+}
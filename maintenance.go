@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"go-xpx-check-fork-util/internal/table"
+)
+
+type (
+	maintenanceWindow struct {
+		name     string
+		start    time.Time
+		duration time.Duration
+		repeat   time.Duration
+		nodes    map[string]struct{}
+	}
+
+	// MaintenanceSchedule tracks the configured maintenance windows and which of them are currently open.
+	MaintenanceSchedule struct {
+		windows []*maintenanceWindow
+		open    map[*maintenanceWindow]bool
+	}
+
+	MaintenanceSummaryAlert struct {
+		Window    string
+		Offline   []*health.NodeInfo
+		OutOfSync map[health.NodeInfo]uint64
+	}
+)
+
+var (
+	ErrInvalidMaintenanceWindow = errors.New("invalid maintenance window")
+)
+
+func (a MaintenanceSummaryAlert) getType() AlertType {
+	return MaintenanceAlertType
+}
+
+func (a MaintenanceSummaryAlert) createMessage() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "<b>🛠 Maintenance window ended </b>\n\n%s", a.Window)
+
+	if len(a.Offline) == 0 && len(a.OutOfSync) == 0 {
+		fmt.Fprintf(&buf, "\n\nAll nodes recovered.")
+		return buf.String()
+	}
+
+	if len(a.Offline) > 0 {
+		names := make([][]string, 0, len(a.Offline))
+		for _, node := range a.Offline {
+			names = append(names, []string{nodeName(*node)})
+		}
+
+		fmt.Fprintf(&buf, "\n\nStill offline (%d):<pre>%s</pre>", len(names), table.Render(names, table.Options{Sort: true}))
+	}
+
+	if len(a.OutOfSync) > 0 {
+		var lines [][]string
+		for _, node := range sortedNodes(a.OutOfSync) {
+			lines = append(lines, []string{node.Name, strconv.FormatUint(node.Height, 10)})
+		}
+
+		fmt.Fprintf(&buf, "\n\nStill out-of-sync (%d):<pre>%s</pre>", len(lines), table.Render(lines, table.Options{Align: []table.Align{table.AlignLeft, table.AlignRight}}))
+	}
+
+	return buf.String()
+}
+
+func parseMaintenanceWindows(windows []MaintenanceWindow) ([]*maintenanceWindow, error) {
+	parsed := make([]*maintenanceWindow, 0, len(windows))
+
+	for i, w := range windows {
+		start, err := time.Parse(time.RFC3339, w.Start)
+		if err != nil {
+			return nil, fmt.Errorf("%w #%d: start: %v", ErrInvalidMaintenanceWindow, i, err)
+		}
+
+		end, err := time.Parse(time.RFC3339, w.End)
+		if err != nil {
+			return nil, fmt.Errorf("%w #%d: end: %v", ErrInvalidMaintenanceWindow, i, err)
+		}
+
+		if !end.After(start) {
+			return nil, fmt.Errorf("%w #%d: end must be after start", ErrInvalidMaintenanceWindow, i)
+		}
+
+		var repeat time.Duration
+		if w.Repeat != "" {
+			repeat, err = time.ParseDuration(w.Repeat)
+			if err != nil {
+				return nil, fmt.Errorf("%w #%d: repeat: %v", ErrInvalidMaintenanceWindow, i, err)
+			}
+
+			if repeat < end.Sub(start) {
+				return nil, fmt.Errorf("%w #%d: repeat must not be shorter than the window", ErrInvalidMaintenanceWindow, i)
+			}
+		}
+
+		nodes := make(map[string]struct{}, len(w.Nodes))
+		for _, endpoint := range w.Nodes {
+			nodes[endpoint] = struct{}{}
+		}
+
+		name := w.Name
+		if name == "" {
+			name = fmt.Sprintf("%s - %s", w.Start, w.End)
+		}
+
+		parsed = append(parsed, &maintenanceWindow{
+			name:     name,
+			start:    start,
+			duration: end.Sub(start),
+			repeat:   repeat,
+			nodes:    nodes,
+		})
+	}
+
+	return parsed, nil
+}
+
+func NewMaintenanceSchedule(windows []MaintenanceWindow) (*MaintenanceSchedule, error) {
+	parsed, err := parseMaintenanceWindows(windows)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MaintenanceSchedule{
+		windows: parsed,
+		open:    make(map[*maintenanceWindow]bool),
+	}, nil
+}
+
+func (w *maintenanceWindow) isActive(t time.Time) bool {
+	if t.Before(w.start) {
+		return false
+	}
+
+	elapsed := t.Sub(w.start)
+	if w.repeat > 0 {
+		elapsed %= w.repeat
+	}
+
+	return elapsed < w.duration
+}
+
+// covers reports whether the window applies to the node; a window without nodes applies to all of them.
+func (w *maintenanceWindow) covers(endpoint string) bool {
+	if len(w.nodes) == 0 {
+		return true
+	}
+
+	_, exists := w.nodes[endpoint]
+	return exists
+}
+
+func (s *MaintenanceSchedule) inMaintenance(endpoint string, t time.Time) bool {
+	if s == nil {
+		return false
+	}
+
+	for _, w := range s.windows {
+		if w.isActive(t) && w.covers(endpoint) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *MaintenanceSchedule) openWindows() []string {
+	if s == nil {
+		return nil
+	}
+
+	var names []string
+	for _, w := range s.windows {
+		if s.open[w] {
+			names = append(names, w.name)
+		}
+	}
+
+	return names
+}
+
+// restoreOpenWindows marks the named windows as open, so that a summary is still sent when they close after a restart.
+func (s *MaintenanceSchedule) restoreOpenWindows(names []string) {
+	if s == nil {
+		return
+	}
+
+	for _, w := range s.windows {
+		for _, name := range names {
+			if w.name == name {
+				s.open[w] = true
+			}
+		}
+	}
+}
+
+// closedWindows updates the open windows and returns the ones that closed since the previous call.
+func (s *MaintenanceSchedule) closedWindows(t time.Time) []*maintenanceWindow {
+	if s == nil {
+		return nil
+	}
+
+	var closed []*maintenanceWindow
+	for _, w := range s.windows {
+		active := w.isActive(t)
+
+		if active && !s.open[w] {
+			log.Printf("Maintenance window opened: %s", w.name)
+		} else if !active && s.open[w] {
+			log.Printf("Maintenance window closed: %s", w.name)
+			closed = append(closed, w)
+		}
+
+		s.open[w] = active
+	}
+
+	return closed
+}
+
+func (am *AlertManager) withoutMaintenanceNodes(nodes map[health.NodeInfo]uint64) map[health.NodeInfo]uint64 {
+	now := time.Now()
+	filtered := make(map[health.NodeInfo]uint64, len(nodes))
+	for node, height := range nodes {
+		if !am.maintenance.inMaintenance(node.Endpoint, now) {
+			filtered[node] = height
+		}
+	}
+
+	return filtered
+}
+
+func (am *AlertManager) withoutMaintenanceOfflineNodes(nodes map[string]*health.NodeInfo) map[string]*health.NodeInfo {
+	now := time.Now()
+	filtered := make(map[string]*health.NodeInfo, len(nodes))
+	for key, node := range nodes {
+		if !am.maintenance.inMaintenance(node.Endpoint, now) {
+			filtered[key] = node
+		}
+	}
+
+	return filtered
+}
+
+// handleMaintenanceWindows sends a summary of the nodes that are still broken for every window that just closed.
+func (am *AlertManager) handleMaintenanceWindows() {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	for _, w := range am.maintenance.closedWindows(time.Now()) {
+		summary := MaintenanceSummaryAlert{
+			Window:    w.name,
+			OutOfSync: make(map[health.NodeInfo]uint64),
+		}
+
+		for _, node := range am.lastFailedConnections {
+			if am.isConfiguredNode(*node) && w.covers(node.Endpoint) {
+				summary.Offline = append(summary.Offline, node)
+			}
+		}
+
+		for node, height := range am.lastNotReached {
+			if am.isConfiguredNode(node) && w.covers(node.Endpoint) {
+				summary.OutOfSync[node] = height
+			}
+		}
+
+		am.sendToTelegram(summary)
+	}
+}
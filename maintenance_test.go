@@ -0,0 +1,121 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMaintenanceWindows(t *testing.T) {
+	t.Run("Valid window", func(t *testing.T) {
+		windows, err := parseMaintenanceWindows([]MaintenanceWindow{{
+			Start:  "2024-09-01T02:00:00Z",
+			End:    "2024-09-01T03:00:00Z",
+			Repeat: "168h",
+			Nodes:  []string{"127.0.0.1:7900"},
+		}})
+		require.NoError(t, err)
+		require.Len(t, windows, 1)
+		assert.Equal(t, time.Hour, windows[0].duration)
+		assert.Equal(t, 168*time.Hour, windows[0].repeat)
+	})
+
+	t.Run("End before start", func(t *testing.T) {
+		_, err := parseMaintenanceWindows([]MaintenanceWindow{{
+			Start: "2024-09-01T03:00:00Z",
+			End:   "2024-09-01T02:00:00Z",
+		}})
+		require.ErrorIs(t, err, ErrInvalidMaintenanceWindow)
+	})
+
+	t.Run("Repeat shorter than window", func(t *testing.T) {
+		_, err := parseMaintenanceWindows([]MaintenanceWindow{{
+			Start:  "2024-09-01T02:00:00Z",
+			End:    "2024-09-01T04:00:00Z",
+			Repeat: "1h",
+		}})
+		require.ErrorIs(t, err, ErrInvalidMaintenanceWindow)
+	})
+}
+
+func TestMaintenanceSchedule(t *testing.T) {
+	schedule, err := NewMaintenanceSchedule([]MaintenanceWindow{{
+		Start:  "2024-09-01T02:00:00Z",
+		End:    "2024-09-01T03:00:00Z",
+		Repeat: "24h",
+		Nodes:  []string{"127.0.0.1:7900"},
+	}})
+	require.NoError(t, err)
+
+	at := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		require.NoError(t, err)
+		return parsed
+	}
+
+	assert.False(t, schedule.inMaintenance("127.0.0.1:7900", at("2024-09-01T01:59:00Z")))
+	assert.True(t, schedule.inMaintenance("127.0.0.1:7900", at("2024-09-01T02:30:00Z")))
+	assert.True(t, schedule.inMaintenance("127.0.0.1:7900", at("2024-09-05T02:30:00Z")))
+	assert.False(t, schedule.inMaintenance("127.0.0.1:7900", at("2024-09-05T03:00:00Z")))
+	assert.False(t, schedule.inMaintenance("127.0.0.2:7900", at("2024-09-01T02:30:00Z")))
+
+	assert.Empty(t, schedule.closedWindows(at("2024-09-01T02:30:00Z")))
+	assert.Len(t, schedule.closedWindows(at("2024-09-01T03:30:00Z")), 1)
+	assert.Empty(t, schedule.closedWindows(at("2024-09-01T04:30:00Z")))
+}
+
+func TestMaintenanceSuppressesSyncAlert(t *testing.T) {
+	am := newIncidentTestAlertManager(t, nil)
+
+	var endpoints []string
+	for _, info := range am.nodeInfos[:4] {
+		endpoints = append(endpoints, info.Endpoint)
+	}
+
+	schedule, err := NewMaintenanceSchedule([]MaintenanceWindow{{
+		Start: time.Now().Add(-time.Hour).Format(time.RFC3339),
+		End:   time.Now().Add(time.Hour).Format(time.RFC3339),
+		Nodes: endpoints,
+	}})
+	require.NoError(t, err)
+	am.maintenance = schedule
+
+	checkpoint := uint64(1000)
+	notReached := map[health.NodeInfo]uint64{
+		*am.nodeInfos[0]: 950,
+		*am.nodeInfos[1]: 951,
+		*am.nodeInfos[2]: 952,
+		*am.nodeInfos[3]: 953,
+		*am.nodeInfos[4]: 954,
+	}
+
+	filtered := am.withoutMaintenanceNodes(notReached)
+	assert.Len(t, filtered, 1)
+	assert.False(t, am.shouldSendSyncAlert(checkpoint, filtered, map[health.NodeInfo]uint64{*am.nodeInfos[5]: 1000}))
+
+	failed := map[string]*health.NodeInfo{
+		am.nodeInfos[0].IdentityKey.String(): am.nodeInfos[0],
+		am.nodeInfos[5].IdentityKey.String(): am.nodeInfos[5],
+	}
+	assert.Len(t, am.withoutMaintenanceOfflineNodes(failed), 1)
+}
+
+func TestMaintenanceSummaryAlertMessage(t *testing.T) {
+	nodeA := &health.NodeInfo{Endpoint: "127.0.0.1:7900", FriendlyName: "nodeA"}
+	nodeB := health.NodeInfo{Endpoint: "127.0.0.2:7900", FriendlyName: "nodeB"}
+
+	msg := MaintenanceSummaryAlert{Window: "upgrade"}.createMessage()
+	assert.Contains(t, msg, "All nodes recovered")
+
+	msg = MaintenanceSummaryAlert{
+		Window:    "upgrade",
+		Offline:   []*health.NodeInfo{nodeA},
+		OutOfSync: map[health.NodeInfo]uint64{nodeB: 990},
+	}.createMessage()
+	assert.Contains(t, msg, "Still offline (1):<pre>nodeA(127.0.0.1)</pre>")
+	assert.Contains(t, msg, "nodeB(127.0.0.2)")
+	assert.Contains(t, msg, "990")
+}
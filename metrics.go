@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+)
+
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metricsWriter writes the Prometheus text format.
+type metricsWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *metricsWriter) header(name, metricType, help string) {
+	fmt.Fprintf(&w.buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// sample writes a sample with labels given as name and value pairs.
+func (w *metricsWriter) sample(name string, value float64, labels ...string) {
+	w.buf.WriteString(name)
+	if len(labels) > 0 {
+		pairs := make([]string, 0, len(labels)/2)
+		for i := 0; i+1 < len(labels); i += 2 {
+			pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], escapeLabelValue(labels[i+1])))
+		}
+		fmt.Fprintf(&w.buf, "{%s}", strings.Join(pairs, ","))
+	}
+	fmt.Fprintf(&w.buf, " %g\n", value)
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func boolMetric(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// writeMetrics renders the status of the last check cycle. The node series cover the configured nodes, so that
+// each of them has a value in every scrape, not only while it is failing.
+func writeMetrics(status Status, nodeInfos []*health.NodeInfo) []byte {
+	var w metricsWriter
+
+	w.header("forkchecker_checkpoint_height", "gauge", "Height of the next block hash check.")
+	w.sample("forkchecker_checkpoint_height", float64(status.Checkpoint))
+
+	if !status.UpdatedAt.IsZero() {
+		w.header("forkchecker_last_cycle_timestamp_seconds", "gauge", "Time the last check cycle finished.")
+		w.sample("forkchecker_last_cycle_timestamp_seconds", float64(status.UpdatedAt.Unix()))
+	}
+
+	offline := make(map[string]bool, len(status.OfflineNodes))
+	for _, node := range status.OfflineNodes {
+		offline[node.Endpoint] = true
+	}
+
+	lags := make(map[string]uint64, len(status.OutOfSyncNodes))
+	for _, node := range status.OutOfSyncNodes {
+		if status.Checkpoint > node.Height {
+			lags[node.Endpoint] = status.Checkpoint - node.Height
+		}
+	}
+
+	nodes := make([]health.NodeInfo, 0, len(nodeInfos))
+	for _, info := range nodeInfos {
+		nodes = append(nodes, *info)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodeName(nodes[i]) < nodeName(nodes[j])
+	})
+
+	w.header("forkchecker_node_offline", "gauge", "Whether the node could not be connected in the last check cycle.")
+	for _, node := range nodes {
+		w.sample("forkchecker_node_offline", boolMetric(offline[node.Endpoint]), "node", nodeName(node), "endpoint", node.Endpoint)
+	}
+
+	w.header("forkchecker_node_lag_blocks", "gauge", "Blocks the node is behind the checkpoint, 0 if it reached it.")
+	for _, node := range nodes {
+		w.sample("forkchecker_node_lag_blocks", float64(lags[node.Endpoint]), "node", nodeName(node), "endpoint", node.Endpoint)
+	}
+
+	w.header("forkchecker_node_hash_streak", "gauge", "Consecutive checkpoints at which the node agreed with the majority hash.")
+	for _, streak := range status.HashStreaks {
+		w.sample("forkchecker_node_hash_streak", float64(streak.Current), "endpoint", streak.Endpoint)
+	}
+
+	if len(status.Scores) > 0 {
+		w.header("forkchecker_node_score", "gauge", "Rolling score of the node between 0 and 1.")
+		for _, score := range status.Scores {
+			w.sample("forkchecker_node_score", score.Score, "node", score.Name, "endpoint", score.Endpoint)
+		}
+	}
+
+	if len(status.ApiGateways) > 0 {
+		w.header("forkchecker_api_gateway_up", "gauge", "Whether the REST gateway passed its last check.")
+		for _, gateway := range status.ApiGateways {
+			w.sample("forkchecker_api_gateway_up", boolMetric(gateway.Healthy), "url", gateway.Url)
+		}
+	}
+
+	w.header("forkchecker_open_incidents", "gauge", "Incidents open with the paging provider.")
+	w.sample("forkchecker_open_incidents", float64(len(status.OpenIncidents)))
+
+	w.header("forkchecker_paused", "gauge", "Whether alerting is paused.")
+	w.sample("forkchecker_paused", boolMetric(status.Paused != nil))
+
+	w.header("forkchecker_standby", "gauge", "Whether another checker instance holds the lease.")
+	w.sample("forkchecker_standby", boolMetric(status.Standby))
+
+	return w.buf.Bytes()
+}
+
+// serveMetrics serves the status of the last check cycle in the Prometheus text format.
+func (s *StatusServer) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	if _, err := w.Write(writeMetrics(s.get(), s.nodeInfos)); err != nil {
+		log.Printf("failed writing metrics response: %v", err)
+	}
+}
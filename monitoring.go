@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+)
+
+const (
+	DefaultMonitoringJob   = "forkchecker"
+	DefaultDashboardOutput = "grafana-dashboard.json"
+	DefaultRulesOutput     = "prometheus-rules.yml"
+)
+
+type (
+	// monitoringRule is a Prometheus alerting rule mirroring one of the checker's own alerts.
+	monitoringRule struct {
+		name     string
+		expr     string
+		duration time.Duration
+		severity string
+		summary  string
+	}
+
+	// monitoringPanel is a Grafana panel showing a single query.
+	monitoringPanel struct {
+		title     string
+		kind      string
+		expr      string
+		legend    string
+		unit      string
+		threshold float64
+		min       *float64
+		max       *float64
+	}
+)
+
+// promDuration formats a duration the way Prometheus parses it, e.g. 1h30m.
+func promDuration(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	if d <= 0 {
+		return "0s"
+	}
+
+	var b strings.Builder
+	for _, unit := range []struct {
+		suffix string
+		size   time.Duration
+	}{{"h", time.Hour}, {"m", time.Minute}, {"s", time.Second}} {
+		if n := d / unit.size; n > 0 {
+			fmt.Fprintf(&b, "%d%s", n, unit.suffix)
+			d -= n * unit.size
+		}
+	}
+
+	return b.String()
+}
+
+// chainStuckWindow is the time without a new checkpoint after which the chain is stuck: the stuck threshold on top
+// of the time the chain takes to advance by a height check interval.
+func chainStuckWindow(config *Config) time.Duration {
+	return config.AlertConfig.getStuckDurationThreshold() + time.Duration(config.HeightCheckInterval)*health.DefaultAvgSecondsPerBlock
+}
+
+// monitoringRules returns rules with the thresholds of the checker's alerts, so the metric-based alerts fire on the
+// same conditions.
+func monitoringRules(config *Config, job string) []monitoringRule {
+	selector := fmt.Sprintf(`{job="%s"}`, job)
+	alertConfig := &config.AlertConfig
+	stuckWindow := chainStuckWindow(config)
+
+	rules := []monitoringRule{
+		{
+			name:     "ForkCheckerDown",
+			expr:     fmt.Sprintf("up%s == 0", selector),
+			duration: alertConfig.getOfflineDurationThreshold(),
+			severity: "critical",
+			summary:  "The fork checker cannot be scraped.",
+		},
+		{
+			name:     "ForkCheckerCycleStalled",
+			expr:     fmt.Sprintf("time() - forkchecker_last_cycle_timestamp_seconds%s > %d", selector, int(stuckWindow.Seconds())),
+			severity: "critical",
+			summary:  "The fork checker has not completed a check cycle for " + promDuration(stuckWindow) + ".",
+		},
+		{
+			name:     "ForkCheckerChainStuck",
+			expr:     fmt.Sprintf("changes(forkchecker_checkpoint_height%s[%s]) == 0", selector, promDuration(stuckWindow)),
+			severity: "critical",
+			summary:  "No node has reached the next checkpoint for " + promDuration(stuckWindow) + ".",
+		},
+		{
+			name:     "ForkCheckerForkDetected",
+			expr:     fmt.Sprintf("forkchecker_node_hash_streak%s == 0", selector),
+			severity: "critical",
+			summary:  "{{ $labels.endpoint }} disagrees with the majority block hash.",
+		},
+		{
+			name: "ForkCheckerNodesOutOfSync",
+			expr: fmt.Sprintf("count(forkchecker_node_lag_blocks%s >= %d) >= %s",
+				selector, alertConfig.OutOfSyncBlocksThreshold,
+				promNodeThreshold(alertConfig.OutOfSyncCriticalNodesThreshold, "forkchecker_node_lag_blocks"+selector)),
+			severity: "warning",
+			summary:  fmt.Sprintf("{{ $value }} nodes are %d or more blocks behind.", alertConfig.OutOfSyncBlocksThreshold),
+		},
+	}
+
+	if alertConfig.OfflineCriticalNodesThreshold.isZero() {
+		rules = append(rules, monitoringRule{
+			name:     "ForkCheckerNodeOffline",
+			expr:     fmt.Sprintf("forkchecker_node_offline%s == 1", selector),
+			duration: alertConfig.getOfflineDurationThreshold(),
+			severity: "warning",
+			summary:  "{{ $labels.node }} is offline.",
+		})
+	} else {
+		rules = append(rules, monitoringRule{
+			name: "ForkCheckerNodesOffline",
+			expr: fmt.Sprintf("count(forkchecker_node_offline%s == 1) >= %s",
+				selector, promNodeThreshold(alertConfig.OfflineCriticalNodesThreshold, "forkchecker_node_offline"+selector)),
+			duration: alertConfig.getOfflineDurationThreshold(),
+			severity: "warning",
+			summary:  "{{ $value }} nodes are offline.",
+		})
+	}
+
+	if config.ApiGatewayConfig.Enabled {
+		rules = append(rules, monitoringRule{
+			name:     "ForkCheckerApiGatewayDown",
+			expr:     fmt.Sprintf("forkchecker_api_gateway_up%s == 0", selector),
+			duration: config.ApiGatewayConfig.getCheckInterval(),
+			severity: "warning",
+			summary:  "REST gateway {{ $labels.url }} is unhealthy.",
+		})
+	}
+
+	return rules
+}
+
+// promNodeThreshold renders a node threshold for a rule. A percentage is taken of the nodes reporting the metric, so
+// the rule follows the number of nodes like the checker does.
+func promNodeThreshold(threshold NodeThreshold, metric string) string {
+	if threshold.percent > 0 {
+		return fmt.Sprintf("%g * count(%s) / 100", threshold.percent, metric)
+	}
+	return strconv.Itoa(threshold.count)
+}
+
+// yamlString quotes a string for YAML; a JSON string is a valid double-quoted YAML scalar.
+func yamlString(value string) string {
+	var buf bytes.Buffer
+	_ = encodeConfigValue(&buf, value)
+	return buf.String()
+}
+
+// generateRules returns a Prometheus rule file with the rules of monitoringRules.
+func generateRules(config *Config, job string) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "# Generated by forkchecker generate-monitoring from the checker's config; regenerate after changing it.\n")
+	fmt.Fprintf(&buf, "groups:\n  - name: %s\n    rules:\n", yamlString(job))
+	for _, rule := range monitoringRules(config, job) {
+		fmt.Fprintf(&buf, "      - alert: %s\n", rule.name)
+		fmt.Fprintf(&buf, "        expr: %s\n", yamlString(rule.expr))
+		if rule.duration > 0 {
+			fmt.Fprintf(&buf, "        for: %s\n", promDuration(rule.duration))
+		}
+		fmt.Fprintf(&buf, "        labels:\n          severity: %s\n", rule.severity)
+		fmt.Fprintf(&buf, "        annotations:\n          summary: %s\n", yamlString(rule.summary))
+	}
+
+	return buf.Bytes()
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
+
+// monitoringPanels returns the panels of the dashboard, with the thresholds of the alert config.
+func monitoringPanels(config *Config, job string) []monitoringPanel {
+	selector := fmt.Sprintf(`job="%s"`, job)
+	nodeSelector := fmt.Sprintf(`{%s,node=~"$node"}`, selector)
+	alertConfig := &config.AlertConfig
+
+	panels := []monitoringPanel{
+		{title: "Checkpoint height", kind: "stat", expr: fmt.Sprintf("forkchecker_checkpoint_height{%s}", selector), unit: "none"},
+		{title: "Offline nodes", kind: "stat", expr: fmt.Sprintf("sum(forkchecker_node_offline{%s})", selector), unit: "none", threshold: 1},
+		{
+			title:     "Out-of-sync nodes",
+			kind:      "stat",
+			expr:      fmt.Sprintf("count(forkchecker_node_lag_blocks{%s} >= %d) or vector(0)", selector, alertConfig.OutOfSyncBlocksThreshold),
+			unit:      "none",
+			threshold: float64(alertConfig.OutOfSyncCriticalNodesThreshold.nodes(len(config.Nodes))),
+		},
+		{title: "Open incidents", kind: "stat", expr: fmt.Sprintf("forkchecker_open_incidents{%s}", selector), unit: "none", threshold: 1},
+		{
+			title:     "Node lag",
+			kind:      "timeseries",
+			expr:      "forkchecker_node_lag_blocks" + nodeSelector,
+			legend:    "{{node}}",
+			unit:      "none",
+			threshold: float64(alertConfig.OutOfSyncBlocksThreshold),
+		},
+		{
+			title:  "Offline",
+			kind:   "timeseries",
+			expr:   "forkchecker_node_offline" + nodeSelector,
+			legend: "{{node}}",
+			unit:   "bool",
+			min:    floatPtr(0),
+			max:    floatPtr(1),
+		},
+		{
+			title:  "Hash agreement streak",
+			kind:   "timeseries",
+			expr:   fmt.Sprintf("forkchecker_node_hash_streak{%s}", selector),
+			legend: "{{endpoint}}",
+			unit:   "none",
+		},
+	}
+
+	if config.ScoreConfig.Enabled {
+		panels = append(panels, monitoringPanel{
+			title:  "Node scores",
+			kind:   "timeseries",
+			expr:   "forkchecker_node_score" + nodeSelector,
+			legend: "{{node}}",
+			unit:   "percentunit",
+			min:    floatPtr(0),
+			max:    floatPtr(1),
+		})
+	}
+
+	if config.ApiGatewayConfig.Enabled {
+		panels = append(panels, monitoringPanel{
+			title:  "REST gateways",
+			kind:   "timeseries",
+			expr:   fmt.Sprintf("forkchecker_api_gateway_up{%s}", selector),
+			legend: "{{url}}",
+			unit:   "bool",
+			min:    floatPtr(0),
+			max:    floatPtr(1),
+		})
+	}
+
+	return panels
+}
+
+func (p monitoringPanel) grafanaPanel(id int, x, y, width, height int) map[string]interface{} {
+	steps := []map[string]interface{}{{"color": "green", "value": nil}}
+	if p.threshold > 0 {
+		steps = append(steps, map[string]interface{}{"color": "red", "value": p.threshold})
+	}
+
+	defaults := map[string]interface{}{
+		"unit":       p.unit,
+		"thresholds": map[string]interface{}{"mode": "absolute", "steps": steps},
+	}
+	if p.min != nil {
+		defaults["min"] = *p.min
+	}
+	if p.max != nil {
+		defaults["max"] = *p.max
+	}
+	if p.kind == "timeseries" && p.threshold > 0 {
+		defaults["custom"] = map[string]interface{}{"thresholdsStyle": map[string]interface{}{"mode": "line"}}
+	}
+
+	return map[string]interface{}{
+		"id":          id,
+		"type":        p.kind,
+		"title":       p.title,
+		"datasource":  map[string]interface{}{"type": "prometheus", "uid": "${datasource}"},
+		"gridPos":     map[string]interface{}{"x": x, "y": y, "w": width, "h": height},
+		"fieldConfig": map[string]interface{}{"defaults": defaults, "overrides": []interface{}{}},
+		"targets": []map[string]interface{}{{
+			"refId":        "A",
+			"expr":         p.expr,
+			"legendFormat": p.legend,
+		}},
+	}
+}
+
+// generateDashboard returns a Grafana dashboard for the metrics of the status server. The node variable lists the
+// configured nodes by the names used in the metrics.
+func generateDashboard(config *Config, job string) ([]byte, error) {
+	nodeInfos, err := parseNodes(config.Nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	options := make([]map[string]interface{}, 0, len(nodeInfos))
+	names := make([]string, 0, len(nodeInfos))
+	for _, info := range nodeInfos {
+		name := nodeName(*info)
+		names = append(names, name)
+		options = append(options, map[string]interface{}{"text": name, "value": name, "selected": false})
+	}
+
+	// The stats fill the first row, with the time series two to a row below them.
+	panels := make([]map[string]interface{}, 0)
+	stats, series := 0, 0
+	for i, panel := range monitoringPanels(config, job) {
+		if panel.kind == "stat" {
+			panels = append(panels, panel.grafanaPanel(i+1, stats*6, 0, 6, 4))
+			stats++
+			continue
+		}
+
+		panels = append(panels, panel.grafanaPanel(i+1, (series%2)*12, 4+(series/2)*8, 12, 8))
+		series++
+	}
+
+	dashboard := map[string]interface{}{
+		"uid":           "forkchecker-" + job,
+		"title":         "Fork checker (" + job + ")",
+		"tags":          []string{"forkchecker"},
+		"schemaVersion": 39,
+		"refresh":       "30s",
+		"time":          map[string]interface{}{"from": "now-6h", "to": "now"},
+		"panels":        panels,
+		"templating": map[string]interface{}{"list": []map[string]interface{}{
+			{
+				"name":  "datasource",
+				"label": "Data source",
+				"type":  "datasource",
+				"query": "prometheus",
+			},
+			{
+				"name":       "node",
+				"label":      "Node",
+				"type":       "custom",
+				"query":      strings.Join(names, ","),
+				"multi":      true,
+				"includeAll": true,
+				"allValue":   ".*",
+				"current":    map[string]interface{}{"text": "All", "value": "$__all"},
+				"options":    options,
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(dashboard); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// runGenerateMonitoring implements the generate-monitoring command. Like generate-config, it never overwrites an
+// existing file; an empty output name skips that file.
+func runGenerateMonitoring(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("generate-monitoring", flag.ContinueOnError)
+	fileName := flags.String("file", "config.json", "Name of file to load config from")
+	job := flags.String("job", DefaultMonitoringJob, "Prometheus job scraping the status server's /metrics")
+	dashboardOutput := flags.String("dashboard", DefaultDashboardOutput, "File to write the Grafana dashboard to")
+	rulesOutput := flags.String("rules", DefaultRulesOutput, "File to write the Prometheus alerting rules to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := LoadConfig(*fileName)
+	if err != nil {
+		return err
+	}
+
+	if config.StatusAddress == "" {
+		fmt.Fprintln(stdout, "Warning: statusAddress is not set, so the checker does not serve the /metrics these files query.")
+	}
+
+	if *dashboardOutput != "" {
+		dashboard, err := generateDashboard(config, *job)
+		if err != nil {
+			return err
+		}
+
+		if err := writeNewFile(*dashboardOutput, dashboard); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Wrote Grafana dashboard to %s\n", *dashboardOutput)
+	}
+
+	if *rulesOutput != "" {
+		if err := writeNewFile(*rulesOutput, generateRules(config, *job)); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Wrote Prometheus alerting rules to %s\n", *rulesOutput)
+	}
+
+	return nil
+}
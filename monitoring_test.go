@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	nodes := newScoreTestNodes()
+	nodeA, nodeB, nodeC := nodes[0], nodes[1], nodes[2]
+
+	status := Status{
+		UpdatedAt:      time.Unix(1700000000, 0),
+		Checkpoint:     100,
+		OfflineNodes:   []StatusNode{newStatusNode(*nodeC, 0, "")},
+		OutOfSyncNodes: []StatusNode{newStatusNode(*nodeB, 93, "")},
+		HashStreaks:    []HashStreak{{Endpoint: nodeA.Endpoint, Current: 4}, {Endpoint: nodeB.Endpoint}},
+		ApiGateways:    []ApiGatewayStatus{{Url: "http://gateway:3000", Healthy: true}},
+	}
+
+	server := &StatusServer{nodeInfos: nodes}
+	server.update(status)
+
+	recorder := httptest.NewRecorder()
+	server.serveMetrics(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, metricsContentType, recorder.Header().Get("Content-Type"))
+
+	metrics := recorder.Body.String()
+	for _, sample := range []string{
+		"forkchecker_checkpoint_height 100\n",
+		"forkchecker_last_cycle_timestamp_seconds 1.7e+09\n",
+		`forkchecker_node_offline{node="` + nodeName(*nodeA) + `",endpoint="10.0.0.1:7900"} 0`,
+		`forkchecker_node_offline{node="` + nodeName(*nodeC) + `",endpoint="10.0.0.3:7900"} 1`,
+		`forkchecker_node_lag_blocks{node="` + nodeName(*nodeA) + `",endpoint="10.0.0.1:7900"} 0`,
+		`forkchecker_node_lag_blocks{node="` + nodeName(*nodeB) + `",endpoint="10.0.0.2:7900"} 7`,
+		`forkchecker_node_hash_streak{endpoint="10.0.0.2:7900"} 0`,
+		`forkchecker_api_gateway_up{url="http://gateway:3000"} 1`,
+		"forkchecker_paused 0\n",
+	} {
+		assert.Contains(t, metrics, sample)
+	}
+	assert.NotContains(t, metrics, "forkchecker_node_score")
+
+	t.Run("Label escaping", func(t *testing.T) {
+		node := &health.NodeInfo{Endpoint: "10.0.0.9:7900", IdentityKey: nodeA.IdentityKey, FriendlyName: `a "b" \c`}
+		metrics := string(writeMetrics(Status{}, []*health.NodeInfo{node}))
+		assert.Contains(t, metrics, `node="a \"b\" \\c(10.0.0.9)"`)
+	})
+}
+
+func TestPromDuration(t *testing.T) {
+	assert.Equal(t, "5m", promDuration(5*time.Minute))
+	assert.Equal(t, "1h30m", promDuration(90*time.Minute))
+	assert.Equal(t, "10m15s", promDuration(10*time.Minute+15*time.Second+500*time.Millisecond))
+	assert.Equal(t, "0s", promDuration(0))
+}
+
+func TestGenerateMonitoring(t *testing.T) {
+	config, err := LoadConfig("sample.config.json")
+	require.NoError(t, err)
+
+	t.Run("Rules", func(t *testing.T) {
+		rules := string(generateRules(config, "checker"))
+		assert.Contains(t, rules, `expr: "count(forkchecker_node_lag_blocks{job=\"checker\"} >= 5) >= 5"`)
+		assert.Contains(t, rules, `expr: "forkchecker_node_offline{job=\"checker\"} == 1"`+"\n        for: 5m")
+
+		// The stuck threshold plus a height check interval of one block.
+		assert.Contains(t, rules, `changes(forkchecker_checkpoint_height{job=\"checker\"}[10m15s]) == 0`)
+		assert.NotContains(t, rules, "ForkCheckerApiGatewayDown")
+
+		config := *config
+		config.ApiGatewayConfig.Enabled = true
+		assert.Contains(t, string(generateRules(&config, "checker")), "ForkCheckerApiGatewayDown")
+	})
+
+	t.Run("Dashboard", func(t *testing.T) {
+		content, err := generateDashboard(config, "checker")
+		require.NoError(t, err)
+
+		var dashboard struct {
+			Panels []struct {
+				Type    string `json:"type"`
+				Targets []struct {
+					Expr string `json:"expr"`
+				} `json:"targets"`
+			} `json:"panels"`
+			Templating struct {
+				List []struct {
+					Name  string `json:"name"`
+					Query string `json:"query"`
+				} `json:"list"`
+			} `json:"templating"`
+		}
+		require.NoError(t, json.Unmarshal(content, &dashboard))
+
+		require.Len(t, dashboard.Templating.List, 2)
+		assert.Equal(t, 6, strings.Count(dashboard.Templating.List[1].Query, ",")+1)
+		assert.Contains(t, dashboard.Templating.List[1].Query, "nodeA(127.0.0.1)")
+
+		require.NotEmpty(t, dashboard.Panels)
+		for _, panel := range dashboard.Panels {
+			require.Len(t, panel.Targets, 1)
+			assert.Contains(t, panel.Targets[0].Expr, `job="checker"`)
+		}
+	})
+
+	t.Run("Command", func(t *testing.T) {
+		dir := t.TempDir()
+		dashboard := filepath.Join(dir, "dashboard.json")
+		rules := filepath.Join(dir, "rules.yml")
+		args := []string{"-file", "sample.config.json", "-dashboard", dashboard, "-rules", rules}
+
+		var stdout bytes.Buffer
+		require.NoError(t, runGenerateMonitoring(args, &stdout))
+		assert.Contains(t, stdout.String(), "statusAddress is not set")
+
+		content, err := os.ReadFile(rules)
+		require.NoError(t, err)
+		assert.Equal(t, generateRules(config, DefaultMonitoringJob), content)
+
+		// Existing files are never overwritten.
+		require.Error(t, runGenerateMonitoring(args, &stdout))
+	})
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+)
+
+var ErrInvalidNetworkConfig = errors.New("invalid network config")
+
+// parseNetwork returns the configured network type and generation hash, or a nil hash if they are to be
+// discovered from the REST gateway. Both must be set together, since the client needs both.
+func (c *Config) parseNetwork() (sdk.NetworkType, *sdk.Hash, error) {
+	if c.NetworkType == "" && c.GenerationHash == "" {
+		return sdk.NotSupportedNet, nil, nil
+	}
+
+	if c.NetworkType == "" || c.GenerationHash == "" {
+		return sdk.NotSupportedNet, nil, fmt.Errorf("%w: networkType and generationHash must be set together", ErrInvalidNetworkConfig)
+	}
+
+	networkType := sdk.NetworkTypeFromString(c.NetworkType)
+	if networkType == sdk.NotSupportedNet {
+		return sdk.NotSupportedNet, nil, fmt.Errorf("%w: unknown network type %s", ErrInvalidNetworkConfig, c.NetworkType)
+	}
+
+	generationHash, err := sdk.StringToHash(c.GenerationHash)
+	if err != nil {
+		return sdk.NotSupportedNet, nil, fmt.Errorf("%w: generation hash: %v", ErrInvalidNetworkConfig, err)
+	}
+
+	return networkType, generationHash, nil
+}
+
+// newClientConfig returns the config of the REST client. With a configured network, no request is made, so
+// the checker also starts against a private chain whose gateways are not reachable yet. Otherwise the network
+// is discovered from the first URL that answers.
+func (c *Config) newClientConfig(urls []string) (*sdk.Config, error) {
+	networkType, generationHash, err := c.parseNetwork()
+	if err != nil {
+		return nil, err
+	}
+
+	if generationHash != nil {
+		// The SDK's default reputation settings, which it does not export.
+		reputation, err := sdk.NewReputationConfig(10, 0.9)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Printf("Using configured network %s with generation hash %s", c.NetworkType, generationHash)
+		return sdk.NewConfigWithReputation(urls, networkType, reputation, sdk.DefaultWebsocketReconnectionTimeout,
+			generationHash, sdk.DefaultFeeCalculationStrategy)
+	}
+
+	for _, url := range urls {
+		var conf *sdk.Config
+		conf, err = sdk.NewConfig(context.Background(), []string{url})
+		if err == nil {
+			log.Printf("Initialized client on URL: %s", url)
+			return conf, nil
+		}
+	}
+
+	return nil, fmt.Errorf("all provided URLs failed: %v", err)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testGenerationHash = "56D112C98F7A7E34D1AEDC4BD01BC06CA2276DD546A93E36690B785E82439CA9"
+
+func TestNetworkConfig(t *testing.T) {
+	t.Run("Discovered", func(t *testing.T) {
+		networkType, generationHash, err := (&Config{}).parseNetwork()
+		require.NoError(t, err)
+		assert.Equal(t, sdk.NotSupportedNet, networkType)
+		assert.Nil(t, generationHash)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		configs := []Config{
+			{NetworkType: "private"},
+			{GenerationHash: testGenerationHash},
+			{NetworkType: "staging", GenerationHash: testGenerationHash},
+			{NetworkType: "private", GenerationHash: "56D1"},
+		}
+
+		for _, config := range configs {
+			_, _, err := config.parseNetwork()
+			require.ErrorIs(t, err, ErrInvalidNetworkConfig)
+		}
+	})
+
+	t.Run("Configured without a reachable gateway", func(t *testing.T) {
+		config := Config{NetworkType: "privateTest", GenerationHash: testGenerationHash}
+
+		// Nothing listens on the URL, so discovering the network would fail.
+		conf, err := config.newClientConfig([]string{"http://127.0.0.1:1"})
+		require.NoError(t, err)
+		assert.Equal(t, sdk.PrivateTest, conf.NetworkType)
+		assert.True(t, strings.EqualFold(testGenerationHash, conf.GenerationHash.String()))
+
+		_, err = (&Config{}).newClientConfig([]string{"http://127.0.0.1:1"})
+		require.Error(t, err)
+	})
+}
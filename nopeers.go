@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"time"
+)
+
+const DefaultNoPeersDurationThreshold = 5 * time.Minute
+
+type (
+	// NoPeersTracker follows the cycles in which the heights were collected but no node was left to compare the
+	// hashes with, i.e. the checker lost its connections mid-cycle. The checkpoint does not advance then, so unlike a
+	// logged error, a lasting loss means no fork coverage at all.
+	NoPeersTracker struct {
+		threshold time.Duration
+		// since is the time of the first cycle without peers, zero while hashes are compared.
+		since   time.Time
+		cycles  int
+		alerted bool
+	}
+
+	// NoPeersAlert reports that no hashes have been compared for the threshold, or that comparisons resumed.
+	NoPeersAlert struct {
+		Height    uint64
+		Since     time.Time
+		Cycles    int
+		Recovered bool
+	}
+)
+
+func NewNoPeersTracker(threshold time.Duration) *NoPeersTracker {
+	return &NoPeersTracker{threshold: threshold}
+}
+
+func (a *AlertConfig) getNoPeersDurationThreshold() time.Duration {
+	return parseOptionalDuration(a.NoPeersDurationThreshold, "no peers duration threshold", DefaultNoPeersDurationThreshold)
+}
+
+// handleNoPeers records whether the hash comparison at the checkpoint collected no hash at all. It alerts once the
+// condition has lasted for the threshold, and again when hashes are compared again.
+func (am *AlertManager) handleNoPeers(checkpoint uint64, noPeers bool) {
+	if am.noPeers == nil {
+		return
+	}
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	t := am.noPeers
+	now := time.Now()
+
+	if !noPeers {
+		if t.alerted {
+			log.Printf("Hashes compared again after %d cycles without connected peers", t.cycles)
+			am.sendToTelegram(NoPeersAlert{Height: checkpoint, Since: t.since, Cycles: t.cycles, Recovered: true})
+		}
+
+		*t = NoPeersTracker{threshold: t.threshold}
+		return
+	}
+
+	if t.since.IsZero() {
+		t.since = now
+	}
+	t.cycles++
+
+	if !t.alerted && now.Sub(t.since) >= t.threshold {
+		t.alerted = true
+		am.sendToTelegram(NoPeersAlert{Height: checkpoint, Since: t.since, Cycles: t.cycles})
+	}
+}
+
+func (a NoPeersAlert) getType() AlertType {
+	return NoPeersAlertType
+}
+
+func (a NoPeersAlert) createMessage() string {
+	var buf bytes.Buffer
+
+	if a.Recovered {
+		fmt.Fprintf(&buf, "<b>✅ Hash comparison resumed </b>\n\n")
+		fmt.Fprintf(&buf, "Block hashes are compared again at %d height, after %d cycles without connected peers since %s.",
+			a.Height, a.Cycles, a.Since.UTC().Format(time.RFC3339))
+		return buf.String()
+	}
+
+	fmt.Fprintf(&buf, "<b>⚠️ No peers to compare hashes </b>\n\n")
+	fmt.Fprintf(&buf, "The nodes reported their heights, but every connection was lost before the hashes at %d height were compared, for %d cycles since %s.\n\n",
+		a.Height, a.Cycles, a.Since.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&buf, "Forks are not detected until the checker can compare hashes again.")
+
+	return buf.String()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoPeersTracker(t *testing.T) {
+	t.Run("Threshold", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		am.noPeers = NewNoPeersTracker(time.Hour)
+
+		am.handleNoPeers(100, true)
+		am.handleNoPeers(100, true)
+		assert.Equal(t, 2, am.noPeers.cycles)
+		assert.False(t, am.noPeers.alerted)
+
+		// The condition is measured from its first cycle.
+		am.noPeers.since = time.Now().Add(-time.Hour)
+		am.handleNoPeers(100, true)
+		assert.True(t, am.noPeers.alerted)
+		assert.Equal(t, 3, am.noPeers.cycles)
+
+		am.handleNoPeers(100, false)
+		assert.False(t, am.noPeers.alerted)
+		assert.Zero(t, am.noPeers.cycles)
+		assert.True(t, am.noPeers.since.IsZero())
+		assert.Equal(t, time.Hour, am.noPeers.threshold)
+	})
+
+	t.Run("Connections lost in the hash phase", func(t *testing.T) {
+		nodeA := newTestNode(t, 10, sdk.Hash{1})
+		nodeB := newTestNode(t, 10, sdk.Hash{1})
+		nodeA.failHashes.Store(true)
+		nodeB.failHashes.Store(true)
+
+		fc := newReportTestForkChecker(t, nodeA, nodeB)
+		fc.alertManager.noPeers = NewNoPeersTracker(0)
+
+		report := fc.checkCycle(context.Background())
+		assert.Equal(t, ErrNoBlockHashes.Error(), report.Error)
+		assert.Len(t, report.NoData, 2)
+		assert.Equal(t, uint64(10), fc.checkpoint)
+		assert.True(t, fc.alertManager.noPeers.alerted)
+		assert.Equal(t, 1, fc.alertManager.noPeers.cycles)
+
+		// The condition lasts across cycles until a hash is collected again.
+		fc.checkCycle(context.Background())
+		assert.Equal(t, 2, fc.alertManager.noPeers.cycles)
+
+		nodeA.failHashes.Store(false)
+		nodeB.failHashes.Store(false)
+		fc.checkCycle(context.Background())
+		assert.False(t, fc.alertManager.noPeers.alerted)
+		assert.Equal(t, uint64(11), fc.checkpoint)
+	})
+
+	t.Run("Disabled tracker", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		am.handleNoPeers(100, true)
+		assert.Nil(t, am.noPeers)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		assert.Equal(t, DefaultNoPeersDurationThreshold, (&AlertConfig{}).getNoPeersDurationThreshold())
+		assert.Equal(t, time.Minute, (&AlertConfig{NoPeersDurationThreshold: "1m"}).getNoPeersDurationThreshold())
+	})
+
+	t.Run("Alert", func(t *testing.T) {
+		since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		alert := NoPeersAlert{Height: 100, Since: since, Cycles: 7}
+		assert.Equal(t, NoPeersAlertType, alert.getType())
+		assert.Contains(t, alert.createMessage(), "100 height")
+		assert.Contains(t, alert.createMessage(), "7 cycles since 2024-05-01T12:00:00Z")
+		assert.Contains(t, alert.createMessage(), "Forks are not detected")
+
+		alert.Recovered = true
+		assert.Contains(t, alert.createMessage(), "compared again")
+	})
+}
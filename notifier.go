@@ -7,15 +7,79 @@ import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// threadedAlertTypes are the alerts of an incident that lasts until the condition clears, so that its repeated
+// alerts can be threaded under the first one.
+var threadedAlertTypes = map[AlertType]struct{}{
+	OfflineAlertType: {},
+	SyncAlertType:    {},
+	HashAlertType:    {},
+}
+
 func (n *Notifier) sendToTelegram(msg string) error {
-	msgConfig := tgbotapi.NewMessage(n.chatID, msg)
+	return n.sendToChat(n.chatID, msg)
+}
+
+func (n *Notifier) sendToChat(chatID int64, msg string) error {
+	_, err := n.send(chatID, msg, 0)
+	return err
+}
+
+// send returns the ID of the sent message, or 0 in a dry run. With replyTo set, the message is sent as a reply,
+// or on its own if the original message has been deleted.
+func (n *Notifier) send(chatID int64, msg string, replyTo int) (int, error) {
+	if n.dryRun {
+		if replyTo != 0 {
+			log.Printf("Dry run, not sending to chat %d in reply to %d:\n%s", chatID, replyTo, msg)
+		} else {
+			log.Printf("Dry run, not sending to chat %d:\n%s", chatID, msg)
+		}
+		return 0, nil
+	}
+
+	msgConfig := tgbotapi.NewMessage(chatID, msg)
 	msgConfig.ParseMode = "HTML"
+	msgConfig.ReplyToMessageID = replyTo
+	msgConfig.AllowSendingWithoutReply = true
 
-	_, err := n.bot.Send(msgConfig)
+	sent, err := n.bot.Send(msgConfig)
 	if err != nil {
-		return fmt.Errorf("failed to send message to telegram: %v", err)
+		return 0, fmt.Errorf("failed to send message to telegram: %v", err)
 	}
 
 	log.Printf("Alerted Telegram!")
+	return sent.MessageID, nil
+}
+
+// sendToThread sends an alert of an incident. The first alert becomes the root of the incident's thread and the
+// following ones are sent as replies to it, until the thread is closed. Without threading, every alert is sent
+// on its own.
+func (n *Notifier) sendToThread(alertType AlertType, msg string) error {
+	if _, threaded := threadedAlertTypes[alertType]; !threaded || n.threads == nil {
+		return n.sendToTelegram(msg)
+	}
+
+	root, exists := n.threads[alertType]
+	messageID, err := n.send(n.chatID, msg, root)
+	if err != nil {
+		return err
+	}
+
+	if !exists && messageID != 0 {
+		n.threads[alertType] = messageID
+	}
+
 	return nil
 }
+
+// closeThread ends the incident of the alert type. If a thread was started, the message is sent as its last reply.
+func (n *Notifier) closeThread(alertType AlertType, msg string) error {
+	root, exists := n.threads[alertType]
+	if !exists {
+		return nil
+	}
+
+	delete(n.threads, alertType)
+
+	_, err := n.send(n.chatID, msg, root)
+	return err
+}
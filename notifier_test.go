@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTelegram answers sendMessage with increasing message IDs and records each message and what it replied to.
+type testTelegram struct {
+	mu       sync.Mutex
+	replies  []string
+	messages []string
+}
+
+func newTestBot(t *testing.T) (*tgbotapi.BotAPI, *testTelegram) {
+	telegram := &testTelegram{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+
+		telegram.mu.Lock()
+		telegram.replies = append(telegram.replies, r.FormValue("reply_to_message_id"))
+		telegram.messages = append(telegram.messages, r.FormValue("text"))
+		messageID := len(telegram.replies)
+		telegram.mu.Unlock()
+
+		fmt.Fprintf(w, `{"ok":true,"result":{"message_id":%d}}`, messageID)
+	}))
+	t.Cleanup(server.Close)
+
+	bot := &tgbotapi.BotAPI{Token: "token", Client: server.Client()}
+	bot.SetAPIEndpoint(server.URL + "/bot%s/%s")
+
+	return bot, telegram
+}
+
+func TestNotifierThreads(t *testing.T) {
+	t.Run("Threaded", func(t *testing.T) {
+		bot, telegram := newTestBot(t)
+		notifier := &Notifier{bot: bot, enabled: true, threads: make(map[AlertType]int)}
+
+		require.NoError(t, notifier.sendToThread(OfflineAlertType, "offline"))
+		require.NoError(t, notifier.sendToThread(HashAlertType, "fork"))
+		require.NoError(t, notifier.sendToThread(OfflineAlertType, "still offline"))
+		require.NoError(t, notifier.closeThread(OfflineAlertType, "resolved"))
+
+		// Closing a thread that was never started sends nothing.
+		require.NoError(t, notifier.closeThread(SyncAlertType, "resolved"))
+
+		// The next incident starts a new thread, alerts of other types are never threaded.
+		require.NoError(t, notifier.sendToThread(OfflineAlertType, "offline again"))
+		require.NoError(t, notifier.sendToThread(DigestAlertType, "digest"))
+		require.NoError(t, notifier.sendToThread(DigestAlertType, "digest"))
+
+		assert.Equal(t, []string{"", "", "1", "1", "", "", ""}, telegram.replies)
+		assert.Equal(t, map[AlertType]int{HashAlertType: 2, OfflineAlertType: 5}, notifier.threads)
+	})
+
+	t.Run("Not threaded", func(t *testing.T) {
+		bot, telegram := newTestBot(t)
+		notifier := &Notifier{bot: bot, enabled: true}
+
+		require.NoError(t, notifier.sendToThread(OfflineAlertType, "offline"))
+		require.NoError(t, notifier.sendToThread(OfflineAlertType, "still offline"))
+		require.NoError(t, notifier.closeThread(OfflineAlertType, "resolved"))
+
+		assert.Equal(t, []string{"", ""}, telegram.replies)
+	})
+}
+
+func TestAlertManagerThreads(t *testing.T) {
+	bot, telegram := newTestBot(t)
+
+	am := newIncidentTestAlertManager(t, nil)
+	am.notifier = &Notifier{bot: bot, enabled: true, threads: make(map[AlertType]int)}
+
+	am.handleHashAlert(10, nil, nil)
+	am.handleHashAlert(11, nil, nil)
+	am.handleHashRecovery()
+
+	// The recovery without an open incident sends nothing.
+	am.handleHashRecovery()
+
+	assert.Equal(t, []string{"", "1", "1"}, telegram.replies)
+	assert.Empty(t, am.notifier.threads)
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPauseMaxDuration = time.Hour * 24
+
+	// AlertsPauseScope keeps checking but sends no alerts or pages, ChecksPauseScope stops the check loop as well.
+	AlertsPauseScope = "alerts"
+	ChecksPauseScope = "checks"
+
+	// pausePollInterval is how often the check loop looks whether a pause of the checks has ended.
+	pausePollInterval = 10 * time.Second
+)
+
+var (
+	ErrInvalidPauseConfig = errors.New("invalid pause config")
+	ErrInvalidPause       = errors.New("invalid pause")
+)
+
+type (
+	// Pause suspends monitoring for a limited time, e.g. during planned network upgrades, and resumes it
+	// automatically. It is shared with the command listener and the status server, so all access goes through
+	// its mutex.
+	Pause struct {
+		scope       string
+		maxDuration time.Duration
+		adminIDs    map[int64]struct{}
+
+		mu     sync.Mutex
+		active bool
+		by     string
+		reason string
+		since  time.Time
+		until  time.Time
+	}
+
+	PauseStatus struct {
+		By      string    `json:"by"`
+		Reason  string    `json:"reason,omitempty"`
+		Scope   string    `json:"scope"`
+		Since   time.Time `json:"since"`
+		Until   time.Time `json:"until"`
+		Message string    `json:"message"`
+	}
+
+	// PauseAlert announces that monitoring was paused or resumed. It is sent even while alerts are paused.
+	PauseAlert struct {
+		Paused    bool
+		Status    PauseStatus
+		ResumedBy string
+	}
+)
+
+func validatePauseConfig(config PauseConfig) error {
+	switch config.Scope {
+	case "", AlertsPauseScope, ChecksPauseScope:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown scope %s", ErrInvalidPauseConfig, config.Scope)
+	}
+}
+
+func NewPause(config PauseConfig) *Pause {
+	scope := config.Scope
+	if scope == "" {
+		scope = AlertsPauseScope
+	}
+
+	adminIDs := make(map[int64]struct{}, len(config.AdminIDs))
+	for _, id := range config.AdminIDs {
+		adminIDs[id] = struct{}{}
+	}
+
+	return &Pause{
+		scope:       scope,
+		maxDuration: config.getMaxDuration(),
+		adminIDs:    adminIDs,
+	}
+}
+
+func (p *Pause) isAdmin(userID int64) bool {
+	if p == nil {
+		return false
+	}
+
+	_, exists := p.adminIDs[userID]
+	return exists
+}
+
+// pause starts a pause or replaces the running one. The duration is capped at the configured maximum, so a
+// forgotten pause still ends.
+func (p *Pause) pause(by, reason string, duration time.Duration, now time.Time) (PauseStatus, error) {
+	if p == nil {
+		return PauseStatus{}, fmt.Errorf("%w: pausing is not available", ErrInvalidPause)
+	}
+
+	if duration <= 0 {
+		return PauseStatus{}, fmt.Errorf("%w: duration must be positive", ErrInvalidPause)
+	}
+
+	if duration > p.maxDuration {
+		duration = p.maxDuration
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.active = true
+	p.by = by
+	p.reason = reason
+	p.since = now
+	p.until = now.Add(duration)
+
+	return p.status(), nil
+}
+
+// resume ends the pause and returns it, if one was running.
+func (p *Pause) resume() (PauseStatus, bool) {
+	if p == nil {
+		return PauseStatus{}, false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.active {
+		return PauseStatus{}, false
+	}
+
+	p.active = false
+	return p.status(), true
+}
+
+// expire ends the pause once its time is up and returns it.
+func (p *Pause) expire(now time.Time) (PauseStatus, bool) {
+	if p == nil {
+		return PauseStatus{}, false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.active || now.Before(p.until) {
+		return PauseStatus{}, false
+	}
+
+	p.active = false
+	return p.status(), true
+}
+
+// snapshot returns the running pause, or nil.
+func (p *Pause) snapshot(now time.Time) *PauseStatus {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.active || !now.Before(p.until) {
+		return nil
+	}
+
+	status := p.status()
+	return &status
+}
+
+// status must be called with the mutex held.
+func (p *Pause) status() PauseStatus {
+	what := "Alerts"
+	if p.scope == ChecksPauseScope {
+		what = "Monitoring"
+	}
+
+	return PauseStatus{
+		By:      p.by,
+		Reason:  p.reason,
+		Scope:   p.scope,
+		Since:   p.since,
+		Until:   p.until,
+		Message: fmt.Sprintf("%s paused by %s until %s", what, p.by, p.until.UTC().Format("2006-01-02 15:04 MST")),
+	}
+}
+
+// alertsPaused reports whether alerts are suppressed, which is the case for either scope.
+func (p *Pause) alertsPaused(now time.Time) bool {
+	return p.snapshot(now) != nil
+}
+
+func (p *Pause) checksPaused(now time.Time) bool {
+	status := p.snapshot(now)
+	return status != nil && status.Scope == ChecksPauseScope
+}
+
+func (a PauseAlert) getType() AlertType {
+	return PauseAlertType
+}
+
+func (a PauseAlert) createMessage() string {
+	var buf bytes.Buffer
+
+	if !a.Paused {
+		fmt.Fprintf(&buf, "<b>▶️ Monitoring resumed </b>\n\n")
+		if a.ResumedBy != "" {
+			fmt.Fprintf(&buf, "Resumed by %s after %s.", a.ResumedBy, time.Since(a.Status.Since).Round(time.Minute))
+		} else {
+			fmt.Fprintf(&buf, "The pause by %s has ended.", a.Status.By)
+		}
+		return buf.String()
+	}
+
+	fmt.Fprintf(&buf, "<b>⏸ Monitoring paused </b>\n\n%s.", a.Status.Message)
+	if a.Status.Reason != "" {
+		fmt.Fprintf(&buf, "\nReason: %s", a.Status.Reason)
+	}
+
+	if a.Status.Scope == AlertsPauseScope {
+		fmt.Fprintf(&buf, "\nThe checks keep running, but no alerts are sent.")
+	}
+
+	fmt.Fprintf(&buf, "\nUse /resume to resume earlier.")
+
+	return buf.String()
+}
+
+func (am *AlertManager) pauseMonitoring(by, reason string, duration time.Duration) (PauseStatus, error) {
+	status, err := am.pause.pause(by, reason, duration, time.Now())
+	if err != nil {
+		return PauseStatus{}, err
+	}
+
+	log.Print(status.Message)
+	am.sendUntracked(PauseAlert{Paused: true, Status: status})
+
+	return status, nil
+}
+
+func (am *AlertManager) resumeMonitoring(by string) bool {
+	status, resumed := am.pause.resume()
+	if !resumed {
+		return false
+	}
+
+	log.Printf("Monitoring resumed by %s", by)
+	am.sendUntracked(PauseAlert{Status: status, ResumedBy: by})
+
+	return true
+}
+
+// handlePause announces that a pause has ended on its own.
+func (am *AlertManager) handlePause() {
+	if status, expired := am.pause.expire(time.Now()); expired {
+		log.Printf("Pause by %s has ended", status.By)
+		am.sendUntracked(PauseAlert{Status: status})
+	}
+}
+
+// authorized checks the bearer token of a pause or resume request. The endpoints are disabled without a token.
+func (s *StatusServer) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+
+	expected := "Bearer " + s.apiToken
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expected)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+
+	return true
+}
+
+// servePause pauses monitoring for the duration given in the query, e.g. POST /pause?duration=2h&by=ops.
+func (s *StatusServer) servePause(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+
+	duration, err := time.ParseDuration(r.URL.Query().Get("duration"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid duration: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	by := r.URL.Query().Get("by")
+	if by == "" {
+		by = "API"
+	}
+
+	status, err := s.alertManager.pauseMonitoring(by, r.URL.Query().Get("reason"), duration)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Printf("failed writing pause response: %v", err)
+	}
+}
+
+func (s *StatusServer) serveResume(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+
+	by := r.URL.Query().Get("by")
+	if by == "" {
+		by = "API"
+	}
+
+	if !s.alertManager.resumeMonitoring(by) {
+		http.Error(w, "monitoring is not paused", http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPause(t *testing.T) {
+	now := time.Now()
+
+	t.Run("Expiry", func(t *testing.T) {
+		pause := NewPause(PauseConfig{MaxDuration: "3h"})
+
+		status, err := pause.pause("@ops", "upgrade", 5*time.Hour, now)
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(3*time.Hour), status.Until)
+		assert.Contains(t, status.Message, "Alerts paused by @ops until")
+
+		assert.True(t, pause.alertsPaused(now))
+		assert.False(t, pause.checksPaused(now))
+
+		_, expired := pause.expire(now.Add(time.Hour))
+		assert.False(t, expired)
+
+		_, expired = pause.expire(now.Add(3 * time.Hour))
+		assert.True(t, expired)
+		assert.Nil(t, pause.snapshot(now))
+
+		_, err = pause.pause("@ops", "", 0, now)
+		require.ErrorIs(t, err, ErrInvalidPause)
+
+		require.ErrorIs(t, validatePauseConfig(PauseConfig{Scope: "everything"}), ErrInvalidPauseConfig)
+	})
+
+	t.Run("Alerts suppressed", func(t *testing.T) {
+		pager := &fakePager{}
+		am := newIncidentTestAlertManager(t, pager)
+		am.notifier = &Notifier{enabled: true, dryRun: true}
+		am.pause = NewPause(PauseConfig{Scope: ChecksPauseScope})
+
+		_, err := am.pauseMonitoring("@ops", "", time.Hour)
+		require.NoError(t, err)
+		assert.True(t, am.pause.checksPaused(time.Now()))
+
+		am.handleHashAlert(10, map[string]sdk.Hash{"a": {1}, "b": {2}}, nil)
+		assert.Empty(t, pager.triggered)
+		assert.NotContains(t, am.lastAlertTimes, HashAlertType)
+
+		assert.True(t, am.resumeMonitoring("@ops"))
+		assert.False(t, am.resumeMonitoring("@ops"))
+
+		am.handleHashAlert(11, map[string]sdk.Hash{"a": {1}, "b": {2}}, nil)
+		assert.Len(t, pager.triggered, 1)
+		assert.Contains(t, am.lastAlertTimes, HashAlertType)
+	})
+
+	t.Run("Commands", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		am.pause = NewPause(PauseConfig{AdminIDs: []int64{42}})
+		listener := NewCommandListener(nil, -1, am)
+
+		assert.True(t, listener.authorized("pause", &tgbotapi.User{ID: 42}))
+		assert.False(t, listener.authorized("pause", &tgbotapi.User{ID: 7}))
+		assert.False(t, listener.authorized("resume", nil))
+		assert.True(t, listener.authorized("incident", &tgbotapi.User{ID: 7}))
+
+		assert.Contains(t, listener.handle("pause", "", "@ops"), "Usage")
+		assert.Contains(t, listener.handle("pause", "soon", "@ops"), "Invalid duration")
+
+		assert.Empty(t, listener.handle("pause", "2h network upgrade", "@ops"))
+		status := am.pause.snapshot(time.Now())
+		require.NotNil(t, status)
+		assert.Equal(t, "network upgrade", status.Reason)
+		assert.Equal(t, "@ops", status.By)
+
+		assert.Empty(t, listener.handle("resume", "", "@ops"))
+		assert.Equal(t, "Monitoring is not paused.", listener.handle("resume", "", "@ops"))
+	})
+
+	t.Run("API", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		am.pause = NewPause(PauseConfig{})
+		server := &StatusServer{alertManager: am, apiToken: "secret"}
+
+		request := func(handler http.HandlerFunc, method, target, token string) int {
+			req := httptest.NewRequest(method, target, nil)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			recorder := httptest.NewRecorder()
+			handler(recorder, req)
+			return recorder.Code
+		}
+
+		assert.Equal(t, http.StatusUnauthorized, request(server.servePause, http.MethodPost, "/pause?duration=1h", "wrong"))
+		assert.Equal(t, http.StatusMethodNotAllowed, request(server.servePause, http.MethodGet, "/pause?duration=1h", "secret"))
+		assert.Equal(t, http.StatusBadRequest, request(server.servePause, http.MethodPost, "/pause", "secret"))
+		assert.Equal(t, http.StatusOK, request(server.servePause, http.MethodPost, "/pause?duration=1h&by=ci", "secret"))
+		assert.Equal(t, "ci", am.pause.snapshot(time.Now()).By)
+
+		assert.Equal(t, http.StatusNoContent, request(server.serveResume, http.MethodPost, "/resume", "secret"))
+		assert.Equal(t, http.StatusConflict, request(server.serveResume, http.MethodPost, "/resume", "secret"))
+	})
+}
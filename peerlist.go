@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+)
+
+const (
+	DefaultPeerListCheckInterval       = time.Minute * 5
+	DefaultPeerListAlertRepeatInterval = time.Hour * 2
+
+	// minPeerLists is the number of peer lists needed for a majority to mean anything.
+	minPeerLists = 2
+)
+
+var ErrUnknownCriticalNode = errors.New("critical node is not in the configured nodes")
+
+type (
+	// PeerListMonitor compares the peer lists reported by the REST gateways, to catch critical nodes that are up but
+	// have been dropped by the rest of the network.
+	PeerListMonitor struct {
+		config       PeerListConfig
+		urls         []string
+		nodes        []*health.NodeInfo
+		client       *http.Client
+		alertManager *AlertManager
+
+		mu             sync.Mutex
+		isolated       []IsolatedNode
+		lastAlertTimes map[string]time.Time
+	}
+
+	IsolatedNode struct {
+		Node        health.NodeInfo `json:"-"`
+		Name        string          `json:"name"`
+		MissingFrom []string        `json:"missingFrom"`
+		Lists       int             `json:"lists"`
+	}
+
+	PeerListAlert struct {
+		Nodes []IsolatedNode
+	}
+
+	peerList struct {
+		url       string
+		publicKey string
+		peers     map[string]struct{}
+	}
+
+	peerDTO struct {
+		PublicKey string `json:"publicKey"`
+	}
+)
+
+func (a PeerListAlert) getType() AlertType {
+	return PeerListAlertType
+}
+
+func (a PeerListAlert) createMessage() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "<b>⚠️ Warning - Isolated nodes </b>")
+	fmt.Fprintf(&buf, "\n\nMissing from the majority of peer lists (%d):", len(a.Nodes))
+
+	fmt.Fprintf(&buf, "<pre>")
+	for _, node := range a.Nodes {
+		fmt.Fprintf(&buf, "%s\n  missing from %d/%d: %s\n", nodeName(node.Node), len(node.MissingFrom), node.Lists, strings.Join(node.MissingFrom, ", "))
+	}
+	fmt.Fprintf(&buf, "</pre>")
+
+	return buf.String()
+}
+
+func (a PeerListAlert) redact(r *Redactor) Alert {
+	nodes := make([]IsolatedNode, 0, len(a.Nodes))
+	for _, node := range a.Nodes {
+		node.Node = r.node(node.Node)
+		node.Name = nodeName(node.Node)
+
+		missingFrom := make([]string, 0, len(node.MissingFrom))
+		for _, url := range node.MissingFrom {
+			missingFrom = append(missingFrom, r.label(url, ""))
+		}
+		node.MissingFrom = missingFrom
+
+		nodes = append(nodes, node)
+	}
+
+	a.Nodes = nodes
+	return a
+}
+
+// criticalNodes returns the configured nodes whose endpoints are listed, or all of them if none are.
+func criticalNodes(nodeInfos []*health.NodeInfo, endpoints []string) ([]*health.NodeInfo, error) {
+	if len(endpoints) == 0 {
+		return nodeInfos, nil
+	}
+
+	critical := make([]*health.NodeInfo, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		found := false
+		for _, info := range nodeInfos {
+			if info.Endpoint == endpoint {
+				critical = append(critical, info)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCriticalNode, endpoint)
+		}
+	}
+
+	return critical, nil
+}
+
+func NewPeerListMonitor(config PeerListConfig, urls []string, nodes []*health.NodeInfo, alertManager *AlertManager) *PeerListMonitor {
+	return &PeerListMonitor{
+		config:         config,
+		urls:           urls,
+		nodes:          nodes,
+		client:         &http.Client{Timeout: apiGatewayRequestTimeout},
+		alertManager:   alertManager,
+		lastAlertTimes: make(map[string]time.Time),
+	}
+}
+
+// Run compares the peer lists every check interval until the context is cancelled.
+func (m *PeerListMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.getCheckInterval())
+	defer ticker.Stop()
+
+	for {
+		m.checkAll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *PeerListMonitor) checkAll() {
+	results := make([]*peerList, len(m.urls))
+
+	var wg sync.WaitGroup
+	for i, url := range m.urls {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+
+			list, err := m.fetchPeerList(url)
+			if err != nil {
+				log.Printf("failed fetching peer list from %s: %v", url, err)
+				return
+			}
+			results[i] = list
+		}(i, url)
+	}
+	wg.Wait()
+
+	var lists []*peerList
+	for _, list := range results {
+		if list != nil {
+			lists = append(lists, list)
+		}
+	}
+
+	isolated := m.findIsolated(lists, time.Now())
+
+	var alerted []IsolatedNode
+
+	m.mu.Lock()
+	m.isolated = isolated
+	for _, node := range isolated {
+		key := node.Node.IdentityKey.String()
+		if time.Since(m.lastAlertTimes[key]) > m.config.getAlertRepeatInterval() {
+			alerted = append(alerted, node)
+			m.lastAlertTimes[key] = time.Now()
+		}
+	}
+	m.mu.Unlock()
+
+	if len(alerted) > 0 {
+		m.alertManager.sendUntracked(PeerListAlert{Nodes: alerted})
+	}
+}
+
+// findIsolated returns the critical nodes missing from more than half of the peer lists. A gateway's own node is
+// not expected in its peer list, so that list does not count for it.
+func (m *PeerListMonitor) findIsolated(lists []*peerList, now time.Time) []IsolatedNode {
+	var isolated []IsolatedNode
+
+	for _, node := range m.nodes {
+		if m.alertManager != nil && m.alertManager.maintenance.inMaintenance(node.Endpoint, now) {
+			continue
+		}
+
+		key := strings.ToUpper(node.IdentityKey.String())
+
+		var eligible int
+		var missingFrom []string
+		for _, list := range lists {
+			if list.publicKey == key {
+				continue
+			}
+
+			eligible++
+			if _, exists := list.peers[key]; !exists {
+				missingFrom = append(missingFrom, list.url)
+			}
+		}
+
+		if eligible < minPeerLists || len(missingFrom)*2 <= eligible {
+			continue
+		}
+
+		log.Printf("node %s is missing from %d of %d peer lists", node.Endpoint, len(missingFrom), eligible)
+		isolated = append(isolated, IsolatedNode{
+			Node:        *node,
+			Name:        nodeName(*node),
+			MissingFrom: missingFrom,
+			Lists:       eligible,
+		})
+	}
+
+	return isolated
+}
+
+func (m *PeerListMonitor) fetchPeerList(url string) (*peerList, error) {
+	self := &peerDTO{}
+	if err := m.getJSON(url, "/node/info", self); err != nil {
+		return nil, err
+	}
+
+	var peers []peerDTO
+	if err := m.getJSON(url, "/node/peers", &peers); err != nil {
+		return nil, err
+	}
+
+	list := &peerList{
+		url:       url,
+		publicKey: strings.ToUpper(self.PublicKey),
+		peers:     make(map[string]struct{}, len(peers)),
+	}
+
+	for _, peer := range peers {
+		list.peers[strings.ToUpper(peer.PublicKey)] = struct{}{}
+	}
+
+	return list, nil
+}
+
+func (m *PeerListMonitor) getJSON(url, route string, dto interface{}) error {
+	resp, err := m.client.Get(strings.TrimRight(url, "/") + route)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", route, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(dto); err != nil {
+		return fmt.Errorf("failed decoding %s: %v", route, err)
+	}
+
+	return nil
+}
+
+func (m *PeerListMonitor) snapshot() []IsolatedNode {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]IsolatedNode, len(m.isolated))
+	copy(result, m.isolated)
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
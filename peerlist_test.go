@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPeerListGateway(t *testing.T, self string, peers ...string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/node/info":
+			fmt.Fprintf(w, `{"publicKey":"%s"}`, self)
+		case "/node/peers":
+			dtos := make([]string, 0, len(peers))
+			for _, peer := range peers {
+				dtos = append(dtos, fmt.Sprintf(`{"publicKey":"%s"}`, strings.ToLower(peer)))
+			}
+			fmt.Fprintf(w, "[%s]", strings.Join(dtos, ","))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestPeerListMonitor(t *testing.T) {
+	nodeInfos, err := parseNodes([]Node{
+		{Endpoint: "10.0.0.1:7900", IdentityKey: "AF7A80E9D6C2A4F5B46B90A1D16E95D4C1B8A3E8D5D1479D7C802C475D70A2E", FriendlyName: "nodeA"},
+		{Endpoint: "10.0.0.2:7900", IdentityKey: "BF7A80E9D6C2A4F5B46B90A1D16E95D4C1B8A3E8D5D1479D7C802C475D70A2E", FriendlyName: "nodeB"},
+		{Endpoint: "10.0.0.3:7900", IdentityKey: "CF7A80E9D6C2A4F5B46B90A1D16E95D4C1B8A3E8D5D1479D7C802C475D70A2E", FriendlyName: "nodeC"},
+	})
+	require.NoError(t, err)
+
+	keyA, keyB, keyC := nodeInfos[0].IdentityKey.String(), nodeInfos[1].IdentityKey.String(), nodeInfos[2].IdentityKey.String()
+
+	t.Run("Critical nodes", func(t *testing.T) {
+		nodes, err := criticalNodes(nodeInfos, nil)
+		require.NoError(t, err)
+		assert.Len(t, nodes, 3)
+
+		nodes, err = criticalNodes(nodeInfos, []string{"10.0.0.2:7900"})
+		require.NoError(t, err)
+		require.Len(t, nodes, 1)
+		assert.Equal(t, "nodeB", nodes[0].FriendlyName)
+
+		_, err = criticalNodes(nodeInfos, []string{"10.0.0.9:7900"})
+		require.ErrorIs(t, err, ErrUnknownCriticalNode)
+	})
+
+	t.Run("Isolated node", func(t *testing.T) {
+		// nodeC is only known to the gateway running on nodeB. Its own gateway does not count.
+		gatewayA := newTestPeerListGateway(t, keyA, keyB)
+		gatewayB := newTestPeerListGateway(t, keyB, keyA, keyC)
+		gatewayC := newTestPeerListGateway(t, keyC, keyA, keyB)
+		gatewayD := newTestPeerListGateway(t, "DF7A80E9D6C2A4F5B46B90A1D16E95D4C1B8A3E8D5D1479D7C802C475D70A2E", keyA, keyB)
+		urls := []string{gatewayA.URL, gatewayB.URL, gatewayC.URL, gatewayD.URL}
+
+		monitor := NewPeerListMonitor(PeerListConfig{}, urls, nodeInfos, nil)
+
+		var lists []*peerList
+		for _, url := range urls {
+			list, err := monitor.fetchPeerList(url)
+			require.NoError(t, err)
+			lists = append(lists, list)
+		}
+
+		isolated := monitor.findIsolated(lists, time.Now())
+		require.Len(t, isolated, 1)
+		assert.Equal(t, "nodeC", isolated[0].Node.FriendlyName)
+		assert.Equal(t, []string{gatewayA.URL, gatewayD.URL}, isolated[0].MissingFrom)
+		assert.Equal(t, 3, isolated[0].Lists)
+
+		msg := PeerListAlert{Nodes: isolated}.createMessage()
+		assert.Contains(t, msg, "nodeC(10.0.0.3)")
+		assert.Contains(t, msg, "missing from 2/3")
+
+		// Custom templates may use the precomputed name, so it is redacted along with the node.
+		redacted := PeerListAlert{Nodes: isolated}.redact(NewRedactor(HashedPrivacyMode, nodeInfos)).(PeerListAlert)
+		assert.Equal(t, nodeName(redacted.Nodes[0].Node), redacted.Nodes[0].Name)
+		assert.NotContains(t, redacted.Nodes[0].Name, "10.0.0.3")
+		assert.NotContains(t, redacted.Nodes[0].Name, "nodeC")
+	})
+
+	t.Run("Not enough lists", func(t *testing.T) {
+		gatewayA := newTestPeerListGateway(t, keyA)
+		monitor := NewPeerListMonitor(PeerListConfig{}, []string{gatewayA.URL}, nodeInfos, nil)
+
+		list, err := monitor.fetchPeerList(gatewayA.URL)
+		require.NoError(t, err)
+		assert.Empty(t, monitor.findIsolated([]*peerList{list}, time.Now()))
+	})
+
+	t.Run("Maintenance", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		am.maintenance, err = NewMaintenanceSchedule([]MaintenanceWindow{{Start: "2024-09-01T02:00:00Z", End: "2024-09-01T03:00:00Z", Nodes: []string{"10.0.0.3:7900"}}})
+		require.NoError(t, err)
+
+		monitor := NewPeerListMonitor(PeerListConfig{}, nil, []*health.NodeInfo{nodeInfos[2]}, am)
+		lists := []*peerList{
+			{url: "a", peers: map[string]struct{}{}},
+			{url: "b", peers: map[string]struct{}{}},
+		}
+
+		assert.Len(t, monitor.findIsolated(lists, time.Date(2024, 9, 1, 1, 0, 0, 0, time.UTC)), 1)
+		assert.Empty(t, monitor.findIsolated(lists, time.Date(2024, 9, 1, 2, 30, 0, 0, time.UTC)))
+	})
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+)
+
+// PingMonitor pings the pooled connections between check cycles, so a node going down is noticed when it happens
+// rather than at the next cycle, which can be minutes away while the checker waits for a slow height.
+type PingMonitor struct {
+	interval     time.Duration
+	pool         *NodePool
+	alertManager *AlertManager
+}
+
+// NewPingMonitor returns nil if pings are disabled.
+func NewPingMonitor(config PoolConfig, pool *NodePool, alertManager *AlertManager) *PingMonitor {
+	interval := config.getPingInterval()
+	if interval <= 0 {
+		return nil
+	}
+
+	return &PingMonitor{interval: interval, pool: pool, alertManager: alertManager}
+}
+
+func (m *PingMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if failed := m.pool.Ping(time.Now().Add(-m.interval)); len(failed) > 0 {
+			m.alertManager.handlePingFailures(failed, time.Now())
+		}
+	}
+}
+
+// Ping sends a chain info request on every connection that has been idle since the given time; the others are known
+// to be alive. Connections that fail are dropped, so the next ConnectToNodes dials the node again, and are returned
+// keyed by identity key.
+func (p *NodePool) Ping(idleSince time.Time) map[string]*health.NodeInfo {
+	var idle []*nodeConn
+	for _, conn := range p.connections() {
+		if conn.idleSince(idleSince) {
+			idle = append(idle, conn)
+		}
+	}
+
+	var mu sync.Mutex
+	failed := make(map[string]*health.NodeInfo)
+
+	p.forEach(len(idle), func(i int) {
+		conn := idle[i]
+		if _, err := conn.chainInfo(); err != nil {
+			p.drop(conn, err)
+
+			mu.Lock()
+			failed[conn.info.IdentityKey.String()] = conn.info
+			mu.Unlock()
+		}
+	})
+
+	return failed
+}
+
+// handlePingFailures starts the offline time of the configured nodes whose ping failed, so the offline duration
+// threshold counts from the failure rather than from the next check cycle. The check cycle still decides whether a
+// node is offline: if it can be dialed again, its offline time is reset.
+func (am *AlertManager) handlePingFailures(failed map[string]*health.NodeInfo, now time.Time) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	for _, info := range am.nodeInfos {
+		key := info.IdentityKey.String()
+		if _, exists := failed[key]; !exists {
+			continue
+		}
+
+		log.Printf("Ping to %s failed, counting it offline since %s", info.Endpoint, now.Format(time.RFC3339))
+
+		if status, exists := am.offlineNodeStats[key]; !exists || status.offlineSince.IsZero() {
+			status.offlineSince = now
+			am.updateNodeStatus(key, status)
+		}
+	}
+}
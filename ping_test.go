@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodePoolPing(t *testing.T) {
+	nodeA := newTestNode(t, 10, sdk.Hash{1})
+	nodeB := newTestNode(t, 10, sdk.Hash{1})
+
+	pool := newTestNodePool(t)
+	_, err := pool.ConnectToNodes([]*health.NodeInfo{nodeA.info(), nodeB.info()}, false)
+	require.NoError(t, err)
+
+	assert.Empty(t, pool.Ping(time.Now()))
+
+	// Connections that just answered are not pinged again.
+	nodeB.delay.Store(int64(time.Second))
+	assert.Empty(t, pool.Ping(time.Now().Add(-time.Minute)))
+
+	failed := pool.Ping(time.Now())
+	require.Len(t, failed, 1)
+	assert.Contains(t, failed, nodeB.info().IdentityKey.String())
+	assert.Len(t, pool.connections(), 1)
+}
+
+func TestHandlePingFailures(t *testing.T) {
+	node := func(am *AlertManager) map[string]*health.NodeInfo {
+		return map[string]*health.NodeInfo{am.nodeInfos[0].IdentityKey.String(): am.nodeInfos[0]}
+	}
+
+	t.Run("Offline since the failed ping", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		am.timedOffline = true
+		failed := node(am)
+
+		// The ping failed longer ago than the offline duration threshold, so the first cycle alerts.
+		am.handlePingFailures(failed, time.Now().Add(-am.config.getOfflineDurationThreshold()))
+		assert.True(t, am.shouldSendOfflineAlert(failed))
+
+		// A node that can be dialed again is no longer offline.
+		assert.False(t, am.shouldSendOfflineAlert(nil))
+		assert.Empty(t, am.offlineNodeStats)
+	})
+
+	t.Run("Offline since the first cycle", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		am.timedOffline = true
+		failed := node(am)
+
+		assert.False(t, am.shouldSendOfflineAlert(failed))
+
+		status := am.offlineNodeStats[am.nodeInfos[0].IdentityKey.String()]
+		status.offlineSince = status.offlineSince.Add(-am.config.getOfflineDurationThreshold())
+		am.updateNodeStatus(am.nodeInfos[0].IdentityKey.String(), status)
+
+		// A later failed ping does not move the start of the offline time.
+		am.handlePingFailures(failed, time.Now())
+		assert.True(t, am.shouldSendOfflineAlert(failed))
+	})
+
+	t.Run("Without pings", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		failed := node(am)
+
+		am.handlePingFailures(failed, time.Now().Add(-time.Hour))
+		assert.False(t, am.shouldSendOfflineAlert(failed))
+	})
+}
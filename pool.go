@@ -0,0 +1,866 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health/packets"
+	crypto "github.com/proximax-storage/go-xpx-crypto"
+)
+
+const (
+	DefaultPoolConcurrency       = 16
+	DefaultPoolConnectTimeout    = 5 * time.Second
+	DefaultPoolRequestTimeout    = 10 * time.Second
+	DefaultPoolHeightWaitTimeout = time.Minute
+	DefaultPoolHashDeadline      = 30 * time.Second
+
+	heightPollInterval = 5 * time.Second
+
+	NoneSecurityMode   = "none"
+	SignedSecurityMode = "signed"
+
+	// nodeDiscoveryPullPingPacketType requests the node's own network node information.
+	nodeDiscoveryPullPingPacketType = packets.PacketType(601)
+)
+
+var (
+	ErrInvalidPoolConfig = errors.New("invalid pool config")
+	// ErrNoBlockHashes means no node answered with a block hash, e.g. every connection failed mid-cycle.
+	ErrNoBlockHashes = errors.New("no block hashes collected")
+)
+
+type (
+	// NodePool keeps authenticated connections to the nodes and queries them with a bounded number of workers.
+	// Every request has a deadline, so an unresponsive node is dropped instead of stalling the whole check.
+	NodePool struct {
+		client *crypto.KeyPair
+		mode   packets.ConnectionSecurityMode
+
+		concurrency       int
+		connectTimeout    time.Duration
+		requestTimeout    time.Duration
+		heightWaitTimeout time.Duration
+		hashConcurrency   int
+		hashDeadline      time.Duration
+		maxConnections    int
+		hashes            *HashCache
+		discovery         *DiscoveryFilter
+
+		defaultLocalAddress string
+		localAddresses      map[string]string
+
+		mu    sync.Mutex
+		conns map[string]*nodeConn
+		// configured holds the identity keys of the nodes given to the last ConnectToNodes, as opposed to discovered.
+		configured map[string]struct{}
+	}
+
+	nodeConn struct {
+		mu      sync.Mutex
+		info    *health.NodeInfo
+		handler *health.Handler
+		hashes  *HashCache
+		// lastActive is the time of the last successful chain info request, guarded by mu.
+		lastActive time.Time
+		// remoteIP is the address the connection was made to, after name resolution.
+		remoteIP net.IP
+	}
+
+	// nodeTcpIo is health.NodeTcpIo with a deadline on every read and write.
+	nodeTcpIo struct {
+		conn    net.Conn
+		timeout time.Duration
+	}
+
+	heightResult struct {
+		height uint64
+		err    error
+	}
+
+	hashResult struct {
+		conn *nodeConn
+		hash sdk.Hash
+		err  error
+	}
+)
+
+func NewNodePool(client *crypto.KeyPair, mode packets.ConnectionSecurityMode, config PoolConfig) *NodePool {
+	return &NodePool{
+		client:            client,
+		mode:              mode,
+		concurrency:       config.getConcurrency(),
+		connectTimeout:    config.getConnectTimeout(),
+		requestTimeout:    config.getRequestTimeout(),
+		heightWaitTimeout: config.getHeightWaitTimeout(),
+		hashConcurrency:   config.getHashConcurrency(),
+		hashDeadline:      config.getHashDeadline(),
+		maxConnections:    config.MaxConnections,
+		conns:             make(map[string]*nodeConn),
+
+		defaultLocalAddress: config.LocalAddress,
+		localAddresses:      config.localAddresses(),
+	}
+}
+
+// parsePoolSecurity returns the connection security mode and the client key pair. Without a configured private key,
+// a random key pair is generated, so nodes that only accept known peers will reject the checker.
+func parsePoolSecurity(config PoolConfig) (packets.ConnectionSecurityMode, *crypto.KeyPair, error) {
+	var mode packets.ConnectionSecurityMode
+	switch config.SecurityMode {
+	case "", NoneSecurityMode:
+		mode = packets.NoneConnectionSecurity
+	case SignedSecurityMode:
+		mode = packets.SignedConnectionSecurity
+	default:
+		return 0, nil, fmt.Errorf("%w: unknown security mode %s", ErrInvalidPoolConfig, config.SecurityMode)
+	}
+
+	if config.MaxConnections < 0 {
+		return 0, nil, fmt.Errorf("%w: negative max connections", ErrInvalidPoolConfig)
+	}
+
+	if config.PingInterval != "" {
+		if interval, err := time.ParseDuration(config.PingInterval); err != nil || interval <= 0 {
+			return 0, nil, fmt.Errorf("%w: invalid ping interval %s", ErrInvalidPoolConfig, config.PingInterval)
+		}
+	}
+
+	if config.ClientPrivateKey == "" {
+		keyPair, err := crypto.NewRandomKeyPair()
+		if err != nil {
+			return 0, nil, fmt.Errorf("error generating random keypair: %s", err)
+		}
+		return mode, keyPair, nil
+	}
+
+	privateKey, err := crypto.NewPrivateKeyfromHexString(config.ClientPrivateKey)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: client private key: %v", ErrInvalidPoolConfig, err)
+	}
+
+	keyPair, err := crypto.NewKeyPair(privateKey, nil, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: client private key: %v", ErrInvalidPoolConfig, err)
+	}
+
+	return mode, keyPair, nil
+}
+
+func (io *nodeTcpIo) Write(p packets.Byter) (int, error) {
+	if err := io.conn.SetWriteDeadline(time.Now().Add(io.timeout)); err != nil {
+		return 0, err
+	}
+
+	return io.conn.Write(p.Bytes())
+}
+
+func (io *nodeTcpIo) Read(parser packets.Parser, expectedSize int) error {
+	if err := io.conn.SetReadDeadline(time.Now().Add(io.timeout)); err != nil {
+		return err
+	}
+
+	buf := make([]byte, expectedSize)
+	for offset := 0; offset < len(buf); {
+		n, err := io.conn.Read(buf[offset:])
+		if err != nil {
+			return err
+		}
+		offset += n
+	}
+
+	return parser.Parse(buf)
+}
+
+func (io *nodeTcpIo) Close() error {
+	return io.conn.Close()
+}
+
+func (p *NodePool) dial(info *health.NodeInfo) (*nodeConn, error) {
+	localAddr, err := p.localAddress(info.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := net.Dialer{Timeout: p.connectTimeout}
+	if localAddr != nil {
+		dialer.LocalAddr = localAddr
+	}
+
+	conn, err := dialer.Dial("tcp", info.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	handler := health.NewHandler(&nodeTcpIo{conn: conn, timeout: p.requestTimeout})
+	if err := handler.AuthHandle(p.client, info.IdentityKey, p.mode); err != nil {
+		handler.Close()
+		return nil, err
+	}
+
+	var remoteIP net.IP
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		remoteIP = addr.IP
+	}
+
+	return &nodeConn{info: info, handler: handler, hashes: p.hashes, remoteIP: remoteIP}, nil
+}
+
+func (c *nodeConn) chainInfo() (*health.ChainInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	req := packets.NewPacketHeader(packets.ChainInfoPacketType)
+	resp := &packets.ChainInfoResponse{}
+	if err := c.handler.CommonHandle(&req, resp); err != nil {
+		return nil, err
+	}
+	c.lastActive = time.Now()
+
+	return &health.ChainInfo{
+		Height:     resp.Height,
+		ChainScore: health.ChainScore{High: resp.ScoreHigh, Low: resp.ScoreLow},
+	}, nil
+}
+
+// blockHash returns the hash of the block at the height, from the hash cache if the node returned it recently.
+func (c *nodeConn) blockHash(height uint64) (sdk.Hash, error) {
+	if hash, exists := c.hashes.get(c.info.IdentityKey.String(), height, time.Now()); exists {
+		return hash, nil
+	}
+
+	ci, err := c.chainInfo()
+	if err != nil {
+		return sdk.Hash{}, err
+	}
+
+	if ci.Height < height {
+		return sdk.Hash{}, health.ErrNodeNotReachedHeight
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp := &packets.BlockHashesResponse{}
+	if err := c.handler.CommonHandle(packets.NewBlockHashesRequest(height, 1), resp); err != nil {
+		return sdk.Hash{}, err
+	}
+
+	if len(resp.Hashes) == 0 {
+		return sdk.Hash{}, health.ErrReturnedZeroHashes
+	}
+
+	c.hashes.put(c.info.IdentityKey.String(), height, resp.Hashes[0], time.Now())
+
+	return resp.Hashes[0], nil
+}
+
+func (c *nodeConn) nodeList() ([]*health.NodeInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	req := packets.NewPacketHeader(packets.NodeDiscoveryPullPeersPacketType)
+	resp := &packets.NodeDiscoveryPullPeersResponse{}
+	if err := c.handler.CommonHandle(&req, resp); err != nil {
+		return nil, err
+	}
+
+	nodes := make([]*health.NodeInfo, 0, len(resp.NetworkNodes))
+	for _, node := range resp.NetworkNodes {
+		if node.Host == "" || node.Port == 0 {
+			continue
+		}
+
+		nodes = append(nodes, &health.NodeInfo{
+			IdentityKey:  node.IdentityKey,
+			Endpoint:     net.JoinHostPort(node.Host, strconv.Itoa(int(node.Port))),
+			FriendlyName: node.FriendlyName,
+		})
+	}
+
+	return nodes, nil
+}
+
+// identity returns the version and network reported by the node along with the hash of its nemesis block.
+func (c *nodeConn) identity() (NodeIdentity, error) {
+	c.mu.Lock()
+	req := packets.NewPacketHeader(nodeDiscoveryPullPingPacketType)
+	resp := &packets.NodeDiscoveryPullPeersResponse{}
+	err := c.handler.CommonHandle(&req, resp)
+	c.mu.Unlock()
+
+	if err != nil {
+		return NodeIdentity{}, err
+	}
+
+	if len(resp.NetworkNodes) == 0 {
+		return NodeIdentity{}, errors.New("empty ping response")
+	}
+
+	nemesisHash, err := c.blockHash(1)
+	if err != nil {
+		return NodeIdentity{}, err
+	}
+
+	return NodeIdentity{
+		Version:           resp.NetworkNodes[0].Version,
+		NetworkIdentifier: resp.NetworkNodes[0].NetworkIdentifier,
+		NemesisHash:       nemesisHash,
+	}, nil
+}
+
+// probe measures the round trip of the connection and the throughput of pulling a batch of block hashes.
+func (c *nodeConn) probe(hashes int) (LinkQuality, error) {
+	var quality LinkQuality
+	var height uint64
+
+	for i := 0; i < linkRttSamples; i++ {
+		start := time.Now()
+		ci, err := c.chainInfo()
+		if err != nil {
+			return LinkQuality{}, err
+		}
+
+		if rtt := time.Since(start); i == 0 || rtt < quality.Rtt {
+			quality.Rtt = rtt
+		}
+		height = ci.Height
+	}
+
+	from := uint64(1)
+	if height > uint64(hashes) {
+		from = height - uint64(hashes) + 1
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	start := time.Now()
+	resp := &packets.BlockHashesResponse{}
+	if err := c.handler.CommonHandle(packets.NewBlockHashesRequest(from, uint32(hashes)), resp); err != nil {
+		return LinkQuality{}, err
+	}
+	elapsed := time.Since(start)
+
+	quality.Bytes = packets.PacketHeaderSize + len(resp.Hashes)*packets.HashSize
+	quality.Throughput = float64(quality.Bytes) / elapsed.Seconds()
+
+	return quality, nil
+}
+
+// idleSince reports whether the connection has not answered a request since the given time.
+func (c *nodeConn) idleSince(t time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastActive.Before(t)
+}
+
+func (c *nodeConn) close() {
+	c.handler.Close()
+}
+
+// forEach calls fn for every index with at most p.concurrency calls running at once.
+func (p *NodePool) forEach(count int, fn func(i int)) {
+	forEachLimited(p.concurrency, count, fn)
+}
+
+// forEachLimited calls fn for every index with at most limit calls running at once.
+func forEachLimited(limit, count int, fn func(i int)) {
+	sem := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (p *NodePool) connections() []*nodeConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conns := make([]*nodeConn, 0, len(p.conns))
+	for _, conn := range p.conns {
+		conns = append(conns, conn)
+	}
+
+	return conns
+}
+
+// drop closes a connection that failed, so that the next ConnectToNodes dials the node again.
+func (p *NodePool) drop(conn *nodeConn, err error) {
+	log.Printf("Dropping connection to %s: %v", conn.info.Endpoint, err)
+
+	p.mu.Lock()
+	if p.conns[conn.info.IdentityKey.String()] == conn {
+		delete(p.conns, conn.info.IdentityKey.String())
+	}
+	p.mu.Unlock()
+
+	conn.close()
+}
+
+// reset closes every connection, so that requests blocked on a node fail and the next ConnectToNodes dials the
+// nodes again.
+func (p *NodePool) reset() {
+	p.mu.Lock()
+	conns := p.conns
+	p.conns = make(map[string]*nodeConn)
+	p.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.close()
+	}
+}
+
+// dedicated returns an empty pool with the same settings, for long requests that must neither hold up the
+// connections of the check cycles nor be closed when those reconnect.
+func (p *NodePool) dedicated() *NodePool {
+	return &NodePool{
+		client:            p.client,
+		mode:              p.mode,
+		concurrency:       p.concurrency,
+		connectTimeout:    p.connectTimeout,
+		requestTimeout:    p.requestTimeout,
+		heightWaitTimeout: p.heightWaitTimeout,
+		hashConcurrency:   p.hashConcurrency,
+		hashDeadline:      p.hashDeadline,
+		conns:             make(map[string]*nodeConn),
+
+		defaultLocalAddress: p.defaultLocalAddress,
+		localAddresses:      p.localAddresses,
+	}
+}
+
+// connect reuses the existing connection to the node if it still responds, or dials a new one.
+func (p *NodePool) connect(info *health.NodeInfo) (*nodeConn, error) {
+	p.mu.Lock()
+	conn, exists := p.conns[info.IdentityKey.String()]
+	p.mu.Unlock()
+
+	if exists {
+		if _, err := conn.chainInfo(); err == nil {
+			return conn, nil
+		}
+
+		conn.close()
+	}
+
+	return p.dial(info)
+}
+
+// ConnectToNodes connects to the nodes and, if discover is set, to the peers they report, breadth first.
+// The given nodes are always connected; discovered peers only if the discovery filter allows them and while there
+// are fewer than maxConnections.
+// It returns the nodes that could not be connected, keyed by identity key.
+func (p *NodePool) ConnectToNodes(nodeInfos []*health.NodeInfo, discover bool) (map[string]*health.NodeInfo, error) {
+	failed := make(map[string]*health.NodeInfo)
+	connected := make(map[string]*nodeConn)
+	handled := make(map[string]struct{})
+
+	var mu sync.Mutex
+
+	queue := make([]*health.NodeInfo, 0, len(nodeInfos))
+	for _, info := range nodeInfos {
+		if _, exists := handled[info.IdentityKey.String()]; !exists {
+			handled[info.IdentityKey.String()] = struct{}{}
+			queue = append(queue, info)
+		}
+	}
+
+	// Every node after the first round was discovered.
+	discoveredConnected := 0
+
+	// full reports whether a discovered peer would exceed the connection or discovered node limit; mu must be held.
+	full := func() bool {
+		if limit := p.discovery.limit(); limit > 0 && discoveredConnected >= limit {
+			return true
+		}
+		return p.maxConnections > 0 && len(connected) >= p.maxConnections
+	}
+
+	for round := 0; len(queue) > 0; round++ {
+		var next []*health.NodeInfo
+
+		p.forEach(len(queue), func(i int) {
+			info := queue[i]
+
+			mu.Lock()
+			skip := round > 0 && full()
+			mu.Unlock()
+			if skip {
+				return
+			}
+
+			conn, err := p.connect(info)
+			if err != nil {
+				log.Printf("Error connecting to %s: %s", info.Endpoint, err)
+
+				mu.Lock()
+				failed[info.IdentityKey.String()] = info
+				mu.Unlock()
+				return
+			}
+
+			var discovered []*health.NodeInfo
+			if discover {
+				discovered, err = conn.nodeList()
+				if err != nil {
+					log.Printf("Error getting list of nodes from %s: %s", info.Endpoint, err)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if round > 0 {
+				if full() {
+					conn.close()
+					return
+				}
+				discoveredConnected++
+			}
+
+			connected[info.IdentityKey.String()] = conn
+			for _, node := range discovered {
+				if _, exists := handled[node.IdentityKey.String()]; !exists {
+					handled[node.IdentityKey.String()] = struct{}{}
+					if p.discovery.allows(node) {
+						next = append(next, node)
+					}
+				}
+			}
+		})
+
+		queue = next
+	}
+
+	configured := make(map[string]struct{}, len(nodeInfos))
+	for _, info := range nodeInfos {
+		configured[info.IdentityKey.String()] = struct{}{}
+	}
+
+	p.mu.Lock()
+	for key, conn := range p.conns {
+		if connected[key] != conn {
+			conn.close()
+		}
+	}
+	p.conns = connected
+	p.configured = configured
+	p.mu.Unlock()
+
+	log.Printf("Connected to %d nodes, %d failed", len(connected), len(failed))
+
+	if len(connected) == 0 {
+		return nil, health.ErrCannotConnect
+	}
+
+	return failed, nil
+}
+
+// discoveredNodes returns the connected nodes that are not among the given configured ones.
+func (p *NodePool) discoveredNodes(nodeInfos []*health.NodeInfo) []*health.NodeInfo {
+	configured := make(map[string]struct{}, len(nodeInfos))
+	for _, info := range nodeInfos {
+		configured[info.IdentityKey.String()] = struct{}{}
+	}
+
+	var nodes []*health.NodeInfo
+	for _, conn := range p.connections() {
+		if _, exists := configured[conn.info.IdentityKey.String()]; !exists {
+			nodes = append(nodes, conn.info)
+		}
+	}
+
+	return nodes
+}
+
+// syncConnections returns the connections whose sync status is checked: all of them, or only the configured nodes
+// if discovered nodes are sampled for the hash checks.
+func (p *NodePool) syncConnections() []*nodeConn {
+	if p.discovery.sampleSize() == 0 {
+		return p.connections()
+	}
+
+	configured, _ := p.splitConnections()
+	return configured
+}
+
+// hashConnections returns the connections whose block hashes are compared: all of them, or the configured nodes and
+// a random sample of the discovered ones, drawn anew in every cycle.
+func (p *NodePool) hashConnections() []*nodeConn {
+	size := p.discovery.sampleSize()
+	if size == 0 {
+		return p.connections()
+	}
+
+	configured, discovered := p.splitConnections()
+	rand.Shuffle(len(discovered), func(i, j int) {
+		discovered[i], discovered[j] = discovered[j], discovered[i]
+	})
+	if len(discovered) > size {
+		discovered = discovered[:size]
+	}
+
+	return append(configured, discovered...)
+}
+
+func (p *NodePool) splitConnections() (configured, discovered []*nodeConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, conn := range p.conns {
+		if _, exists := p.configured[key]; exists {
+			configured = append(configured, conn)
+		} else {
+			discovered = append(discovered, conn)
+		}
+	}
+
+	return configured, discovered
+}
+
+// WaitHeight polls the connected nodes until all of them reach the height or the height wait timeout expires.
+// Nodes that fail to respond are dropped and reported as not reached with their last known height, or as failed,
+// keyed by identity key, if they never reported one. Discovered nodes that are only sampled for the hash checks are
+// not polled.
+func (p *NodePool) WaitHeight(height uint64) (notReached, reached map[health.NodeInfo]uint64, failed map[string]*health.NodeInfo, err error) {
+	pending := p.syncConnections()
+	if len(pending) == 0 {
+		return nil, nil, nil, health.ErrNoConnectedPeers
+	}
+
+	log.Printf("Waiting for %d nodes to reach the height %d", len(pending), height)
+
+	notReached = make(map[health.NodeInfo]uint64)
+	reached = make(map[health.NodeInfo]uint64)
+	failed = make(map[string]*health.NodeInfo)
+	lastHeights := make(map[*nodeConn]uint64, len(pending))
+	deadline := time.Now().Add(p.heightWaitTimeout)
+
+	for {
+		results := make([]heightResult, len(pending))
+		p.forEach(len(pending), func(i int) {
+			ci, err := pending[i].chainInfo()
+			if err != nil {
+				results[i] = heightResult{err: err}
+				return
+			}
+			results[i] = heightResult{height: ci.Height}
+		})
+
+		var stillPending []*nodeConn
+		for i, conn := range pending {
+			result := results[i]
+			switch {
+			case result.err != nil:
+				p.drop(conn, result.err)
+				if lastHeight, exists := lastHeights[conn]; exists {
+					notReached[*conn.info] = lastHeight
+				} else {
+					failed[conn.info.IdentityKey.String()] = conn.info
+				}
+			case result.height >= height:
+				reached[*conn.info] = result.height
+			default:
+				lastHeights[conn] = result.height
+				stillPending = append(stillPending, conn)
+			}
+		}
+		pending = stillPending
+
+		remaining := time.Until(deadline)
+		if len(pending) == 0 || remaining <= 0 {
+			break
+		}
+
+		if remaining > heightPollInterval {
+			remaining = heightPollInterval
+		}
+		time.Sleep(remaining)
+	}
+
+	for _, conn := range pending {
+		log.Printf("Node %s has not reached the height %d, current: %d", conn.info.Endpoint, height, lastHeights[conn])
+		notReached[*conn.info] = lastHeights[conn]
+	}
+
+	return notReached, reached, failed, nil
+}
+
+// CompareHashes collects the block hash at the height from every hash-checked node that has reached it, with at most
+// hashConcurrency requests at once. The results are compared as they arrive, and onFork is called with the hashes so
+// far and the nodes yet to answer as soon as two differ, so a fork is not alerted only once the slowest node answers.
+// Nodes that failed or have not answered by the hash deadline are returned as no data.
+// It returns health.ErrHashesAreNotTheSame along with the hashes if they differ, and ErrNoBlockHashes if no node
+// answered, so that an unverified checkpoint is not taken as agreed.
+func (p *NodePool) CompareHashes(height uint64, onFork func(hashes map[string]sdk.Hash, pending []*health.NodeInfo)) (map[string]sdk.Hash, []*health.NodeInfo, error) {
+	conns := p.hashConnections()
+	if len(conns) == 0 {
+		return nil, nil, health.ErrNoConnectedPeers
+	}
+
+	// Buffered, so the workers still running after the deadline never block.
+	results := make(chan hashResult, len(conns))
+	go forEachLimited(p.hashConcurrency, len(conns), func(i int) {
+		hash, err := conns[i].blockHash(height)
+		results <- hashResult{conn: conns[i], hash: hash, err: err}
+	})
+
+	deadline := time.NewTimer(p.hashDeadline)
+	defer deadline.Stop()
+
+	pending := make(map[*nodeConn]struct{}, len(conns))
+	for _, conn := range conns {
+		pending[conn] = struct{}{}
+	}
+
+	hashes := make(map[string]sdk.Hash, len(conns))
+	var failed []*health.NodeInfo
+	var first *sdk.Hash
+	forked := false
+
+collect:
+	for len(pending) > 0 {
+		select {
+		case result := <-results:
+			delete(pending, result.conn)
+
+			if errors.Is(result.err, health.ErrNodeNotReachedHeight) {
+				log.Printf("Skip getting block hash from %s: %s", result.conn.info.Endpoint, result.err)
+				continue
+			}
+
+			if result.err != nil {
+				p.drop(result.conn, result.err)
+				failed = append(failed, result.conn.info)
+				continue
+			}
+
+			hashes[result.conn.info.Endpoint] = result.hash
+
+			if first == nil {
+				first = &result.hash
+			} else if !forked && result.hash != *first {
+				forked = true
+				if onFork != nil {
+					onFork(copyHashes(hashes), pendingNodes(pending))
+				}
+			}
+		case <-deadline.C:
+			break collect
+		}
+	}
+
+	noData := pendingNodes(pending)
+	for _, info := range noData {
+		log.Printf("No block hash from %s within %s", info.Endpoint, p.hashDeadline)
+	}
+
+	noData = append(noData, failed...)
+	sort.Slice(noData, func(i, j int) bool {
+		return noData[i].Endpoint < noData[j].Endpoint
+	})
+
+	if p.hashes != nil {
+		hits, misses := p.hashes.stats()
+		log.Printf("Hash cache: %d hits, %d misses", hits, misses)
+	}
+
+	if forked {
+		return hashes, noData, health.ErrHashesAreNotTheSame
+	}
+
+	if len(hashes) == 0 {
+		return hashes, noData, ErrNoBlockHashes
+	}
+
+	return hashes, noData, nil
+}
+
+func copyHashes(hashes map[string]sdk.Hash) map[string]sdk.Hash {
+	result := make(map[string]sdk.Hash, len(hashes))
+	for endpoint, hash := range hashes {
+		result[endpoint] = hash
+	}
+	return result
+}
+
+func pendingNodes(pending map[*nodeConn]struct{}) []*health.NodeInfo {
+	nodes := make([]*health.NodeInfo, 0, len(pending))
+	for conn := range pending {
+		nodes = append(nodes, conn.info)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Endpoint < nodes[j].Endpoint
+	})
+
+	return nodes
+}
+
+// Identities queries the identity of every connected node, keyed by identity key. Nodes that fail to answer are
+// dropped and left out.
+func (p *NodePool) Identities() map[string]NodeIdentity {
+	conns := p.connections()
+	identities := make(map[string]NodeIdentity, len(conns))
+	var mu sync.Mutex
+
+	p.forEach(len(conns), func(i int) {
+		conn := conns[i]
+
+		identity, err := conn.identity()
+		if err != nil {
+			p.drop(conn, err)
+			return
+		}
+
+		mu.Lock()
+		identities[conn.info.IdentityKey.String()] = identity
+		mu.Unlock()
+	})
+
+	return identities
+}
+
+// ProbeLinks measures the connection quality of every connected node, keyed by identity key. Nodes that fail to
+// answer are dropped and left out.
+func (p *NodePool) ProbeLinks(hashes int) map[string]LinkQuality {
+	conns := p.connections()
+	probes := make(map[string]LinkQuality, len(conns))
+	var mu sync.Mutex
+
+	p.forEach(len(conns), func(i int) {
+		conn := conns[i]
+
+		quality, err := conn.probe(hashes)
+		if err != nil {
+			p.drop(conn, err)
+			return
+		}
+
+		mu.Lock()
+		probes[conn.info.IdentityKey.String()] = quality
+		mu.Unlock()
+	})
+
+	return probes
+}
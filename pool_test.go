@@ -0,0 +1,411 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health/packets"
+	crypto "github.com/proximax-storage/go-xpx-crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testNode is a minimal catapult node speaking the packets used by the pool.
+type testNode struct {
+	keyPair  *crypto.KeyPair
+	listener net.Listener
+	height   atomic.Uint64
+	delay    atomic.Int64
+	hash     sdk.Hash
+	nemesis  sdk.Hash
+	version  uint32
+	peers    []*testNode
+	// failHashes closes the connection on a block hashes request, while the height is still served.
+	failHashes atomic.Bool
+}
+
+func newTestNode(t *testing.T, height uint64, hash sdk.Hash) *testNode {
+	keyPair, err := crypto.NewRandomKeyPair()
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	node := &testNode{keyPair: keyPair, listener: listener, hash: hash}
+	node.height.Store(height)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go node.serve(conn)
+		}
+	}()
+
+	return node
+}
+
+func (n *testNode) info() *health.NodeInfo {
+	return &health.NodeInfo{IdentityKey: n.keyPair.PublicKey, Endpoint: n.listener.Addr().String()}
+}
+
+func testPacketHeader(size int, packetType packets.PacketType) []byte {
+	buf := make([]byte, packets.PacketHeaderSize)
+	binary.LittleEndian.PutUint32(buf[:4], uint32(size))
+	binary.LittleEndian.PutUint32(buf[4:], uint32(packetType))
+	return buf
+}
+
+func (n *testNode) serve(conn net.Conn) {
+	defer conn.Close()
+
+	challenge := packets.GenerateServerChallengeRequest()
+	if _, err := conn.Write(append(testPacketHeader(packets.ServerChallengeRequestSize, packets.ServerChallengePacketType), challenge.Challenge[:]...)); err != nil {
+		return
+	}
+
+	buf := make([]byte, packets.ServerChallengeResponseSize)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return
+	}
+
+	clientResp := &packets.ServerChallengeResponse{}
+	if err := clientResp.Parse(buf[packets.PacketHeaderSize:]); err != nil {
+		return
+	}
+
+	resp, err := packets.GenerateClientChallengeResponse(clientResp, n.keyPair)
+	if err != nil {
+		return
+	}
+
+	if _, err := conn.Write(append(testPacketHeader(packets.ClientChallengeResponseSize, packets.ClientChallengePacketType), resp.Signature.Bytes()...)); err != nil {
+		return
+	}
+
+	for {
+		header := make([]byte, packets.PacketHeaderSize)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+
+		time.Sleep(time.Duration(n.delay.Load()))
+
+		var reply []byte
+		switch packetType := packets.PacketType(binary.LittleEndian.Uint32(header[4:])); packetType {
+		case packets.ChainInfoPacketType:
+			reply = testPacketHeader(packets.ChainInfoResponseSize, packetType)
+			reply = binary.LittleEndian.AppendUint64(reply, n.height.Load())
+			reply = append(reply, make([]byte, 16)...)
+		case packets.BlockHashesPacketType:
+			if n.failHashes.Load() {
+				return
+			}
+
+			req := make([]byte, packets.BlockHashesRequestSize-packets.PacketHeaderSize)
+			if _, err := io.ReadFull(conn, req); err != nil {
+				return
+			}
+
+			from, count := binary.LittleEndian.Uint64(req[:8]), int(binary.LittleEndian.Uint32(req[8:]))
+			reply = testPacketHeader(packets.PacketHeaderSize+count*packets.HashSize, packetType)
+			for i := 0; i < count; i++ {
+				hash := n.hash
+				if from+uint64(i) == 1 {
+					hash = n.nemesis
+				}
+				reply = append(reply, hash[:]...)
+			}
+		case nodeDiscoveryPullPingPacketType:
+			node := n.networkNode("", 7900)
+			reply = append(testPacketHeader(packets.PacketHeaderSize+len(node), packetType), node...)
+		case packets.NodeDiscoveryPullPeersPacketType:
+			var nodes []byte
+			for _, peer := range n.peers {
+				addr := peer.listener.Addr().(*net.TCPAddr)
+				nodes = append(nodes, peer.networkNode(addr.IP.String(), uint16(addr.Port))...)
+			}
+			reply = append(testPacketHeader(packets.PacketHeaderSize+len(nodes), packetType), nodes...)
+		default:
+			return
+		}
+
+		if _, err := conn.Write(reply); err != nil {
+			return
+		}
+	}
+}
+
+// networkNode encodes the node's network node information, without a friendly name.
+func (n *testNode) networkNode(host string, port uint16) []byte {
+	size := 4 + packets.PublicKeySize + 2 + 2 + 1 + 4 + 4 + 1 + 1 + len(host)
+
+	buf := binary.LittleEndian.AppendUint32(nil, uint32(size))
+	buf = append(buf, n.keyPair.PublicKey.Raw...)
+	buf = binary.LittleEndian.AppendUint16(buf, port)
+	buf = binary.LittleEndian.AppendUint16(buf, 7910)
+	buf = append(buf, 0xb8)
+	buf = binary.LittleEndian.AppendUint32(buf, n.version)
+	buf = binary.LittleEndian.AppendUint32(buf, 0)
+	buf = append(buf, byte(len(host)), 0)
+	return append(buf, host...)
+}
+
+func newTestNodePool(t *testing.T) *NodePool {
+	keyPair, err := crypto.NewRandomKeyPair()
+	require.NoError(t, err)
+
+	return NewNodePool(keyPair, packets.NoneConnectionSecurity, PoolConfig{
+		Concurrency:       4,
+		ConnectTimeout:    "1s",
+		RequestTimeout:    "200ms",
+		HeightWaitTimeout: "1s",
+	})
+}
+
+func TestNodePool(t *testing.T) {
+	t.Run("Connect", func(t *testing.T) {
+		nodeA := newTestNode(t, 10, sdk.Hash{1})
+		nodeB := newTestNode(t, 10, sdk.Hash{1})
+
+		// Nothing listens on the port of a closed node.
+		closed := newTestNode(t, 10, sdk.Hash{1})
+		closed.listener.Close()
+
+		pool := newTestNodePool(t)
+		failed, err := pool.ConnectToNodes([]*health.NodeInfo{nodeA.info(), nodeB.info(), closed.info()}, true)
+		require.NoError(t, err)
+		assert.Len(t, failed, 1)
+		assert.Contains(t, failed, closed.keyPair.PublicKey.String())
+		assert.Len(t, pool.connections(), 2)
+
+		_, err = newTestNodePool(t).ConnectToNodes([]*health.NodeInfo{closed.info()}, false)
+		require.ErrorIs(t, err, health.ErrCannotConnect)
+	})
+
+	t.Run("Slow node does not delay the rest", func(t *testing.T) {
+		var nodes []*health.NodeInfo
+		for i := 0; i < 8; i++ {
+			nodes = append(nodes, newTestNode(t, 12, sdk.Hash{1}).info())
+		}
+
+		slow := newTestNode(t, 12, sdk.Hash{1})
+		nodes = append(nodes, slow.info())
+
+		pool := newTestNodePool(t)
+		_, err := pool.ConnectToNodes(nodes, false)
+		require.NoError(t, err)
+
+		slow.delay.Store(int64(10 * time.Second))
+
+		start := time.Now()
+		notReached, reached, failed, err := pool.WaitHeight(10)
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), 2*time.Second)
+		assert.Len(t, reached, 8)
+		assert.Empty(t, notReached)
+
+		// The slow node never reported a height, so it has failed rather than fallen behind.
+		require.Len(t, failed, 1)
+		assert.Contains(t, failed, slow.keyPair.PublicKey.String())
+
+		// The slow node has been dropped, so comparing hashes does not wait for it either.
+		start = time.Now()
+		hashes, _, err := pool.CompareHashes(10, nil)
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Len(t, hashes, 8)
+	})
+
+	t.Run("Node behind", func(t *testing.T) {
+		ahead := newTestNode(t, 12, sdk.Hash{1})
+		behind := newTestNode(t, 8, sdk.Hash{1})
+
+		pool := newTestNodePool(t)
+		_, err := pool.ConnectToNodes([]*health.NodeInfo{ahead.info(), behind.info()}, false)
+		require.NoError(t, err)
+
+		notReached, reached, failed, err := pool.WaitHeight(10)
+		require.NoError(t, err)
+		assert.Equal(t, map[health.NodeInfo]uint64{*ahead.info(): 12}, reached)
+		assert.Equal(t, map[health.NodeInfo]uint64{*behind.info(): 8}, notReached)
+		assert.Empty(t, failed)
+
+		// A node that has not reached the height is skipped rather than dropped.
+		hashes, _, err := pool.CompareHashes(10, nil)
+		require.NoError(t, err)
+		assert.Len(t, hashes, 1)
+		assert.Len(t, pool.connections(), 2)
+	})
+
+	t.Run("Fork", func(t *testing.T) {
+		nodeA := newTestNode(t, 10, sdk.Hash{1})
+		nodeB := newTestNode(t, 10, sdk.Hash{2})
+
+		pool := newTestNodePool(t)
+		_, err := pool.ConnectToNodes([]*health.NodeInfo{nodeA.info(), nodeB.info()}, false)
+		require.NoError(t, err)
+
+		hashes, _, err := pool.CompareHashes(10, nil)
+		require.ErrorIs(t, err, health.ErrHashesAreNotTheSame)
+		assert.Equal(t, sdk.Hash{1}, hashes[nodeA.info().Endpoint])
+		assert.Equal(t, sdk.Hash{2}, hashes[nodeB.info().Endpoint])
+	})
+
+	t.Run("Fork reported before the slowest node", func(t *testing.T) {
+		nodeA := newTestNode(t, 10, sdk.Hash{1})
+		nodeB := newTestNode(t, 10, sdk.Hash{2})
+		slow := newTestNode(t, 10, sdk.Hash{1})
+
+		pool := newTestNodePool(t)
+		_, err := pool.ConnectToNodes([]*health.NodeInfo{nodeA.info(), nodeB.info(), slow.info()}, false)
+		require.NoError(t, err)
+
+		slow.delay.Store(int64(150 * time.Millisecond))
+		pool.hashDeadline = 100 * time.Millisecond
+
+		var forkHashes map[string]sdk.Hash
+		var forkPending []*health.NodeInfo
+		hashes, noData, err := pool.CompareHashes(10, func(hashes map[string]sdk.Hash, pending []*health.NodeInfo) {
+			forkHashes, forkPending = hashes, pending
+		})
+		require.ErrorIs(t, err, health.ErrHashesAreNotTheSame)
+		assert.Len(t, forkHashes, 2)
+		require.Len(t, forkPending, 1)
+		assert.Equal(t, slow.info().Endpoint, forkPending[0].Endpoint)
+
+		// The slow node misses the deadline and is reported as no data rather than dropped.
+		assert.Len(t, hashes, 2)
+		require.Len(t, noData, 1)
+		assert.Equal(t, slow.info().Endpoint, noData[0].Endpoint)
+	})
+
+	t.Run("Failed nodes", func(t *testing.T) {
+		nodeA := newTestNode(t, 10, sdk.Hash{1})
+		nodeB := newTestNode(t, 10, sdk.Hash{1})
+
+		pool := newTestNodePool(t)
+		_, err := pool.ConnectToNodes([]*health.NodeInfo{nodeA.info(), nodeB.info()}, false)
+		require.NoError(t, err)
+
+		// A node that fails is reported as no data.
+		nodeB.delay.Store(int64(time.Second))
+		hashes, noData, err := pool.CompareHashes(10, nil)
+		require.NoError(t, err)
+		assert.Len(t, hashes, 1)
+		require.Len(t, noData, 1)
+		assert.Equal(t, nodeB.info().Endpoint, noData[0].Endpoint)
+
+		// Without a single hash the height is not verified.
+		_, err = pool.ConnectToNodes([]*health.NodeInfo{nodeA.info(), nodeB.info()}, false)
+		require.NoError(t, err)
+
+		nodeA.delay.Store(int64(time.Second))
+		hashes, noData, err = pool.CompareHashes(10, nil)
+		require.ErrorIs(t, err, ErrNoBlockHashes)
+		assert.Empty(t, hashes)
+		assert.Len(t, noData, 2)
+	})
+
+	t.Run("Max connections", func(t *testing.T) {
+		nodeA := newTestNode(t, 10, sdk.Hash{1})
+		nodeB := newTestNode(t, 10, sdk.Hash{1})
+		for i := 0; i < 4; i++ {
+			nodeA.peers = append(nodeA.peers, newTestNode(t, 10, sdk.Hash{1}))
+		}
+
+		pool := newTestNodePool(t)
+		_, err := pool.ConnectToNodes([]*health.NodeInfo{nodeA.info(), nodeB.info()}, true)
+		require.NoError(t, err)
+		assert.Len(t, pool.connections(), 6)
+
+		pool.maxConnections = 3
+		_, err = pool.ConnectToNodes([]*health.NodeInfo{nodeA.info(), nodeB.info()}, true)
+		require.NoError(t, err)
+		assert.Len(t, pool.connections(), 3)
+
+		// The configured nodes are connected even beyond the limit.
+		pool.maxConnections = 1
+		_, err = pool.ConnectToNodes([]*health.NodeInfo{nodeA.info(), nodeB.info()}, true)
+		require.NoError(t, err)
+		assert.Len(t, pool.connections(), 2)
+	})
+
+	t.Run("Hash sample of discovered nodes", func(t *testing.T) {
+		nodeA := newTestNode(t, 10, sdk.Hash{1})
+		for i := 0; i < 4; i++ {
+			nodeA.peers = append(nodeA.peers, newTestNode(t, 10, sdk.Hash{1}))
+		}
+
+		// A lagging peer is never reported as out of sync.
+		nodeA.peers[0].height.Store(5)
+		nodeA.peers[1].hash = sdk.Hash{2}
+
+		pool := newTestNodePool(t)
+		pool.discovery = &DiscoveryFilter{hashSampleSize: 2}
+		_, err := pool.ConnectToNodes([]*health.NodeInfo{nodeA.info()}, true)
+		require.NoError(t, err)
+		assert.Len(t, pool.connections(), 5)
+
+		notReached, reached, _, err := pool.WaitHeight(10)
+		require.NoError(t, err)
+		assert.Empty(t, notReached)
+		assert.Equal(t, map[health.NodeInfo]uint64{*nodeA.info(): 10}, reached)
+
+		hashConns := pool.hashConnections()
+		require.Len(t, hashConns, 3)
+		assert.Equal(t, nodeA.info().Endpoint, hashConns[0].info.Endpoint)
+
+		// A forked peer is found once it is sampled.
+		forked := false
+		for i := 0; i < 50 && !forked; i++ {
+			_, _, err := pool.CompareHashes(10, nil)
+			forked = errors.Is(err, health.ErrHashesAreNotTheSame)
+		}
+		assert.True(t, forked)
+	})
+
+	t.Run("No connections", func(t *testing.T) {
+		_, _, _, err := newTestNodePool(t).WaitHeight(10)
+		require.ErrorIs(t, err, health.ErrNoConnectedPeers)
+	})
+}
+
+func TestParsePoolSecurity(t *testing.T) {
+	mode, keyPair, err := parsePoolSecurity(PoolConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, packets.NoneConnectionSecurity, mode)
+	assert.NotNil(t, keyPair)
+
+	privateKey := "A31411C7B6B5D5B7BAE5E0FA4F0A2A9D2F2D9C87E2F4A3B4D1E0F9A8B7C6D5E4"
+	mode, keyPair, err = parsePoolSecurity(PoolConfig{SecurityMode: SignedSecurityMode, ClientPrivateKey: privateKey})
+	require.NoError(t, err)
+	assert.Equal(t, packets.SignedConnectionSecurity, mode)
+
+	// The same key is used on every run.
+	_, other, err := parsePoolSecurity(PoolConfig{ClientPrivateKey: privateKey})
+	require.NoError(t, err)
+	assert.Equal(t, keyPair.PublicKey.String(), other.PublicKey.String())
+
+	_, _, err = parsePoolSecurity(PoolConfig{SecurityMode: "tls"})
+	require.ErrorIs(t, err, ErrInvalidPoolConfig)
+
+	_, _, err = parsePoolSecurity(PoolConfig{ClientPrivateKey: "abcd"})
+	require.ErrorIs(t, err, ErrInvalidPoolConfig)
+
+	_, _, err = parsePoolSecurity(PoolConfig{MaxConnections: -1})
+	require.ErrorIs(t, err, ErrInvalidPoolConfig)
+
+	_, _, err = parsePoolSecurity(PoolConfig{PingInterval: "-1s"})
+	require.ErrorIs(t, err, ErrInvalidPoolConfig)
+}
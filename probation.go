@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"go-xpx-check-fork-util/internal/table"
+)
+
+type (
+	// Probation keeps the nodes added to the config since the last run out of the critical thresholds for a while,
+	// as a node syncing from scratch would otherwise raise sync alerts until it caught up. A node is new if it is
+	// missing from the known nodes saved with the state, so probation needs stateConfig.
+	Probation struct {
+		period time.Duration
+		// since holds the time each node in probation was first seen, keyed by identity key.
+		since map[string]time.Time
+	}
+
+	// ProbationAlert reports, for information only, the issues of the nodes in probation.
+	ProbationAlert struct {
+		Height    uint64
+		Offline   []*health.NodeInfo
+		OutOfSync map[health.NodeInfo]uint64
+	}
+)
+
+// NewProbation returns nil if no probation period is configured.
+func NewProbation(period time.Duration) *Probation {
+	if period <= 0 {
+		return nil
+	}
+
+	return &Probation{period: period, since: make(map[string]time.Time)}
+}
+
+func (a *AlertConfig) getProbationPeriod() time.Duration {
+	return parseOptionalDuration(a.ProbationPeriod, "probation period", 0)
+}
+
+// restore puts the configured nodes missing from the saved known nodes in probation from now. Without known nodes,
+// e.g. in a state saved before probation was enabled, every node is considered established.
+func (p *Probation) restore(known []string, since map[string]time.Time, nodeInfos []*health.NodeInfo, now time.Time) {
+	if p == nil || known == nil {
+		return
+	}
+
+	saved := make(map[string]struct{}, len(known))
+	for _, key := range known {
+		saved[key] = struct{}{}
+	}
+
+	for _, info := range nodeInfos {
+		key := info.IdentityKey.String()
+		if t, exists := since[key]; exists {
+			p.since[key] = t
+		} else if _, exists := saved[key]; !exists {
+			p.since[key] = now
+		}
+	}
+}
+
+// export returns the configured nodes and the ones still in probation, to be saved with the state.
+func (p *Probation) export(nodeInfos []*health.NodeInfo, now time.Time) ([]string, map[string]time.Time) {
+	if p == nil {
+		return nil, nil
+	}
+
+	known := make([]string, 0, len(nodeInfos))
+	var since map[string]time.Time
+	for _, info := range nodeInfos {
+		key := info.IdentityKey.String()
+		known = append(known, key)
+
+		if p.inProbation(*info, now) {
+			if since == nil {
+				since = make(map[string]time.Time)
+			}
+			since[key] = p.since[key]
+		}
+	}
+
+	sort.Strings(known)
+	return known, since
+}
+
+func (p *Probation) inProbation(node health.NodeInfo, now time.Time) bool {
+	if p == nil {
+		return false
+	}
+
+	since, exists := p.since[node.IdentityKey.String()]
+	return exists && now.Sub(since) < p.period
+}
+
+// thresholdNodes is the number of configured nodes the percentage thresholds are taken of, without the nodes in
+// probation, which would otherwise still raise the threshold they do not count toward.
+func (am *AlertManager) thresholdNodes() int {
+	now := time.Now()
+	total := 0
+	for _, info := range am.nodeInfos {
+		if !am.probation.inProbation(*info, now) {
+			total++
+		}
+	}
+
+	return total
+}
+
+// withoutProbationNodes splits off the out-of-sync nodes in probation, which do not count toward the thresholds.
+func (am *AlertManager) withoutProbationNodes(nodes map[health.NodeInfo]uint64) (filtered, probation map[health.NodeInfo]uint64) {
+	now := time.Now()
+	filtered = make(map[health.NodeInfo]uint64, len(nodes))
+	probation = make(map[health.NodeInfo]uint64)
+	for node, height := range nodes {
+		if am.probation.inProbation(node, now) {
+			probation[node] = height
+		} else {
+			filtered[node] = height
+		}
+	}
+
+	return filtered, probation
+}
+
+// withoutProbationOfflineNodes splits off the offline nodes in probation.
+func (am *AlertManager) withoutProbationOfflineNodes(nodes map[string]*health.NodeInfo) (filtered map[string]*health.NodeInfo, probation []*health.NodeInfo) {
+	now := time.Now()
+	filtered = make(map[string]*health.NodeInfo, len(nodes))
+	for key, node := range nodes {
+		if am.probation.inProbation(*node, now) {
+			probation = append(probation, node)
+		} else {
+			filtered[key] = node
+		}
+	}
+
+	return filtered, probation
+}
+
+// sendProbationAlert reports the issues of the nodes in probation, at most once per sync alert repeat interval.
+// It is never paged.
+func (am *AlertManager) sendProbationAlert(alert ProbationAlert) {
+	// Like the sync alert, only nodes at least outOfSyncBlocksThreshold blocks behind are reported.
+	for node, height := range alert.OutOfSync {
+		if int(alert.Height-height) < am.config.OutOfSyncBlocksThreshold {
+			delete(alert.OutOfSync, node)
+		}
+	}
+
+	if len(alert.Offline) == 0 && len(alert.OutOfSync) == 0 {
+		return
+	}
+
+	if time.Since(am.lastAlertTimes[ProbationAlertType]) <= am.config.getSyncAlertRepeatInterval() {
+		return
+	}
+
+	am.sendToTelegram(alert)
+}
+
+func (a ProbationAlert) getType() AlertType {
+	return ProbationAlertType
+}
+
+func (a ProbationAlert) createMessage() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "<b>ℹ️ Nodes in probation </b>\n\n")
+	fmt.Fprintf(&buf, "These nodes were added recently and do not count toward the alert thresholds until their probation ends.")
+
+	if len(a.Offline) > 0 {
+		names := make([][]string, 0, len(a.Offline))
+		for _, node := range a.Offline {
+			names = append(names, []string{nodeName(*node)})
+		}
+
+		fmt.Fprintf(&buf, "\n\nOffline (%d):<pre>%s</pre>", len(names), table.Render(names, table.Options{Sort: true}))
+	}
+
+	if len(a.OutOfSync) > 0 {
+		var lines [][]string
+		for _, node := range sortedNodes(a.OutOfSync) {
+			lines = append(lines, []string{node.Name, strconv.FormatUint(node.Height, 10)})
+		}
+
+		fmt.Fprintf(&buf, "\n\nNot at %d height yet (%d):<pre>%s</pre>", a.Height, len(lines), table.Render(lines, table.Options{Align: []table.Align{table.AlignLeft, table.AlignRight}}))
+	}
+
+	return buf.String()
+}
+
+func (a ProbationAlert) redact(r *Redactor) Alert {
+	offline := make([]*health.NodeInfo, 0, len(a.Offline))
+	for _, node := range a.Offline {
+		redacted := r.node(*node)
+		offline = append(offline, &redacted)
+	}
+
+	a.Offline = offline
+	a.OutOfSync = r.nodeHeights(a.OutOfSync)
+	return a
+}
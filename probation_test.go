@@ -0,0 +1,122 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbation(t *testing.T) {
+	now := time.Now()
+	nodes := newScoreTestNodes()
+	established, added := nodes[0], nodes[1]
+
+	t.Run("Disabled", func(t *testing.T) {
+		probation := NewProbation(0)
+		assert.Nil(t, probation)
+		probation.restore([]string{}, nil, nodes, now)
+		assert.False(t, probation.inProbation(*added, now))
+
+		known, since := probation.export(nodes, now)
+		assert.Nil(t, known)
+		assert.Nil(t, since)
+	})
+
+	t.Run("Nodes missing from the state", func(t *testing.T) {
+		probation := NewProbation(24 * time.Hour)
+		probation.restore([]string{established.IdentityKey.String()}, nil, []*health.NodeInfo{established, added}, now)
+
+		assert.False(t, probation.inProbation(*established, now))
+		assert.True(t, probation.inProbation(*added, now))
+		assert.False(t, probation.inProbation(*added, now.Add(25*time.Hour)))
+
+		known, since := probation.export([]*health.NodeInfo{established, added}, now)
+		assert.Len(t, known, 2)
+		assert.Equal(t, map[string]time.Time{added.IdentityKey.String(): now}, since)
+
+		// The probation carries over a restart, and ends once the period has passed.
+		restored := NewProbation(24 * time.Hour)
+		restored.restore(known, since, []*health.NodeInfo{established, added}, now.Add(time.Hour))
+		assert.True(t, restored.inProbation(*added, now.Add(time.Hour)))
+
+		_, since = restored.export([]*health.NodeInfo{established, added}, now.Add(25*time.Hour))
+		assert.Nil(t, since)
+	})
+
+	t.Run("State saved before probation", func(t *testing.T) {
+		probation := NewProbation(24 * time.Hour)
+		probation.restore(nil, nil, nodes, now)
+		for _, node := range nodes {
+			assert.False(t, probation.inProbation(*node, now))
+		}
+	})
+
+	t.Run("Thresholds", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		am.notifier = &Notifier{enabled: true, dryRun: true}
+		am.config.OutOfSyncCriticalNodesThreshold = NodeThreshold{count: 1}
+		am.config.OfflineCriticalNodesThreshold = NodeThreshold{count: 1}
+		am.config.OfflineDurationThreshold = "0s"
+
+		syncing, offline := am.nodeInfos[0], am.nodeInfos[1]
+		am.probation = NewProbation(time.Hour)
+		am.probation.since[syncing.IdentityKey.String()] = now
+		am.probation.since[offline.IdentityKey.String()] = now
+
+		am.handleOfflineAlert(map[string]*health.NodeInfo{offline.IdentityKey.String(): offline}, 0)
+		am.handleSyncAlert(1000, map[health.NodeInfo]uint64{*syncing: 10}, map[health.NodeInfo]uint64{*am.nodeInfos[2]: 1000})
+
+		// Neither node counts toward the critical thresholds, and both are reported for information.
+		assert.NotContains(t, am.lastAlertTimes, OfflineAlertType)
+		assert.NotContains(t, am.lastAlertTimes, SyncAlertType)
+		require.Contains(t, am.lastAlertTimes, ProbationAlertType)
+		assert.Equal(t, []*health.NodeInfo{offline}, am.lastProbationOffline)
+
+		// The same nodes out of probation raise the alerts.
+		am.probation = nil
+		am.handleOfflineAlert(map[string]*health.NodeInfo{offline.IdentityKey.String(): offline}, 0)
+		am.handleSyncAlert(1000, map[health.NodeInfo]uint64{*syncing: 10}, map[health.NodeInfo]uint64{*am.nodeInfos[2]: 1000})
+		assert.Contains(t, am.lastAlertTimes, OfflineAlertType)
+		assert.Contains(t, am.lastAlertTimes, SyncAlertType)
+	})
+
+	t.Run("Percentage thresholds", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		am.notifier = &Notifier{enabled: true, dryRun: true}
+		am.config.OutOfSyncCriticalNodesThreshold = NodeThreshold{percent: 50}
+		am.config.OfflineCriticalNodesThreshold = NodeThreshold{percent: 50}
+		am.config.OfflineDurationThreshold = "0s"
+
+		// Two established nodes and the rest in probation: half of the established ones is a single node.
+		am.probation = NewProbation(time.Hour)
+		for _, node := range am.nodeInfos[2:] {
+			am.probation.since[node.IdentityKey.String()] = now
+		}
+		assert.Equal(t, 2, am.thresholdNodes())
+
+		lagging, synced := am.nodeInfos[0], am.nodeInfos[1]
+		reached := map[health.NodeInfo]uint64{*synced: 1000}
+		for _, node := range am.nodeInfos[2:] {
+			reached[*node] = 1000
+		}
+
+		am.handleOfflineAlert(map[string]*health.NodeInfo{lagging.IdentityKey.String(): lagging}, 0)
+		am.handleSyncAlert(1000, map[health.NodeInfo]uint64{*lagging: 10}, reached)
+		assert.Contains(t, am.lastAlertTimes, OfflineAlertType)
+		assert.Contains(t, am.lastAlertTimes, SyncAlertType)
+	})
+
+	t.Run("Alert", func(t *testing.T) {
+		alert := ProbationAlert{Height: 1000, Offline: []*health.NodeInfo{established}, OutOfSync: map[health.NodeInfo]uint64{*added: 10}}
+		assert.Equal(t, ProbationAlertType, alert.getType())
+		assert.Contains(t, alert.createMessage(), nodeName(*established))
+		assert.Contains(t, alert.createMessage(), "Not at 1000 height yet (1)")
+
+		redacted := alert.redact(NewRedactor(HashedPrivacyMode, nil)).createMessage()
+		assert.NotContains(t, redacted, established.Endpoint)
+		assert.NotContains(t, redacted, added.Endpoint)
+	})
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+)
+
+const (
+	// FriendlyPrivacyMode replaces endpoints with friendly names, hashing those of nodes without one.
+	FriendlyPrivacyMode = "friendly"
+	// HashedPrivacyMode replaces every endpoint with a hashed identifier.
+	HashedPrivacyMode = "hashed"
+)
+
+var ErrUnknownPrivacyMode = errors.New("unknown privacy mode")
+
+type (
+	// Redactor hides node endpoints in outbound alerts. Local logs and the status API keep the full details.
+	Redactor struct {
+		mode  string
+		names map[string]string
+	}
+
+	redactable interface {
+		redact(r *Redactor) Alert
+	}
+)
+
+func validatePrivacyMode(mode string) error {
+	switch mode {
+	case "", FriendlyPrivacyMode, HashedPrivacyMode:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownPrivacyMode, mode)
+	}
+}
+
+func NewRedactor(mode string, nodeInfos []*health.NodeInfo) *Redactor {
+	if mode == "" {
+		return nil
+	}
+
+	names := make(map[string]string, len(nodeInfos))
+	for _, info := range nodeInfos {
+		if info.FriendlyName != "" {
+			names[info.Endpoint] = info.FriendlyName
+		}
+	}
+
+	return &Redactor{mode: mode, names: names}
+}
+
+func hashedLabel(endpoint string) string {
+	sum := sha256.Sum256([]byte(endpoint))
+	return "node-" + hex.EncodeToString(sum[:4])
+}
+
+func (r *Redactor) label(endpoint, friendlyName string) string {
+	if r.mode == FriendlyPrivacyMode {
+		if friendlyName != "" {
+			return friendlyName
+		}
+
+		if name, exists := r.names[endpoint]; exists {
+			return name
+		}
+	}
+
+	return hashedLabel(endpoint)
+}
+
+func (r *Redactor) node(node health.NodeInfo) health.NodeInfo {
+	node.Endpoint = r.label(node.Endpoint, node.FriendlyName)
+	node.FriendlyName = ""
+	return node
+}
+
+func (r *Redactor) nodeHeights(nodes map[health.NodeInfo]uint64) map[health.NodeInfo]uint64 {
+	redacted := make(map[health.NodeInfo]uint64, len(nodes))
+	for node, height := range nodes {
+		redacted[r.node(node)] = height
+	}
+
+	return redacted
+}
+
+// redact returns a copy of the alert with its endpoints hidden, or the alert itself if there is nothing to hide.
+func (r *Redactor) redact(alert Alert) Alert {
+	if r == nil {
+		return alert
+	}
+
+	if a, ok := alert.(redactable); ok {
+		return a.redact(r)
+	}
+
+	return alert
+}
+
+func (a SyncAlert) redact(r *Redactor) Alert {
+	a.Reached = r.nodeHeights(a.Reached)
+	a.NotReached = r.nodeHeights(a.NotReached)
+	return a
+}
+
+func (a HashAlert) redact(r *Redactor) Alert {
+	hashes := make(map[string]sdk.Hash, len(a.Hashes))
+	for endpoint, hash := range a.Hashes {
+		label := r.label(endpoint, "")
+		if _, exists := hashes[label]; exists {
+			label = hashedLabel(endpoint)
+		}
+
+		hashes[label] = hash
+	}
+
+	noData := make([]string, 0, len(a.NoData))
+	for _, endpoint := range a.NoData {
+		noData = append(noData, r.label(endpoint, ""))
+	}
+
+	a.Hashes = hashes
+	a.NoData = noData
+	return a
+}
+
+func (a OfflineAlert) redact(r *Redactor) Alert {
+	notConnected := make(map[string]*health.NodeInfo, len(a.NotConnected))
+	for key, node := range a.NotConnected {
+		redacted := r.node(*node)
+		notConnected[key] = &redacted
+	}
+
+	a.NotConnected = notConnected
+	return a
+}
+
+func (a MaintenanceSummaryAlert) redact(r *Redactor) Alert {
+	offline := make([]*health.NodeInfo, 0, len(a.Offline))
+	for _, node := range a.Offline {
+		redacted := r.node(*node)
+		offline = append(offline, &redacted)
+	}
+
+	a.Offline = offline
+	a.OutOfSync = r.nodeHeights(a.OutOfSync)
+	return a
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePrivacyMode(t *testing.T) {
+	require.NoError(t, validatePrivacyMode(""))
+	require.NoError(t, validatePrivacyMode(FriendlyPrivacyMode))
+	require.NoError(t, validatePrivacyMode(HashedPrivacyMode))
+	require.ErrorIs(t, validatePrivacyMode("secret"), ErrUnknownPrivacyMode)
+}
+
+func TestRedactor(t *testing.T) {
+	nodeA := &health.NodeInfo{Endpoint: "10.0.0.1:7900", FriendlyName: "nodeA"}
+	nodeB := &health.NodeInfo{Endpoint: "10.0.0.2:7900"}
+
+	t.Run("Disabled", func(t *testing.T) {
+		redactor := NewRedactor("", []*health.NodeInfo{nodeA, nodeB})
+		alert := HashAlert{Height: 10, Hashes: map[string]sdk.Hash{nodeA.Endpoint: {1}}}
+		assert.Equal(t, alert, redactor.redact(alert))
+	})
+
+	t.Run("Friendly names", func(t *testing.T) {
+		redactor := NewRedactor(FriendlyPrivacyMode, []*health.NodeInfo{nodeA, nodeB})
+
+		hashAlert := redactor.redact(HashAlert{
+			Height: 10,
+			Hashes: map[string]sdk.Hash{nodeA.Endpoint: {1}, nodeB.Endpoint: {2}},
+			NoData: []string{nodeA.Endpoint},
+		}).(HashAlert)
+		assert.Equal(t, map[string]sdk.Hash{"nodeA": {1}, hashedLabel(nodeB.Endpoint): {2}}, hashAlert.Hashes)
+		assert.Equal(t, []string{"nodeA"}, hashAlert.NoData)
+		assert.Contains(t, hashAlert.createMessage(), "No data (1):")
+
+		syncAlert := redactor.redact(SyncAlert{
+			Height:     10,
+			Reached:    map[health.NodeInfo]uint64{*nodeA: 10},
+			NotReached: map[health.NodeInfo]uint64{*nodeB: 5},
+		})
+		msg := syncAlert.createMessage()
+		assert.Contains(t, msg, "nodeA")
+		assert.Contains(t, msg, hashedLabel(nodeB.Endpoint))
+		assert.NotContains(t, msg, "10.0.0.")
+	})
+
+	t.Run("Hashed identifiers", func(t *testing.T) {
+		redactor := NewRedactor(HashedPrivacyMode, []*health.NodeInfo{nodeA, nodeB})
+
+		offlineAlert := OfflineAlert{NotConnected: map[string]*health.NodeInfo{"keyA": nodeA}}
+		msg := redactor.redact(offlineAlert).createMessage()
+		assert.Contains(t, msg, hashedLabel(nodeA.Endpoint))
+		assert.NotContains(t, msg, "nodeA")
+		assert.NotContains(t, msg, "10.0.0.1")
+
+		// The original alert keeps its details for logging and offline tracking.
+		assert.Equal(t, "10.0.0.1:7900", offlineAlert.NotConnected["keyA"].Endpoint)
+	})
+}
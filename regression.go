@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"go-xpx-check-fork-util/internal/table"
+)
+
+const DefaultHeightRegressionTolerance = 2
+
+type (
+	// HeightTracker remembers the last height reported by every node, to catch a node whose height goes down, e.g.
+	// after a local rollback or a database reset. The sync checks only compare nodes against the checkpoint, so a
+	// node that drops back but is still ahead of it goes unnoticed there.
+	HeightTracker struct {
+		tolerance uint64
+		heights   map[string]uint64
+	}
+
+	HeightRegression struct {
+		Node health.NodeInfo
+		From uint64
+		To   uint64
+	}
+
+	// HeightRegressionAlert reports nodes whose height went down by more than the tolerance since the last cycle.
+	HeightRegressionAlert struct {
+		Regressions []HeightRegression
+	}
+)
+
+func NewHeightTracker(tolerance uint64) *HeightTracker {
+	return &HeightTracker{tolerance: tolerance, heights: make(map[string]uint64)}
+}
+
+func (r HeightRegression) Blocks() uint64 {
+	return r.From - r.To
+}
+
+// update records the heights of a cycle and returns the nodes that went down by more than the tolerance, largest
+// regression first. A height of 0 means the node did not answer and is not recorded.
+func (t *HeightTracker) update(heights ...map[health.NodeInfo]uint64) []HeightRegression {
+	var regressions []HeightRegression
+	for _, nodes := range heights {
+		for node, height := range nodes {
+			if height == 0 {
+				continue
+			}
+
+			key := node.IdentityKey.String()
+			if last, exists := t.heights[key]; exists && last > height+t.tolerance {
+				regressions = append(regressions, HeightRegression{Node: node, From: last, To: height})
+			}
+
+			t.heights[key] = height
+		}
+	}
+
+	sort.Slice(regressions, func(i, j int) bool {
+		if regressions[i].Blocks() != regressions[j].Blocks() {
+			return regressions[i].Blocks() > regressions[j].Blocks()
+		}
+		return nodeName(regressions[i].Node) < nodeName(regressions[j].Node)
+	})
+
+	return regressions
+}
+
+func (a HeightRegressionAlert) getType() AlertType {
+	return HeightRegressionAlertType
+}
+
+func (a HeightRegressionAlert) createMessage() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "<b>❗ Height regression </b>\n\n")
+	rows := make([][]string, 0, len(a.Regressions))
+	for _, regression := range a.Regressions {
+		rows = append(rows, []string{nodeName(regression.Node), fmt.Sprintf("%d -> %d (-%d)", regression.From, regression.To, regression.Blocks())})
+	}
+	fmt.Fprintf(&buf, "Height went down, possibly a rollback or a database reset (%d):<pre>%s</pre>", len(a.Regressions), table.Render(rows, table.Options{}))
+
+	return buf.String()
+}
+
+func (a HeightRegressionAlert) redact(r *Redactor) Alert {
+	regressions := make([]HeightRegression, 0, len(a.Regressions))
+	for _, regression := range a.Regressions {
+		regression.Node = r.node(regression.Node)
+		regressions = append(regressions, regression)
+	}
+
+	a.Regressions = regressions
+	return a
+}
+
+// handleHeights alerts on nodes whose height went down since the last cycle. Nodes under maintenance are skipped,
+// as a resync is expected there, but their height is still recorded.
+func (am *AlertManager) handleHeights(notReached, reached map[health.NodeInfo]uint64) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	now := time.Now()
+
+	var alert HeightRegressionAlert
+	for _, regression := range am.heights.update(notReached, reached) {
+		log.Printf("height of %s went down from %d to %d", regression.Node.Endpoint, regression.From, regression.To)
+
+		if !am.maintenance.inMaintenance(regression.Node.Endpoint, now) {
+			alert.Regressions = append(alert.Regressions, regression)
+		}
+	}
+
+	if len(alert.Regressions) > 0 {
+		am.sendToTelegram(alert)
+	}
+}
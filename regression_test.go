@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeightTracker(t *testing.T) {
+	nodeA := health.NodeInfo{Endpoint: "10.0.0.1:7900", IdentityKey: getPublicKey("AF7A80E9D6C2A4F5B46B90A1D16E95D4C1B8A3E8D5D1479D7C802C475D70A2E1"), FriendlyName: "nodeA"}
+	nodeB := health.NodeInfo{Endpoint: "10.0.0.2:7900", IdentityKey: getPublicKey("BF7A80E9D6C2A4F5B46B90A1D16E95D4C1B8A3E8D5D1479D7C802C475D70A2E1"), FriendlyName: "nodeB"}
+
+	t.Run("Regression", func(t *testing.T) {
+		tracker := NewHeightTracker(DefaultHeightRegressionTolerance)
+		assert.Empty(t, tracker.update(map[health.NodeInfo]uint64{nodeA: 100}, map[health.NodeInfo]uint64{nodeB: 100}))
+
+		// Going down within the tolerance, e.g. between two API nodes behind a load balancer, is not a regression.
+		assert.Empty(t, tracker.update(map[health.NodeInfo]uint64{nodeA: 98}, map[health.NodeInfo]uint64{nodeB: 101}))
+
+		regressions := tracker.update(map[health.NodeInfo]uint64{nodeA: 90, nodeB: 50})
+		require.Len(t, regressions, 2)
+		assert.Equal(t, HeightRegression{Node: nodeB, From: 101, To: 50}, regressions[0])
+		assert.Equal(t, HeightRegression{Node: nodeA, From: 98, To: 90}, regressions[1])
+		assert.Equal(t, uint64(51), regressions[0].Blocks())
+
+		// The new height is the baseline, so a regression is reported once.
+		assert.Empty(t, tracker.update(map[health.NodeInfo]uint64{nodeA: 90, nodeB: 50}))
+	})
+
+	t.Run("Unanswered height", func(t *testing.T) {
+		tracker := NewHeightTracker(DefaultHeightRegressionTolerance)
+		tracker.update(map[health.NodeInfo]uint64{nodeA: 100})
+		assert.Empty(t, tracker.update(map[health.NodeInfo]uint64{nodeA: 0}))
+		assert.Empty(t, tracker.update(map[health.NodeInfo]uint64{nodeA: 100}))
+	})
+
+	t.Run("Alert", func(t *testing.T) {
+		alert := HeightRegressionAlert{Regressions: []HeightRegression{{Node: nodeA, From: 100, To: 40}}}
+		assert.Equal(t, HeightRegressionAlertType, alert.getType())
+		assert.Contains(t, alert.createMessage(), nodeName(nodeA))
+		assert.Contains(t, alert.createMessage(), "100 -&gt; 40 (-60)")
+
+		redacted := alert.redact(NewRedactor(HashedPrivacyMode, nil)).(HeightRegressionAlert)
+		assert.NotContains(t, redacted.createMessage(), nodeA.Endpoint)
+		assert.Contains(t, redacted.createMessage(), "100 -&gt; 40 (-60)")
+	})
+}
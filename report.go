@@ -0,0 +1,49 @@
+package main
+
+import (
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+)
+
+type (
+	// CheckReport is the outcome of a single check cycle, printed by the one-shot mode. Unlike the alerts, the
+	// conditions are reported as found, without the duration thresholds or repeat intervals.
+	CheckReport struct {
+		Time       time.Time         `json:"time"`
+		Checkpoint uint64            `json:"checkpoint"`
+		PeerHeight uint64            `json:"peerHeight"`
+		Connected  int               `json:"connected"`
+		Offline    []StatusNode      `json:"offline"`
+		NotReached []StatusNode      `json:"notReached"`
+		Hashes     map[string]string `json:"hashes,omitempty"`
+		NoData     []StatusNode      `json:"noData,omitempty"`
+		Fork       bool              `json:"fork"`
+		Stuck      bool              `json:"stuck"`
+		Error      string            `json:"error,omitempty"`
+	}
+)
+
+// healthy reports whether the cycle completed without finding a fork, a stuck chain or an offline node.
+func (r *CheckReport) healthy() bool {
+	return r.Error == "" && !r.Fork && !r.Stuck && len(r.Offline) == 0
+}
+
+// observeOffline records the configured nodes that could not be connected.
+func (r *CheckReport) observeOffline(nodeInfos []*health.NodeInfo, failed map[string]*health.NodeInfo) {
+	r.Offline = []StatusNode{}
+	for _, info := range nodeInfos {
+		if _, exists := failed[info.IdentityKey.String()]; exists {
+			r.Offline = append(r.Offline, newStatusNode(*info, 0, ""))
+		}
+	}
+	sortStatusNodes(r.Offline)
+}
+
+func (r *CheckReport) observeNotReached(notReached map[health.NodeInfo]uint64) {
+	r.NotReached = []StatusNode{}
+	for node, height := range notReached {
+		r.NotReached = append(r.NotReached, newStatusNode(node, height, ""))
+	}
+	sortStatusNodes(r.NotReached)
+}
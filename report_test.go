@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newReportTestForkChecker(t *testing.T, nodes ...*testNode) *ForkChecker {
+	am := newIncidentTestAlertManager(t, nil)
+	am.notifier = &Notifier{enabled: true, dryRun: true}
+	am.hashStreaks = NewHashStreakTracker()
+
+	am.nodeInfos = nil
+	for _, node := range nodes {
+		am.nodeInfos = append(am.nodeInfos, node.info())
+	}
+
+	return &ForkChecker{
+		cfg:          Config{HeightCheckInterval: 1},
+		alertManager: am,
+		nodePool:     newTestNodePool(t),
+		checkpoint:   10,
+	}
+}
+
+func TestCheckReport(t *testing.T) {
+	t.Run("Healthy", func(t *testing.T) {
+		nodeA := newTestNode(t, 12, sdk.Hash{1})
+		nodeB := newTestNode(t, 10, sdk.Hash{1})
+
+		fc := newReportTestForkChecker(t, nodeA, nodeB)
+		report := fc.checkCycle(context.Background())
+		assert.True(t, report.healthy())
+		assert.Equal(t, uint64(10), report.Checkpoint)
+		assert.Equal(t, uint64(12), report.PeerHeight)
+		assert.Equal(t, 2, report.Connected)
+		assert.Empty(t, report.Offline)
+		assert.Equal(t, sdk.Hash{1}.String(), report.Hashes[nodeA.info().Endpoint])
+		assert.Equal(t, uint64(11), fc.checkpoint)
+	})
+
+	t.Run("Fork", func(t *testing.T) {
+		nodeA := newTestNode(t, 10, sdk.Hash{1})
+		nodeB := newTestNode(t, 10, sdk.Hash{2})
+
+		// Alerts are logged rather than sent in a dry run.
+		report := newReportTestForkChecker(t, nodeA, nodeB).checkCycle(context.Background())
+		assert.False(t, report.healthy())
+		assert.True(t, report.Fork)
+		assert.Len(t, report.Hashes, 2)
+	})
+
+	t.Run("Fork followed up with the final hashes", func(t *testing.T) {
+		nodeA := newTestNode(t, 10, sdk.Hash{1})
+		nodeB := newTestNode(t, 10, sdk.Hash{2})
+		slow := newTestNode(t, 10, sdk.Hash{1})
+		slow.delay.Store(int64(100 * time.Millisecond))
+
+		bot, telegram := newTestBot(t)
+		fc := newReportTestForkChecker(t, nodeA, nodeB, slow)
+		fc.alertManager.notifier = &Notifier{bot: bot, enabled: true, chatID: 1}
+
+		report := fc.checkCycle(context.Background())
+		assert.True(t, report.Fork)
+		assert.Len(t, report.Hashes, 3)
+
+		// The fork is alerted before the slow node answers, then followed up with its hash.
+		require.Len(t, telegram.messages, 2)
+		assert.Contains(t, telegram.messages[0], "No data (1)")
+		assert.Contains(t, telegram.messages[0], slow.info().Endpoint)
+		assert.Contains(t, telegram.messages[1], "final hashes")
+		assert.NotContains(t, telegram.messages[1], "No data")
+		assert.Contains(t, telegram.messages[1], slow.info().Endpoint)
+	})
+
+	t.Run("Failed before reporting a height", func(t *testing.T) {
+		nodeA := newTestNode(t, 10, sdk.Hash{1})
+		silent := newTestNode(t, 10, sdk.Hash{1})
+		silent.delay.Store(int64(time.Second))
+
+		// The node accepts the connection but never answers, so it is offline rather than at height 0.
+		report := newReportTestForkChecker(t, nodeA, silent).checkCycle(context.Background())
+		require.Len(t, report.Offline, 1)
+		assert.Equal(t, silent.info().Endpoint, report.Offline[0].Endpoint)
+		assert.Empty(t, report.NotReached)
+	})
+
+	t.Run("Offline and stuck", func(t *testing.T) {
+		behind := newTestNode(t, 8, sdk.Hash{1})
+		closed := newTestNode(t, 8, sdk.Hash{1})
+		closed.listener.Close()
+
+		report := newReportTestForkChecker(t, behind, closed).checkCycle(context.Background())
+		assert.False(t, report.healthy())
+		assert.True(t, report.Stuck)
+		require.Len(t, report.Offline, 1)
+		assert.Equal(t, closed.info().Endpoint, report.Offline[0].Endpoint)
+		require.Len(t, report.NotReached, 1)
+		assert.Equal(t, uint64(8), report.NotReached[0].Height)
+	})
+
+	t.Run("Offline alert in the first cycle", func(t *testing.T) {
+		nodeA := newTestNode(t, 15, sdk.Hash{1})
+		closed := newTestNode(t, 15, sdk.Hash{1})
+		closed.listener.Close()
+
+		fc := newReportTestForkChecker(t, nodeA, closed)
+		bot, telegram := newTestBot(t)
+		fc.alertManager.notifier = &Notifier{bot: bot, enabled: true}
+		fc.alertManager.config.OfflineCriticalNodesThreshold = NodeThreshold{count: 1}
+		fc.alertManager.config.OfflineDurationThreshold = "0s"
+
+		// The offline alert states the chain height of the same cycle, not the one of the cycle before.
+		fc.checkCycle(context.Background())
+		require.Len(t, telegram.messages, 1)
+		assert.Contains(t, telegram.messages[0], "Chain height: <b>15</b>")
+	})
+
+	t.Run("No connections", func(t *testing.T) {
+		closed := newTestNode(t, 10, sdk.Hash{1})
+		closed.listener.Close()
+
+		report := newReportTestForkChecker(t, closed).checkCycle(context.Background())
+		assert.False(t, report.healthy())
+		assert.Equal(t, health.ErrCannotConnect.Error(), report.Error)
+	})
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+)
+
+const (
+	DefaultScoreWindow = time.Hour
+
+	DefaultUptimeWeight    = 0.4
+	DefaultSyncWeight      = 0.3
+	DefaultAgreementWeight = 0.3
+)
+
+var ErrInvalidScoreConfig = errors.New("invalid score config")
+
+type (
+	// ScoreTracker keeps a rolling reputation score per configured node from the results of the check cycles, for
+	// load balancers to weight traffic away from unhealthy nodes.
+	ScoreTracker struct {
+		window  time.Duration
+		weights ScoreWeights
+		file    string
+		nodes   map[string]*nodeScore
+	}
+
+	nodeScore struct {
+		node      health.NodeInfo
+		uptime    []scoreSample
+		sync      []scoreSample
+		agreement []scoreSample
+	}
+
+	scoreSample struct {
+		time time.Time
+		ok   bool
+	}
+
+	// NodeScore is the score of a node between 0 and 1, with the share of good samples of each component. Weight is
+	// the score scaled to 0-100, as used by most load balancers.
+	NodeScore struct {
+		Name        string   `json:"name"`
+		Endpoint    string   `json:"endpoint"`
+		IdentityKey string   `json:"identityKey"`
+		Score       float64  `json:"score"`
+		Weight      int      `json:"weight"`
+		Uptime      *float64 `json:"uptime,omitempty"`
+		Sync        *float64 `json:"sync,omitempty"`
+		Agreement   *float64 `json:"agreement,omitempty"`
+	}
+)
+
+func validateScoreConfig(config ScoreConfig) error {
+	w := config.Weights
+	if w.Uptime < 0 || w.Sync < 0 || w.Agreement < 0 {
+		return fmt.Errorf("%w: negative weight", ErrInvalidScoreConfig)
+	}
+
+	return nil
+}
+
+// NewScoreTracker returns nil if scoring is disabled.
+func NewScoreTracker(config ScoreConfig, nodeInfos []*health.NodeInfo) *ScoreTracker {
+	if !config.Enabled {
+		return nil
+	}
+
+	nodes := make(map[string]*nodeScore, len(nodeInfos))
+	for _, info := range nodeInfos {
+		nodes[info.IdentityKey.String()] = &nodeScore{node: *info}
+	}
+
+	return &ScoreTracker{
+		window:  config.getWindow(),
+		weights: config.getWeights(),
+		file:    config.File,
+		nodes:   nodes,
+	}
+}
+
+// add appends a sample and drops the ones that have left the window.
+func (s *ScoreTracker) add(samples []scoreSample, sample scoreSample) []scoreSample {
+	samples = append(samples, sample)
+
+	cutoff := sample.time.Add(-s.window)
+	i := 0
+	for i < len(samples) && samples[i].time.Before(cutoff) {
+		i++
+	}
+
+	return samples[i:]
+}
+
+// observeOffline records which configured nodes could be connected.
+func (s *ScoreTracker) observeOffline(now time.Time, failed map[string]*health.NodeInfo) {
+	if s == nil {
+		return
+	}
+
+	for key, node := range s.nodes {
+		_, offline := failed[key]
+		node.uptime = s.add(node.uptime, scoreSample{time: now, ok: !offline})
+	}
+}
+
+// observeSync records which nodes reached the checkpoint. A stuck chain says nothing about the nodes, so it is
+// not recorded.
+func (s *ScoreTracker) observeSync(now time.Time, notReached, reached map[health.NodeInfo]uint64) {
+	if s == nil || len(reached) == 0 {
+		return
+	}
+
+	for key, node := range s.nodes {
+		for info := range reached {
+			if info.IdentityKey.String() == key {
+				node.sync = s.add(node.sync, scoreSample{time: now, ok: true})
+			}
+		}
+
+		for info := range notReached {
+			if info.IdentityKey.String() == key {
+				node.sync = s.add(node.sync, scoreSample{time: now, ok: false})
+			}
+		}
+	}
+}
+
+// observeHashes records which nodes agreed with the majority hash. Without a clear majority, nothing is recorded.
+func (s *ScoreTracker) observeHashes(now time.Time, hashes map[string]sdk.Hash) {
+	if s == nil {
+		return
+	}
+
+	majority, ok := majorityHash(hashes)
+	if !ok {
+		return
+	}
+
+	for _, node := range s.nodes {
+		if hash, exists := hashes[node.node.Endpoint]; exists {
+			node.agreement = s.add(node.agreement, scoreSample{time: now, ok: hash == majority})
+		}
+	}
+}
+
+// share returns the share of good samples, or nil without any.
+func share(samples []scoreSample) *float64 {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	good := 0
+	for _, sample := range samples {
+		if sample.ok {
+			good++
+		}
+	}
+
+	result := roundScore(float64(good) / float64(len(samples)))
+	return &result
+}
+
+func roundScore(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
+// scores returns the scores of the configured nodes, best first. The score is the weighted average of the components
+// that have samples in the window, so a node that is never reached scores 0 on its uptime alone.
+func (s *ScoreTracker) scores() []NodeScore {
+	if s == nil {
+		return nil
+	}
+
+	result := make([]NodeScore, 0, len(s.nodes))
+	for _, node := range s.nodes {
+		score := NodeScore{
+			Name:        nodeName(node.node),
+			Endpoint:    node.node.Endpoint,
+			IdentityKey: node.node.IdentityKey.String(),
+			Uptime:      share(node.uptime),
+			Sync:        share(node.sync),
+			Agreement:   share(node.agreement),
+		}
+
+		var sum, weights float64
+		for _, component := range []struct {
+			value  *float64
+			weight float64
+		}{
+			{score.Uptime, s.weights.Uptime},
+			{score.Sync, s.weights.Sync},
+			{score.Agreement, s.weights.Agreement},
+		} {
+			if component.value != nil {
+				sum += *component.value * component.weight
+				weights += component.weight
+			}
+		}
+
+		// A node without any samples yet is given the benefit of the doubt.
+		score.Score = 1
+		if weights > 0 {
+			score.Score = roundScore(sum / weights)
+		}
+		score.Weight = int(math.Round(score.Score * 100))
+
+		result = append(result, score)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// export writes the scores to the configured file, through a temporary file so a reader never sees a partial one.
+func (s *ScoreTracker) export(scores []NodeScore) error {
+	if s == nil || s.file == "" {
+		return nil
+	}
+
+	content, err := json.Marshal(scores)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.file + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return fmt.Errorf("failed writing score file '%s': %w", tmp, err)
+	}
+
+	return os.Rename(tmp, s.file)
+}
+
+// publishScores exports the scores after a check cycle.
+func (fc *ForkChecker) publishScores(scores []NodeScore) {
+	if err := fc.scores.export(scores); err != nil {
+		log.Printf("failed to export node scores: %v", err)
+	}
+}
+
+// serveScores serves the node scores of the last check cycle on their own, for load balancers polling them.
+func (s *StatusServer) serveScores(w http.ResponseWriter, r *http.Request) {
+	scores := s.get().Scores
+	if scores == nil {
+		scores = []NodeScore{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(scores); err != nil {
+		log.Printf("failed writing scores response: %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newScoreTestNodes() []*health.NodeInfo {
+	return []*health.NodeInfo{
+		{Endpoint: "10.0.0.1:7900", IdentityKey: getPublicKey("AF7A80E9D6C2A4F5B46B90A1D16E95D4C1B8A3E8D5D1479D7C802C475D70A2E1"), FriendlyName: "nodeA"},
+		{Endpoint: "10.0.0.2:7900", IdentityKey: getPublicKey("BF7A80E9D6C2A4F5B46B90A1D16E95D4C1B8A3E8D5D1479D7C802C475D70A2E1"), FriendlyName: "nodeB"},
+		{Endpoint: "10.0.0.3:7900", IdentityKey: getPublicKey("CF7A80E9D6C2A4F5B46B90A1D16E95D4C1B8A3E8D5D1479D7C802C475D70A2E1"), FriendlyName: "nodeC"},
+	}
+}
+
+func scoreOf(scores []NodeScore, node *health.NodeInfo) NodeScore {
+	for _, score := range scores {
+		if score.Endpoint == node.Endpoint {
+			return score
+		}
+	}
+	return NodeScore{}
+}
+
+func TestScoreTracker(t *testing.T) {
+	nodes := newScoreTestNodes()
+	nodeA, nodeB, nodeC := nodes[0], nodes[1], nodes[2]
+	start := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Disabled", func(t *testing.T) {
+		tracker := NewScoreTracker(ScoreConfig{}, nodes)
+		assert.Nil(t, tracker)
+
+		tracker.observeOffline(start, nil)
+		assert.Nil(t, tracker.scores())
+		assert.NoError(t, tracker.export(nil))
+	})
+
+	t.Run("Scores", func(t *testing.T) {
+		tracker := NewScoreTracker(ScoreConfig{Enabled: true}, nodes)
+
+		// Without samples, every node keeps the full score.
+		for _, score := range tracker.scores() {
+			assert.Equal(t, 1.0, score.Score)
+			assert.Equal(t, 100, score.Weight)
+		}
+
+		for i := 0; i < 4; i++ {
+			now := start.Add(time.Duration(i) * time.Minute)
+			tracker.observeOffline(now, map[string]*health.NodeInfo{nodeC.IdentityKey.String(): nodeC})
+
+			notReached := map[health.NodeInfo]uint64{}
+			reached := map[health.NodeInfo]uint64{*nodeA: 10}
+			if i%2 == 0 {
+				notReached[*nodeB] = 8
+			} else {
+				reached[*nodeB] = 10
+			}
+			tracker.observeSync(now, notReached, reached)
+
+			tracker.observeHashes(now, map[string]sdk.Hash{nodeA.Endpoint: {1}, nodeB.Endpoint: {1}})
+		}
+
+		scores := tracker.scores()
+		require.Len(t, scores, 3)
+		assert.Equal(t, []string{nodeA.Endpoint, nodeB.Endpoint, nodeC.Endpoint}, []string{scores[0].Endpoint, scores[1].Endpoint, scores[2].Endpoint})
+
+		assert.Equal(t, 1.0, scoreOf(scores, nodeA).Score)
+
+		// Half of the syncs failed: 0.4*1 + 0.3*0.5 + 0.3*1.
+		nodeBScore := scoreOf(scores, nodeB)
+		assert.Equal(t, 0.5, *nodeBScore.Sync)
+		assert.Equal(t, 0.85, nodeBScore.Score)
+		assert.Equal(t, 85, nodeBScore.Weight)
+
+		// A node that is never reached has nothing but its uptime.
+		nodeCScore := scoreOf(scores, nodeC)
+		assert.Equal(t, 0.0, nodeCScore.Score)
+		assert.Nil(t, nodeCScore.Sync)
+		assert.Nil(t, nodeCScore.Agreement)
+	})
+
+	t.Run("Inconclusive cycles", func(t *testing.T) {
+		tracker := NewScoreTracker(ScoreConfig{Enabled: true}, nodes)
+
+		// A stuck chain and a tie between hashes say nothing about the nodes.
+		tracker.observeSync(start, map[health.NodeInfo]uint64{*nodeA: 8}, map[health.NodeInfo]uint64{})
+		tracker.observeHashes(start, map[string]sdk.Hash{nodeA.Endpoint: {1}, nodeB.Endpoint: {2}})
+
+		score := scoreOf(tracker.scores(), nodeA)
+		assert.Nil(t, score.Sync)
+		assert.Nil(t, score.Agreement)
+	})
+
+	t.Run("Window", func(t *testing.T) {
+		tracker := NewScoreTracker(ScoreConfig{Enabled: true, Window: "10m", Weights: ScoreWeights{Uptime: 1}}, nodes)
+
+		offline := map[string]*health.NodeInfo{nodeA.IdentityKey.String(): nodeA}
+		tracker.observeOffline(start, offline)
+		tracker.observeOffline(start.Add(5*time.Minute), offline)
+		assert.Equal(t, 0.0, scoreOf(tracker.scores(), nodeA).Score)
+
+		// The offline samples leave the window, and only the weighted component counts.
+		tracker.observeOffline(start.Add(16*time.Minute), nil)
+		tracker.observeHashes(start.Add(16*time.Minute), map[string]sdk.Hash{nodeA.Endpoint: {2}, nodeB.Endpoint: {1}, nodeC.Endpoint: {1}})
+		score := scoreOf(tracker.scores(), nodeA)
+		assert.Equal(t, 1.0, score.Score)
+		assert.Equal(t, 0.0, *score.Agreement)
+	})
+
+	t.Run("Invalid weights", func(t *testing.T) {
+		require.ErrorIs(t, validateScoreConfig(ScoreConfig{Weights: ScoreWeights{Sync: -1}}), ErrInvalidScoreConfig)
+	})
+}
+
+func TestScoreFeed(t *testing.T) {
+	nodes := newScoreTestNodes()
+	file := filepath.Join(t.TempDir(), "scores.json")
+
+	tracker := NewScoreTracker(ScoreConfig{Enabled: true, File: file}, nodes)
+	tracker.observeOffline(time.Now(), map[string]*health.NodeInfo{nodes[2].IdentityKey.String(): nodes[2]})
+	scores := tracker.scores()
+
+	t.Run("Export", func(t *testing.T) {
+		require.NoError(t, tracker.export(scores))
+
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+
+		var exported []NodeScore
+		require.NoError(t, json.Unmarshal(content, &exported))
+		assert.Equal(t, scores, exported)
+	})
+
+	t.Run("Status server", func(t *testing.T) {
+		server := &StatusServer{}
+
+		recorder := httptest.NewRecorder()
+		server.serveScores(recorder, httptest.NewRequest("GET", "/scores", nil))
+		assert.JSONEq(t, "[]", recorder.Body.String())
+
+		server.update(Status{Scores: scores})
+		recorder = httptest.NewRecorder()
+		server.serveScores(recorder, httptest.NewRequest("GET", "/scores", nil))
+
+		var served []NodeScore
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &served))
+		assert.Equal(t, scores, served)
+	})
+}
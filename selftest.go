@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+var ErrSelfTestFailed = errors.New("alert channel self-test failed")
+
+// selfTest sends a test message to the alert channels before the monitoring starts, so that a wrong chat ID or a
+// bot removed from the chat fails the startup rather than swallowing the first real alert. The alert chat and the
+// pager are critical; a failing digest chat is only logged. Nothing is sent in a dry run or with notifications off.
+func (am *AlertManager) selfTest(config SelfTestConfig) error {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	if am.notifier.enabled && !am.notifier.dryRun {
+		msg := fmt.Sprintf("<b>🔧 Fork checker started </b>\n\nSelf-test by the checker on %s: alerts are sent to this chat.", host)
+		if err := am.notifier.sendToTelegram(msg); err != nil {
+			return fmt.Errorf("%w: chat %d: %v", ErrSelfTestFailed, am.notifier.chatID, err)
+		}
+
+		if am.digest != nil && am.digest.chatID != 0 && am.digest.chatID != am.notifier.chatID {
+			msg := fmt.Sprintf("<b>🔧 Fork checker started </b>\n\nSelf-test by the checker on %s: summaries are sent to this chat.", host)
+			if err := am.notifier.sendToChat(am.digest.chatID, msg); err != nil {
+				log.Printf("Self-test of the digest chat %d failed: %v", am.digest.chatID, err)
+			}
+		}
+	}
+
+	if config.Pager && am.pager != nil {
+		key := fmt.Sprintf("%s-self-test", incidentSource)
+		if err := am.pager.trigger(key, fmt.Sprintf("Fork checker self-test on %s, resolved right away", host)); err != nil {
+			return fmt.Errorf("%w: pager: %v", ErrSelfTestFailed, err)
+		}
+
+		if err := am.pager.resolve(key); err != nil {
+			return fmt.Errorf("%w: pager: resolving the test incident %s: %v", ErrSelfTestFailed, key, err)
+		}
+	}
+
+	log.Printf("Self-test of the alert channels passed")
+	return nil
+}
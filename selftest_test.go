@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingPager struct{}
+
+func (failingPager) trigger(dedupKey, summary string) error {
+	return errors.New("invalid routing key")
+}
+
+func (failingPager) resolve(dedupKey string) error {
+	return nil
+}
+
+func TestSelfTest(t *testing.T) {
+	t.Run("Passes", func(t *testing.T) {
+		bot, telegram := newTestBot(t)
+		pager := &fakePager{}
+
+		am := newIncidentTestAlertManager(t, pager)
+		am.notifier = &Notifier{bot: bot, chatID: 1, enabled: true}
+		am.digest = &DigestCollector{chatID: 2}
+
+		require.NoError(t, am.selfTest(SelfTestConfig{Pager: true}))
+		assert.Len(t, telegram.replies, 2)
+		assert.Equal(t, []string{"go-xpx-check-fork-util-self-test"}, pager.triggered)
+		assert.Equal(t, pager.triggered, pager.resolved)
+	})
+
+	t.Run("Pager is opt-in", func(t *testing.T) {
+		pager := &fakePager{}
+		am := newIncidentTestAlertManager(t, pager)
+
+		require.NoError(t, am.selfTest(SelfTestConfig{}))
+		assert.Empty(t, pager.triggered)
+	})
+
+	t.Run("Broken chat", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`)
+		}))
+		t.Cleanup(server.Close)
+
+		bot := &tgbotapi.BotAPI{Token: "token", Client: server.Client()}
+		bot.SetAPIEndpoint(server.URL + "/bot%s/%s")
+
+		am := newIncidentTestAlertManager(t, nil)
+		am.notifier = &Notifier{bot: bot, chatID: 42, enabled: true}
+
+		err := am.selfTest(SelfTestConfig{})
+		assert.ErrorIs(t, err, ErrSelfTestFailed)
+		assert.ErrorContains(t, err, "chat 42")
+		assert.ErrorContains(t, err, "chat not found")
+	})
+
+	t.Run("Broken pager", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, failingPager{})
+		assert.ErrorIs(t, am.selfTest(SelfTestConfig{Pager: true}), ErrSelfTestFailed)
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		am.notifier = &Notifier{enabled: true, dryRun: true}
+		assert.NoError(t, am.selfTest(SelfTestConfig{}))
+	})
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	FileStateBackend = "file"
+	S3StateBackend   = "s3"
+
+	stateRequestTimeout = 10 * time.Second
+)
+
+var (
+	ErrUnknownStateBackend = errors.New("unknown state backend")
+	ErrEmptyStateFile      = errors.New("File cannot be empty for file state backend")
+	ErrEmptyStateBucket    = errors.New("Bucket and Key cannot be empty for s3 state backend")
+	ErrEmptyStateRegion    = errors.New("Region cannot be empty for s3 state backend unless AWS_REGION is set")
+)
+
+type (
+	// CheckerState is the part of the checker's memory that has to survive a restart, e.g. between scheduled runs.
+	CheckerState struct {
+		Checkpoint      uint64                      `json:"checkpoint"`
+		LastAlertTimes  map[string]time.Time        `json:"lastAlertTimes"`
+		LastStuckHeight uint64                      `json:"lastStuckHeight"`
+		LastStuckTime   time.Time                   `json:"lastStuckTime"`
+		OfflineNodes    map[string]OfflineNodeState `json:"offlineNodes"`
+		OpenIncidents   map[string]string           `json:"openIncidents"`
+		HashStreaks     []HashStreak                `json:"hashStreaks"`
+		OpenMaintenance []string                    `json:"openMaintenance"`
+		Threads         map[string]int              `json:"threads,omitempty"`
+		Lease           *InstanceLease              `json:"lease,omitempty"`
+		KnownNodes      []string                    `json:"knownNodes,omitempty"`
+		ProbationNodes  map[string]time.Time        `json:"probationNodes,omitempty"`
+		SavedAt         time.Time                   `json:"savedAt"`
+
+		// ConfiguredCheckpoint is the checkpoint set in the config when the state was saved, to tell when it changed.
+		ConfiguredCheckpoint uint64 `json:"configuredCheckpoint,omitempty"`
+	}
+
+	OfflineNodeState struct {
+		ConsecutiveOfflineCount int       `json:"consecutiveOfflineCount"`
+		LastOfflineAlertTime    time.Time `json:"lastOfflineAlertTime"`
+		OfflineSince            time.Time `json:"offlineSince,omitempty"`
+	}
+
+	// StateStore persists the checker state. load returns nil if nothing has been saved yet.
+	StateStore interface {
+		load() (*CheckerState, error)
+		save(state *CheckerState) error
+	}
+
+	FileStateStore struct {
+		file string
+	}
+
+	// S3StateStore keeps the state in a single S3 object, signing requests with the credentials from the
+	// standard AWS environment variables, which are set automatically inside Lambda.
+	S3StateStore struct {
+		url    string
+		host   string
+		path   string
+		region string
+		client *http.Client
+	}
+)
+
+func (s *StateConfig) Validate() error {
+	switch s.Backend {
+	case "":
+		return nil
+	case FileStateBackend:
+		if s.File == "" {
+			return ErrEmptyStateFile
+		}
+	case S3StateBackend:
+		if s.Bucket == "" || s.Key == "" {
+			return ErrEmptyStateBucket
+		}
+
+		if s.getRegion() == "" {
+			return ErrEmptyStateRegion
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownStateBackend, s.Backend)
+	}
+
+	return nil
+}
+
+func (s *StateConfig) getRegion() string {
+	if s.Region != "" {
+		return s.Region
+	}
+
+	return os.Getenv("AWS_REGION")
+}
+
+func newStateStore(config StateConfig) StateStore {
+	switch config.Backend {
+	case FileStateBackend:
+		return &FileStateStore{file: config.File}
+	case S3StateBackend:
+		return NewS3StateStore(config)
+	default:
+		return nil
+	}
+}
+
+func (s *FileStateStore) load() (*CheckerState, error) {
+	content, err := os.ReadFile(s.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed reading state file '%s': %w", s.file, err)
+	}
+
+	state := &CheckerState{}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling state file '%s': %w", s.file, err)
+	}
+
+	return state, nil
+}
+
+// save writes the state to a temporary file first, so a crash never leaves a truncated state behind.
+func (s *FileStateStore) save(state *CheckerState) error {
+	content, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.file + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return fmt.Errorf("failed writing state file '%s': %w", tmp, err)
+	}
+
+	return os.Rename(tmp, s.file)
+}
+
+func NewS3StateStore(config StateConfig) *S3StateStore {
+	region := config.getRegion()
+	path := "/" + awsURIEncode(config.Key)
+
+	var url, host string
+	if config.Endpoint != "" {
+		// Custom endpoints (e.g. S3-compatible storage) are addressed path-style.
+		url = strings.TrimRight(config.Endpoint, "/")
+		path = "/" + awsURIEncode(config.Bucket) + path
+		host = strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://")
+	} else {
+		host = fmt.Sprintf("%s.s3.%s.amazonaws.com", config.Bucket, region)
+		url = "https://" + host
+	}
+
+	return &S3StateStore{
+		url:    url,
+		host:   host,
+		path:   path,
+		region: region,
+		client: &http.Client{Timeout: stateRequestTimeout},
+	}
+}
+
+func (s *S3StateStore) load() (*CheckerState, error) {
+	resp, err := s.do(http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed loading state from S3: status %d: %s", resp.StatusCode, body)
+	}
+
+	state := &CheckerState{}
+	if err := json.NewDecoder(resp.Body).Decode(state); err != nil {
+		return nil, fmt.Errorf("failed decoding state from S3: %w", err)
+	}
+
+	return state, nil
+}
+
+func (s *S3StateStore) save(state *CheckerState) error {
+	content, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(http.MethodPut, content)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed saving state to S3: status %d: %s", resp.StatusCode, body)
+	}
+
+	return nil
+}
+
+func (s *S3StateStore) do(method string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, s.url+s.path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	s.sign(req, body, time.Now().UTC())
+
+	return s.client.Do(req)
+}
+
+// sign adds an AWS Signature Version 4 authorization header to the request.
+func (s *S3StateStore) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Host = s.host
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+
+	headers := map[string]string{"host": s.host}
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") || lower == "content-type" {
+			headers[lower] = strings.TrimSpace(req.Header.Get(name))
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{req.Method, s.path, "", canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.region)
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+os.Getenv("AWS_SECRET_ACCESS_KEY")), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		os.Getenv("AWS_ACCESS_KEY_ID"), scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsURIEncode escapes everything but the unreserved characters, keeping the slashes of an object key.
+func awsURIEncode(path string) string {
+	var buf strings.Builder
+	for _, b := range []byte(path) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~', b == '/':
+			buf.WriteByte(b)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", b)
+		}
+	}
+
+	return buf.String()
+}
+
+func (fc *ForkChecker) exportState() *CheckerState {
+	am := fc.alertManager
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	state := &CheckerState{
+		Checkpoint:      fc.checkpoint,
+		LastAlertTimes:  make(map[string]time.Time, len(am.lastAlertTimes)),
+		LastStuckHeight: am.lastStuckHeight,
+		LastStuckTime:   am.lastStuckTime,
+		OfflineNodes:    make(map[string]OfflineNodeState, len(am.offlineNodeStats)),
+		OpenIncidents:   make(map[string]string, len(am.openIncidents)),
+		HashStreaks:     am.hashStreaks.all(),
+		OpenMaintenance: am.maintenance.openWindows(),
+		Lease:           fc.exportLease(time.Now().UTC()),
+		SavedAt:         time.Now().UTC(),
+	}
+	state.ConfiguredCheckpoint = fc.cfg.Checkpoint
+
+	state.KnownNodes, state.ProbationNodes = am.probation.export(am.nodeInfos, state.SavedAt)
+
+	for alertType, t := range am.lastAlertTimes {
+		state.LastAlertTimes[alertType.String()] = t
+	}
+
+	for key, status := range am.offlineNodeStats {
+		state.OfflineNodes[key] = OfflineNodeState{
+			ConsecutiveOfflineCount: status.consecutiveOfflineCount,
+			LastOfflineAlertTime:    status.lastOfflineAlertTime,
+			OfflineSince:            status.offlineSince,
+		}
+	}
+
+	for alertType, dedupKey := range am.openIncidents {
+		state.OpenIncidents[alertType.String()] = dedupKey
+	}
+
+	for alertType, messageID := range am.notifier.threads {
+		if state.Threads == nil {
+			state.Threads = make(map[string]int, len(am.notifier.threads))
+		}
+		state.Threads[alertType.String()] = messageID
+	}
+
+	return state
+}
+
+func (fc *ForkChecker) restoreState(state *CheckerState) {
+	am := fc.alertManager
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	// A checkpoint configured since the state was saved re-points the checker, otherwise the saved one is resumed.
+	switch {
+	case fc.cfg.Checkpoint != 0 && fc.cfg.Checkpoint != state.ConfiguredCheckpoint:
+		log.Printf("Configured checkpoint %d changed since the state was saved, overriding the saved checkpoint %d", fc.cfg.Checkpoint, state.Checkpoint)
+		fc.checkpoint = fc.cfg.Checkpoint
+	case state.Checkpoint != 0:
+		fc.checkpoint = state.Checkpoint
+	}
+
+	am.lastStuckHeight = state.LastStuckHeight
+	am.lastStuckTime = state.LastStuckTime
+	am.probation.restore(state.KnownNodes, state.ProbationNodes, am.nodeInfos, time.Now())
+
+	for name, t := range state.LastAlertTimes {
+		if alertType, err := parseAlertType(name); err == nil {
+			am.lastAlertTimes[alertType] = t
+		}
+	}
+
+	for key, node := range state.OfflineNodes {
+		am.offlineNodeStats[key] = NodeStatus{
+			consecutiveOfflineCount: node.ConsecutiveOfflineCount,
+			lastOfflineAlertTime:    node.LastOfflineAlertTime,
+			offlineSince:            node.OfflineSince,
+		}
+	}
+
+	for name, dedupKey := range state.OpenIncidents {
+		if alertType, err := parseAlertType(name); err == nil {
+			am.openIncidents[alertType] = dedupKey
+		}
+	}
+
+	// Threads are only restored if threading is still enabled.
+	if am.notifier.threads != nil {
+		for name, messageID := range state.Threads {
+			if alertType, err := parseAlertType(name); err == nil {
+				am.notifier.threads[alertType] = messageID
+			}
+		}
+	}
+
+	am.hashStreaks.restore(state.HashStreaks)
+	am.maintenance.restoreOpenWindows(state.OpenMaintenance)
+
+	log.Printf("Restored state saved at %s, checkpoint: %d", state.SavedAt.Format(time.RFC3339), fc.checkpoint)
+}
+
+func (fc *ForkChecker) initState() error {
+	fc.state = newStateStore(fc.cfg.StateConfig)
+	if fc.state == nil {
+		return nil
+	}
+
+	state, err := fc.state.load()
+	if err != nil {
+		return err
+	}
+
+	if state != nil {
+		fc.restoreState(state)
+		fc.gap = newCoverageGap(state, fc.checkpoint, fc.cfg.StateConfig.getGapReportThreshold(), time.Now())
+	}
+
+	return nil
+}
+
+func (fc *ForkChecker) saveState() error {
+	if fc.state == nil {
+		return nil
+	}
+
+	fc.stateMu.Lock()
+	defer fc.stateMu.Unlock()
+
+	if fc.released {
+		return nil
+	}
+
+	return fc.state.save(fc.exportState())
+}
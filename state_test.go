@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateConfigValidate(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+
+	require.NoError(t, (&StateConfig{}).Validate())
+	require.NoError(t, (&StateConfig{Backend: FileStateBackend, File: "state.json"}).Validate())
+	require.NoError(t, (&StateConfig{Backend: S3StateBackend, Bucket: "bucket", Key: "state.json", Region: "eu-west-1"}).Validate())
+
+	require.ErrorIs(t, (&StateConfig{Backend: "dynamodb"}).Validate(), ErrUnknownStateBackend)
+	require.ErrorIs(t, (&StateConfig{Backend: FileStateBackend}).Validate(), ErrEmptyStateFile)
+	require.ErrorIs(t, (&StateConfig{Backend: S3StateBackend, Bucket: "bucket"}).Validate(), ErrEmptyStateBucket)
+	require.ErrorIs(t, (&StateConfig{Backend: S3StateBackend, Bucket: "bucket", Key: "state.json"}).Validate(), ErrEmptyStateRegion)
+}
+
+func TestFileStateStore(t *testing.T) {
+	store := &FileStateStore{file: filepath.Join(t.TempDir(), "state.json")}
+
+	state, err := store.load()
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	require.NoError(t, store.save(&CheckerState{Checkpoint: 42}))
+
+	state, err = store.load()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), state.Checkpoint)
+}
+
+func TestS3StateStore(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "token")
+
+	var (
+		mu      sync.Mutex
+		objects = make(map[string][]byte)
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"), auth)
+		assert.Contains(t, auth, "/eu-west-1/s3/aws4_request")
+		assert.Contains(t, auth, "x-amz-security-token")
+		assert.Equal(t, "token", r.Header.Get("X-Amz-Security-Token"))
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodGet:
+			object, exists := objects[r.URL.Path]
+			if !exists {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(object)
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, sha256Hex(body), r.Header.Get("X-Amz-Content-Sha256"))
+			objects[r.URL.Path] = body
+		}
+	}))
+	defer server.Close()
+
+	store := NewS3StateStore(StateConfig{Backend: S3StateBackend, Bucket: "bucket", Key: "fork checker/state.json", Region: "eu-west-1", Endpoint: server.URL})
+
+	state, err := store.load()
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	require.NoError(t, store.save(&CheckerState{Checkpoint: 42}))
+	assert.Contains(t, objects, "/bucket/fork checker/state.json")
+
+	state, err = store.load()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), state.Checkpoint)
+}
+
+func TestExportRestoreState(t *testing.T) {
+	am := newIncidentTestAlertManager(t, nil)
+	am.hashStreaks = NewHashStreakTracker()
+	am.hashStreaks.update(10, map[string]sdk.Hash{"a": {1}, "b": {1}, "c": {2}}, newStreakTestNodes(t, "a", "b", "c"))
+	am.lastAlertTimes[SyncAlertType] = time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+	am.openIncidents[HashAlertType] = incidentKey("fork", 10)
+	am.offlineNodeStats["keyA"] = NodeStatus{consecutiveOfflineCount: 3, offlineSince: time.Date(2024, 9, 1, 1, 0, 0, 0, time.UTC)}
+	am.notifier.threads = map[AlertType]int{HashAlertType: 42}
+
+	maintenance, err := NewMaintenanceSchedule([]MaintenanceWindow{{Name: "upgrade", Start: "2024-09-01T02:00:00Z", End: "2024-09-01T03:00:00Z"}})
+	require.NoError(t, err)
+	maintenance.closedWindows(time.Date(2024, 9, 1, 2, 30, 0, 0, time.UTC))
+	am.maintenance = maintenance
+
+	fc := &ForkChecker{alertManager: am, checkpoint: 11}
+	saved := fc.exportState()
+
+	content, err := json.Marshal(saved)
+	require.NoError(t, err)
+	loaded := &CheckerState{}
+	require.NoError(t, json.Unmarshal(content, loaded))
+
+	restoredAm := newIncidentTestAlertManager(t, nil)
+	restoredAm.hashStreaks = NewHashStreakTracker()
+	restoredAm.notifier.threads = make(map[AlertType]int)
+	restoredAm.maintenance, err = NewMaintenanceSchedule([]MaintenanceWindow{{Name: "upgrade", Start: "2024-09-01T02:00:00Z", End: "2024-09-01T03:00:00Z"}})
+	require.NoError(t, err)
+
+	restored := &ForkChecker{alertManager: restoredAm, checkpoint: 5}
+	restored.restoreState(loaded)
+
+	assert.Equal(t, uint64(11), restored.checkpoint)
+	assert.Equal(t, am.lastAlertTimes[SyncAlertType], restoredAm.lastAlertTimes[SyncAlertType])
+	assert.Equal(t, am.openIncidents, restoredAm.openIncidents)
+	assert.Equal(t, 3, restoredAm.offlineNodeStats["keyA"].consecutiveOfflineCount)
+	assert.Equal(t, am.offlineNodeStats["keyA"].offlineSince, restoredAm.offlineNodeStats["keyA"].offlineSince)
+	assert.Equal(t, am.notifier.threads, restoredAm.notifier.threads)
+	assert.Len(t, restoredAm.hashStreaks.all(), 3)
+	assert.Equal(t, []string{"upgrade"}, restoredAm.maintenance.openWindows())
+
+	// The restored open window still produces a summary when it closes.
+	assert.Len(t, restoredAm.maintenance.closedWindows(time.Date(2024, 9, 1, 4, 0, 0, 0, time.UTC)), 1)
+
+	t.Run("Configured checkpoint", func(t *testing.T) {
+		fc := &ForkChecker{alertManager: newIncidentTestAlertManager(t, nil), cfg: Config{Checkpoint: 100}, checkpoint: 150}
+		fc.alertManager.hashStreaks = NewHashStreakTracker()
+		saved := fc.exportState()
+		assert.Equal(t, uint64(100), saved.ConfiguredCheckpoint)
+
+		// An unchanged configured checkpoint resumes from the saved one, e.g. in every scheduled run.
+		fc.checkpoint = 100
+		fc.restoreState(saved)
+		assert.Equal(t, uint64(150), fc.checkpoint)
+
+		// A checkpoint configured since the state was saved re-points the checker.
+		fc.cfg.Checkpoint = 500
+		fc.checkpoint = 500
+		fc.restoreState(saved)
+		assert.Equal(t, uint64(500), fc.checkpoint)
+
+		// So does one configured where there was none.
+		fc.restoreState(&CheckerState{Checkpoint: 150})
+		assert.Equal(t, uint64(500), fc.checkpoint)
+	})
+}
+
+func TestRunLambda(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		served    int
+		responses = make(map[string]string)
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if strings.HasSuffix(r.URL.Path, "/next") {
+			served++
+			if served > 2 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Lambda-Runtime-Aws-Request-Id", []string{"", "req-1", "req-2"}[served])
+			w.Header().Set("Lambda-Runtime-Deadline-Ms", "32503680000000")
+			w.Write([]byte("{}"))
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		responses[strings.TrimPrefix(r.URL.Path, "/2018-06-01/runtime/invocation/")] = string(body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	invocations := 0
+	handler := func(ctx context.Context) (interface{}, error) {
+		_, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline)
+
+		invocations++
+		if invocations == 2 {
+			return nil, errors.New("state unavailable")
+		}
+
+		return map[string]uint64{"checkpoint": 11}, nil
+	}
+
+	err := runLambda(strings.TrimPrefix(server.URL, "http://"), handler)
+	require.Error(t, err)
+
+	assert.Equal(t, 2, invocations)
+	assert.JSONEq(t, `{"checkpoint": 11}`, responses["req-1/response"])
+	assert.JSONEq(t, `{"errorMessage": "state unavailable", "errorType": "CheckError"}`, responses["req-2/error"])
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+)
+
+const RecentStreakBreakWindow = 24 * time.Hour
+
+type (
+	StatusNode struct {
+		Name        string `json:"name"`
+		Endpoint    string `json:"endpoint"`
+		IdentityKey string `json:"identityKey"`
+		Height      uint64 `json:"height,omitempty"`
+		Version     string `json:"version,omitempty"`
+	}
+
+	// Status is a snapshot of the checker's view of the network, published after every check cycle.
+	Status struct {
+		UpdatedAt             time.Time    `json:"updatedAt"`
+		Checkpoint            uint64       `json:"checkpoint"`
+		OfflineNodes          []StatusNode `json:"offlineNodes"`
+		OutOfSyncNodes        []StatusNode `json:"outOfSyncNodes"`
+		OpenIncidents         []string     `json:"openIncidents"`
+		RecentlyBrokenStreaks []HashStreak `json:"recentlyBrokenStreaks"`
+		HashStreaks           []HashStreak `json:"hashStreaks"`
+
+		ApiGateways   []ApiGatewayStatus  `json:"apiGateways,omitempty"`
+		IsolatedNodes []IsolatedNode      `json:"isolatedNodes,omitempty"`
+		IncidentMode  *IncidentModeStatus `json:"incidentMode,omitempty"`
+		Nodes         []StatusNode        `json:"nodes,omitempty"`
+		DegradedLinks []DegradedLink      `json:"degradedLinks,omitempty"`
+		Paused        *PauseStatus        `json:"paused,omitempty"`
+		Scores        []NodeScore         `json:"scores,omitempty"`
+		// Standby is set while another checker instance holds the lease.
+		Standby bool `json:"standby,omitempty"`
+	}
+
+	StatusServer struct {
+		mu     sync.RWMutex
+		status Status
+
+		// alertManager and apiToken serve the pause and resume endpoints, which are disabled without a token.
+		alertManager *AlertManager
+		apiToken     string
+
+		// nodeInfos are the configured nodes, reported in the metrics even while healthy.
+		nodeInfos []*health.NodeInfo
+	}
+)
+
+func newStatusNode(node health.NodeInfo, height uint64, version string) StatusNode {
+	return StatusNode{
+		Name:        nodeName(node),
+		Endpoint:    node.Endpoint,
+		IdentityKey: node.IdentityKey.String(),
+		Height:      height,
+		Version:     version,
+	}
+}
+
+func sortStatusNodes(nodes []StatusNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+}
+
+// buildStatus collects the state of the last check cycle.
+func (fc *ForkChecker) buildStatus() Status {
+	am := fc.alertManager
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	status := Status{
+		UpdatedAt:             time.Now().UTC(),
+		Checkpoint:            fc.checkpoint,
+		OfflineNodes:          []StatusNode{},
+		OutOfSyncNodes:        []StatusNode{},
+		OpenIncidents:         []string{},
+		RecentlyBrokenStreaks: am.hashStreaks.brokenSince(time.Now().Add(-RecentStreakBreakWindow)),
+		HashStreaks:           am.hashStreaks.all(),
+		ApiGateways:           fc.gateways.snapshot(),
+		IsolatedNodes:         fc.peerLists.snapshot(),
+		IncidentMode:          am.incidentMode.snapshot(),
+		Standby:               am.standby.Load(),
+		DegradedLinks:         am.links.degraded(am.nodeInfos),
+		Paused:                am.pause.snapshot(time.Now()),
+		Scores:                fc.scores.scores(),
+	}
+
+	for _, node := range am.lastFailedConnections {
+		status.OfflineNodes = append(status.OfflineNodes, newStatusNode(*node, 0, am.identities.version(*node)))
+	}
+	sortStatusNodes(status.OfflineNodes)
+
+	for node, height := range am.lastNotReached {
+		status.OutOfSyncNodes = append(status.OutOfSyncNodes, newStatusNode(node, height, am.identities.version(node)))
+	}
+	sortStatusNodes(status.OutOfSyncNodes)
+
+	for _, info := range am.nodeInfos {
+		if version := am.identities.version(*info); version != "" {
+			status.Nodes = append(status.Nodes, newStatusNode(*info, 0, version))
+		}
+	}
+	sortStatusNodes(status.Nodes)
+
+	for _, key := range am.openIncidents {
+		status.OpenIncidents = append(status.OpenIncidents, key)
+	}
+	sort.Strings(status.OpenIncidents)
+
+	return status
+}
+
+func (s *StatusServer) update(status Status) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+func (s *StatusServer) get() Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.status
+}
+
+func (s *StatusServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.get()); err != nil {
+		log.Printf("failed writing status response: %v", err)
+	}
+}
+
+func (s *StatusServer) listen(address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/status", s)
+	mux.HandleFunc("/scores", s.serveScores)
+	mux.HandleFunc("/metrics", s.serveMetrics)
+
+	if s.apiToken != "" {
+		mux.HandleFunc("/pause", s.servePause)
+		mux.HandleFunc("/resume", s.serveResume)
+	}
+
+	log.Printf("Serving status on %s/status", address)
+	if err := http.ListenAndServe(address, mux); err != nil {
+		log.Printf("status server stopped: %v", err)
+	}
+}
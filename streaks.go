@@ -0,0 +1,156 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+)
+
+type (
+	// HashStreak counts the consecutive checkpoints at which a node agreed with the majority hash.
+	HashStreak struct {
+		IdentityKey string `json:"identityKey"`
+		Endpoint    string `json:"endpoint"`
+		Current     uint64 `json:"current"`
+		Longest     uint64 `json:"longest"`
+
+		// Set when the streak was last broken.
+		BrokenLength uint64     `json:"brokenLength,omitempty"`
+		BrokenHeight uint64     `json:"brokenHeight,omitempty"`
+		BrokenTime   *time.Time `json:"brokenTime,omitempty"`
+	}
+
+	// HashStreakTracker keeps the streaks of the configured and connected nodes by identity key, so that a node
+	// keeps its streak when its endpoint changes and one that is gone does not linger in the state and metrics.
+	HashStreakTracker struct {
+		streaks map[string]*HashStreak
+	}
+)
+
+func NewHashStreakTracker() *HashStreakTracker {
+	return &HashStreakTracker{streaks: make(map[string]*HashStreak)}
+}
+
+// majorityHash returns the hash reported by most nodes, or false if there is a tie for first place.
+func majorityHash(hashes map[string]sdk.Hash) (sdk.Hash, bool) {
+	counts := make(map[sdk.Hash]int)
+	for _, hash := range hashes {
+		counts[hash]++
+	}
+
+	var majority sdk.Hash
+	best, tie := 0, false
+	for hash, count := range counts {
+		if count > best {
+			majority, best, tie = hash, count, false
+		} else if count == best {
+			tie = true
+		}
+	}
+
+	return majority, best > 0 && !tie
+}
+
+// update records the hashes reported at a checkpoint by the given nodes and drops the streaks of the other ones.
+// Nodes that did not report a hash keep their streak.
+func (t *HashStreakTracker) update(height uint64, hashes map[string]sdk.Hash, nodes []*health.NodeInfo) {
+	tracked := make(map[string]*health.NodeInfo, len(nodes))
+	for _, node := range nodes {
+		tracked[node.IdentityKey.String()] = node
+	}
+
+	for key := range t.streaks {
+		if _, exists := tracked[key]; !exists {
+			delete(t.streaks, key)
+		}
+	}
+
+	majority, ok := majorityHash(hashes)
+	if !ok {
+		return
+	}
+
+	for key, node := range tracked {
+		hash, reported := hashes[node.Endpoint]
+		if !reported {
+			continue
+		}
+
+		streak, exists := t.streaks[key]
+		if !exists {
+			streak = &HashStreak{IdentityKey: key}
+			t.streaks[key] = streak
+		}
+		streak.Endpoint = node.Endpoint
+
+		if hash != majority {
+			streak.BrokenLength = streak.Current
+			streak.BrokenHeight = height
+			now := time.Now()
+			streak.BrokenTime = &now
+			streak.Current = 0
+			continue
+		}
+
+		streak.Current++
+		if streak.Current > streak.Longest {
+			streak.Longest = streak.Current
+		}
+	}
+}
+
+func (t *HashStreakTracker) all() []HashStreak {
+	result := make([]HashStreak, 0, len(t.streaks))
+	for _, streak := range t.streaks {
+		result = append(result, *streak)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Endpoint < result[j].Endpoint
+	})
+
+	return result
+}
+
+// restore replaces the tracked streaks with previously saved ones. Streaks saved without an identity key, before
+// they were keyed by it, are dropped.
+func (t *HashStreakTracker) restore(streaks []HashStreak) {
+	t.streaks = make(map[string]*HashStreak, len(streaks))
+	for i := range streaks {
+		streak := streaks[i]
+		if streak.IdentityKey != "" {
+			t.streaks[streak.IdentityKey] = &streak
+		}
+	}
+}
+
+// brokenSince returns the streaks that were broken after the given time, most recent first.
+func (t *HashStreakTracker) brokenSince(since time.Time) []HashStreak {
+	var result []HashStreak
+	for _, streak := range t.streaks {
+		if streak.BrokenTime != nil && streak.BrokenTime.After(since) {
+			result = append(result, *streak)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].BrokenTime.After(*result[j].BrokenTime)
+	})
+
+	return result
+}
+
+// observeHashes records the hashes compared at a checkpoint for the streaks of the configured nodes and the
+// connected discovered ones.
+func (am *AlertManager) observeHashes(height uint64, hashes map[string]sdk.Hash, discovered []*health.NodeInfo) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	nodes := make([]*health.NodeInfo, 0, len(am.nodeInfos)+len(discovered))
+	nodes = append(nodes, am.nodeInfos...)
+	nodes = append(nodes, discovered...)
+
+	am.hashStreaks.update(height, hashes, nodes)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	crypto "github.com/proximax-storage/go-xpx-crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMajorityHash(t *testing.T) {
+	t.Run("Majority", func(t *testing.T) {
+		hash, ok := majorityHash(map[string]sdk.Hash{"a": {1}, "b": {1}, "c": {2}})
+		assert.True(t, ok)
+		assert.Equal(t, sdk.Hash{1}, hash)
+	})
+
+	t.Run("Tie", func(t *testing.T) {
+		_, ok := majorityHash(map[string]sdk.Hash{"a": {1}, "b": {2}})
+		assert.False(t, ok)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		_, ok := majorityHash(nil)
+		assert.False(t, ok)
+	})
+}
+
+// newStreakTestNodes returns nodes with random identity keys at the given endpoints.
+func newStreakTestNodes(t *testing.T, endpoints ...string) []*health.NodeInfo {
+	nodes := make([]*health.NodeInfo, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		keyPair, err := crypto.NewRandomKeyPair()
+		require.NoError(t, err)
+		nodes = append(nodes, &health.NodeInfo{IdentityKey: keyPair.PublicKey, Endpoint: endpoint})
+	}
+
+	return nodes
+}
+
+func TestHashStreakTracker(t *testing.T) {
+	tracker := NewHashStreakTracker()
+	nodes := newStreakTestNodes(t, "a", "b", "c")
+
+	for height := uint64(1); height <= 3; height++ {
+		tracker.update(height, map[string]sdk.Hash{"a": {1}, "b": {1}, "c": {1}}, nodes)
+	}
+
+	tracker.update(4, map[string]sdk.Hash{"a": {1}, "b": {1}, "c": {2}}, nodes)
+	tracker.update(5, map[string]sdk.Hash{"a": {1}, "b": {1}, "c": {1}}, nodes)
+
+	// A tie gives no majority, so no streak changes.
+	tracker.update(6, map[string]sdk.Hash{"a": {1}, "c": {2}}, nodes)
+
+	streaks := tracker.all()
+	require.Len(t, streaks, 3)
+
+	assert.Equal(t, "a", streaks[0].Endpoint)
+	assert.Equal(t, uint64(5), streaks[0].Current)
+	assert.Equal(t, uint64(5), streaks[0].Longest)
+
+	assert.Equal(t, "c", streaks[2].Endpoint)
+	assert.Equal(t, uint64(1), streaks[2].Current)
+	assert.Equal(t, uint64(3), streaks[2].Longest)
+	assert.Equal(t, uint64(3), streaks[2].BrokenLength)
+	assert.Equal(t, uint64(4), streaks[2].BrokenHeight)
+
+	broken := tracker.brokenSince(time.Now().Add(-time.Minute))
+	require.Len(t, broken, 1)
+	assert.Equal(t, "c", broken[0].Endpoint)
+
+	assert.Empty(t, tracker.brokenSince(time.Now().Add(time.Minute)))
+
+	// A streak that was never broken has no broken time in the status or the state.
+	data, err := json.Marshal(streaks[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "brokenTime")
+
+	data, err = json.Marshal(streaks[2])
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "brokenTime")
+
+	t.Run("Identity", func(t *testing.T) {
+		tracker := NewHashStreakTracker()
+		nodes := newStreakTestNodes(t, "a", "b", "c")
+
+		tracker.update(1, map[string]sdk.Hash{"a": {1}, "b": {1}, "c": {1}}, nodes)
+
+		// A node that moved to another endpoint keeps its streak.
+		nodes[2].Endpoint = "d"
+		tracker.update(2, map[string]sdk.Hash{"a": {1}, "b": {1}, "d": {1}}, nodes)
+
+		streaks := tracker.all()
+		require.Len(t, streaks, 3)
+		assert.Equal(t, "d", streaks[2].Endpoint)
+		assert.Equal(t, nodes[2].IdentityKey.String(), streaks[2].IdentityKey)
+		assert.Equal(t, uint64(2), streaks[2].Current)
+
+		// A node that is neither configured nor connected any more is dropped, even without a majority.
+		tracker.update(3, nil, nodes[:2])
+		assert.Len(t, tracker.all(), 2)
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		tracker := NewHashStreakTracker()
+		tracker.restore([]HashStreak{{IdentityKey: "key", Endpoint: "a", Current: 3}, {Endpoint: "b", Current: 2}})
+
+		// Streaks saved by endpoint only cannot be matched to a node.
+		streaks := tracker.all()
+		require.Len(t, streaks, 1)
+		assert.Equal(t, "key", streaks[0].IdentityKey)
+	})
+}
+
+func TestStatusServer(t *testing.T) {
+	am := newIncidentTestAlertManager(t, nil)
+	am.hashStreaks = NewHashStreakTracker()
+	am.hashStreaks.update(10, map[string]sdk.Hash{"a": {1}, "b": {1}, "c": {2}}, newStreakTestNodes(t, "a", "b", "c"))
+	am.openIncidents[HashAlertType] = incidentKey("fork", 10)
+
+	fc := &ForkChecker{alertManager: am, checkpoint: 11, status: &StatusServer{}}
+	fc.status.update(fc.buildStatus())
+
+	recorder := httptest.NewRecorder()
+	fc.status.ServeHTTP(recorder, httptest.NewRequest("GET", "/status", nil))
+
+	var status Status
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&status))
+	assert.Equal(t, uint64(11), status.Checkpoint)
+	assert.Equal(t, []string{incidentKey("fork", 10)}, status.OpenIncidents)
+	assert.Len(t, status.HashStreaks, 3)
+	require.Len(t, status.RecentlyBrokenStreaks, 1)
+	assert.Equal(t, "c", status.RecentlyBrokenStreaks[0].Endpoint)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"go-xpx-check-fork-util/internal/table"
+)
+
+type (
+	// NodeHeight is a node with its reported height, as exposed to message templates.
+	NodeHeight struct {
+		Name     string
+		Endpoint string
+		Height   uint64
+	}
+
+	// HashGroup is a block hash with the endpoints that reported it, as exposed to message templates.
+	HashGroup struct {
+		Hash      string
+		Endpoints []string
+	}
+)
+
+var templateFuncs = template.FuncMap{
+	"nodeName":     nodeName,
+	"sortedNodes":  sortedNodes,
+	"offlineNodes": offlineNodes,
+	"hashGroups":   hashGroups,
+	"padRight":     table.PadRight,
+	"heightOffset": heightOffset,
+	"padLeft":      table.PadLeft,
+	"escape":       table.Escape,
+	"join":         strings.Join,
+}
+
+// loadTemplates parses the message template files configured per alert type.
+func loadTemplates(files map[string]string) (map[AlertType]*template.Template, error) {
+	templates := make(map[AlertType]*template.Template, len(files))
+
+	for name, file := range files {
+		alertType, err := parseAlertType(name)
+		if err != nil {
+			return nil, err
+		}
+
+		tmpl, err := template.New(filepath.Base(file)).Funcs(templateFuncs).ParseFiles(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing %s alert template '%s': %v", name, file, err)
+		}
+
+		templates[alertType] = tmpl
+	}
+
+	return templates, nil
+}
+
+func renderTemplate(tmpl *template.Template, alert Alert) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, alert); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// nodeName formats a node as "friendlyName(host)", or just the host if the node has no distinct friendly name.
+func nodeName(node health.NodeInfo) string {
+	return table.NodeName(node.FriendlyName, node.Endpoint)
+}
+
+func sortedNodes(nodes map[health.NodeInfo]uint64) []NodeHeight {
+	result := make([]NodeHeight, 0, len(nodes))
+	for node, height := range nodes {
+		result = append(result, NodeHeight{
+			Name:     nodeName(node),
+			Endpoint: node.Endpoint,
+			Height:   height,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+func offlineNodes(nodes map[string]*health.NodeInfo) []string {
+	result := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		result = append(result, nodeName(*node))
+	}
+
+	sort.Strings(result)
+	return result
+}
+
+func hashGroups(hashes map[string]sdk.Hash) []HashGroup {
+	grouped := make(map[sdk.Hash][]string)
+	for endpoint, hash := range hashes {
+		grouped[hash] = append(grouped[hash], endpoint)
+	}
+
+	result := make([]HashGroup, 0, len(grouped))
+	for hash, endpoints := range grouped {
+		sort.Strings(endpoints)
+		result = append(result, HashGroup{Hash: hash.String(), Endpoints: endpoints})
+	}
+
+	// Largest group first, so the majority hash leads the message.
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i].Endpoints) != len(result[j].Endpoints) {
+			return len(result[i].Endpoints) > len(result[j].Endpoints)
+		}
+		return result[i].Hash < result[j].Hash
+	})
+
+	return result
+}
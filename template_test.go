@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTemplates(t *testing.T) {
+	t.Run("Example templates", func(t *testing.T) {
+		templates, err := loadTemplates(map[string]string{
+			"sync":    "templates/sync.tmpl",
+			"hash":    "templates/hash.tmpl",
+			"offline": "templates/offline.tmpl",
+		})
+		require.NoError(t, err)
+		assert.Len(t, templates, 3)
+	})
+
+	t.Run("Unknown alert type", func(t *testing.T) {
+		_, err := loadTemplates(map[string]string{"unknown": "templates/sync.tmpl"})
+		require.ErrorIs(t, err, ErrUnknownAlertType)
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		_, err := loadTemplates(map[string]string{"sync": "templates/missing.tmpl"})
+		require.Error(t, err)
+	})
+}
+
+func TestAlertManagerCreateMessage(t *testing.T) {
+	nodeA := health.NodeInfo{Endpoint: "127.0.0.1:7900", FriendlyName: "nodeA"}
+	nodeB := health.NodeInfo{Endpoint: "node-b.example.com:7900"}
+
+	syncAlert := SyncAlert{
+		Height:     1000,
+		Reached:    map[health.NodeInfo]uint64{nodeA: 1000},
+		NotReached: map[health.NodeInfo]uint64{nodeB: 990},
+	}
+
+	t.Run("Default message without template", func(t *testing.T) {
+		am := &AlertManager{}
+		assert.Equal(t, syncAlert.createMessage(), am.createMessage(syncAlert))
+	})
+
+	t.Run("Custom template", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "sync.tmpl")
+		content := `Height {{ .Height }}:{{ range sortedNodes .NotReached }} {{ .Name }}={{ .Height }}{{ end }}`
+		require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+
+		templates, err := loadTemplates(map[string]string{"sync": file})
+		require.NoError(t, err)
+
+		am := &AlertManager{templates: templates}
+		assert.Equal(t, "Height 1000: node-b=990", am.createMessage(syncAlert))
+	})
+
+	t.Run("Chain height", func(t *testing.T) {
+		alert := syncAlert
+		alert.ChainHeight = 1010
+		line := "Chain height: <b>1010</b> (10 ahead of the checkpoint)"
+		assert.Contains(t, alert.createMessage(), line)
+
+		templates, err := loadTemplates(map[string]string{"sync": "templates/sync.tmpl", "hash": "templates/hash.tmpl"})
+		require.NoError(t, err)
+		am := &AlertManager{templates: templates}
+		assert.Contains(t, am.createMessage(alert), line)
+
+		hashAlert := HashAlert{Height: 1000, ChainHeight: 1010, Hashes: map[string]sdk.Hash{"127.0.0.1:7900": {}}}
+		assert.Contains(t, hashAlert.createMessage(), line)
+		assert.Contains(t, am.createMessage(hashAlert), line)
+
+		// A stuck chain stops below the checkpoint.
+		stuck := SyncAlert{Height: 1000, ChainHeight: 998, NotReached: map[health.NodeInfo]uint64{nodeB: 998}}
+		assert.Contains(t, stuck.createMessage(), "Chain height: <b>998</b> (2 behind the checkpoint)")
+
+		// Without a known chain height, the line is left out.
+		assert.NotContains(t, syncAlert.createMessage(), "Chain height")
+		assert.NotContains(t, am.createMessage(syncAlert), "Chain height")
+	})
+
+	t.Run("Fall back on execution error", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "hash.tmpl")
+		require.NoError(t, os.WriteFile(file, []byte(`{{ .Missing }}`), 0644))
+
+		templates, err := loadTemplates(map[string]string{"hash": file})
+		require.NoError(t, err)
+
+		hashAlert := HashAlert{Height: 10, Hashes: map[string]sdk.Hash{"127.0.0.1:7900": {}}}
+		am := &AlertManager{templates: templates}
+		assert.Equal(t, hashAlert.createMessage(), am.createMessage(hashAlert))
+	})
+}
+
+func TestHashGroups(t *testing.T) {
+	majority := sdk.Hash{1}
+	minority := sdk.Hash{2}
+
+	groups := hashGroups(map[string]sdk.Hash{
+		"c:7900": majority,
+		"a:7900": majority,
+		"b:7900": minority,
+	})
+
+	require.Len(t, groups, 2)
+	assert.Equal(t, majority.String(), groups[0].Hash)
+	assert.Equal(t, []string{"a:7900", "c:7900"}, groups[0].Endpoints)
+	assert.Equal(t, []string{"b:7900"}, groups[1].Endpoints)
+}
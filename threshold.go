@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidNodeThreshold = errors.New("invalid node threshold")
+
+// NodeThreshold is a number of nodes, given in the config either as a count (5) or as a percentage of the monitored
+// nodes ("25%"), so that a config scales when nodes are added or removed.
+type NodeThreshold struct {
+	count   int
+	percent float64
+}
+
+func parseNodeThreshold(value string) (NodeThreshold, error) {
+	value = strings.TrimSpace(value)
+
+	if number, isPercent := strings.CutSuffix(value, "%"); isPercent {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return NodeThreshold{}, fmt.Errorf("%w: %s is not a percentage above 0 and up to 100", ErrInvalidNodeThreshold, value)
+		}
+		return NodeThreshold{percent: percent}, nil
+	}
+
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		return NodeThreshold{}, fmt.Errorf("%w: %s is neither a node count nor a percentage", ErrInvalidNodeThreshold, value)
+	}
+
+	return NodeThreshold{count: count}, nil
+}
+
+func (t *NodeThreshold) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		value = string(data)
+	}
+
+	threshold, err := parseNodeThreshold(value)
+	if err != nil {
+		return err
+	}
+
+	*t = threshold
+	return nil
+}
+
+func (t NodeThreshold) MarshalJSON() ([]byte, error) {
+	if t.percent > 0 {
+		return json.Marshal(t.String())
+	}
+	return json.Marshal(t.count)
+}
+
+func (t NodeThreshold) String() string {
+	if t.percent > 0 {
+		return strconv.FormatFloat(t.percent, 'f', -1, 64) + "%"
+	}
+	return strconv.Itoa(t.count)
+}
+
+func (t NodeThreshold) isZero() bool {
+	return t.count == 0 && t.percent == 0
+}
+
+// nodes resolves the threshold against the number of monitored nodes. A percentage is rounded up, so "25%" of 6
+// nodes is 2, and is at least 1 node.
+func (t NodeThreshold) nodes(total int) int {
+	if t.percent == 0 {
+		return t.count
+	}
+
+	nodes := int(math.Ceil(t.percent * float64(total) / 100))
+	if nodes < 1 {
+		return 1
+	}
+	return nodes
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeThreshold(t *testing.T) {
+	t.Run("Parse", func(t *testing.T) {
+		tests := []struct {
+			json  string
+			nodes int
+		}{
+			{`5`, 5},
+			{`"5"`, 5},
+			{`0`, 0},
+			{`"25%"`, 2},
+			{`"33.4%"`, 3},
+			{`" 100 % "`, 8},
+			{`"1%"`, 1},
+		}
+
+		for _, test := range tests {
+			var threshold NodeThreshold
+			require.NoError(t, json.Unmarshal([]byte(test.json), &threshold), test.json)
+			assert.Equal(t, test.nodes, threshold.nodes(8), test.json)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, value := range []string{`-1`, `"0%"`, `"101%"`, `"many"`, `1.5`, `true`} {
+			var threshold NodeThreshold
+			require.ErrorIs(t, json.Unmarshal([]byte(value), &threshold), ErrInvalidNodeThreshold, value)
+		}
+	})
+
+	t.Run("Marshal", func(t *testing.T) {
+		for _, value := range []string{`5`, `"12.5%"`} {
+			var threshold NodeThreshold
+			require.NoError(t, json.Unmarshal([]byte(value), &threshold))
+
+			content, err := json.Marshal(threshold)
+			require.NoError(t, err)
+			assert.Equal(t, value, string(content))
+		}
+	})
+}
+
+func TestPercentageThresholds(t *testing.T) {
+	t.Run("Out of sync", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		am.config.OutOfSyncCriticalNodesThreshold = NodeThreshold{percent: 50}
+
+		// 3 of the 6 nodes are far behind.
+		notReached := map[health.NodeInfo]uint64{}
+		reached := map[health.NodeInfo]uint64{}
+		for i, info := range am.nodeInfos {
+			if i < 2 {
+				notReached[*info] = 900
+			} else {
+				reached[*info] = 1000
+			}
+		}
+		assert.False(t, am.shouldSendSyncAlert(1000, notReached, reached))
+
+		notReached[*am.nodeInfos[2]] = 900
+		assert.True(t, am.shouldSendSyncAlert(1000, notReached, reached))
+	})
+
+	t.Run("Offline", func(t *testing.T) {
+		am := newIncidentTestAlertManager(t, nil)
+		am.timedOffline = true
+		am.config.OfflineCriticalNodesThreshold = NodeThreshold{percent: 25}
+
+		failed := map[string]*health.NodeInfo{}
+		offline := func(info *health.NodeInfo) {
+			failed[info.IdentityKey.String()] = info
+			am.handlePingFailures(failed, time.Now().Add(-time.Hour))
+		}
+
+		// 25% of 6 nodes rounds up to 2.
+		offline(am.nodeInfos[0])
+		assert.False(t, am.shouldSendOfflineAlert(failed))
+
+		offline(am.nodeInfos[1])
+		assert.True(t, am.shouldSendOfflineAlert(failed))
+	})
+
+	t.Run("Rules follow the node count", func(t *testing.T) {
+		config, err := LoadConfig("sample.config.json")
+		require.NoError(t, err)
+		config.AlertConfig.OutOfSyncCriticalNodesThreshold = NodeThreshold{percent: 25}
+		config.AlertConfig.OfflineCriticalNodesThreshold = NodeThreshold{count: 2}
+
+		rules := string(generateRules(config, "checker"))
+		assert.Contains(t, rules, `count(forkchecker_node_lag_blocks{job=\"checker\"} >= 5) >= 25 * count(forkchecker_node_lag_blocks{job=\"checker\"}) / 100`)
+		assert.Contains(t, rules, `count(forkchecker_node_offline{job=\"checker\"} == 1) >= 2`)
+	})
+}
@@ -1,38 +1,9 @@
 package main
 
 import (
-	"net"
-	"strings"
-
 	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
 )
 
-func insertSpaceIfExceedsLength(input string, maxLength int) string {
-	if len(input) > maxLength {
-		return input[:maxLength] + " " + input[maxLength:]
-	}
-	return input
-}
-
-// Checks if the input is a DNS name and abbreviates it if so.
-func abbreviateIfDNSName(address string) string {
-	host, _, err := net.SplitHostPort(address)
-	if err != nil {
-		host = address
-	}
-
-	if ip := net.ParseIP(host); ip != nil {
-		return host
-	}
-
-	parts := strings.Split(host, ".")
-	if len(parts) > 0 {
-		return parts[0]
-	}
-
-	return address
-}
-
 func parseNodes(nodes []Node) ([]*health.NodeInfo, error) {
 	nodeInfos := make([]*health.NodeInfo, 0, len(nodes))
 
@@ -47,3 +18,17 @@ func parseNodes(nodes []Node) ([]*health.NodeInfo, error) {
 
 	return nodeInfos, nil
 }
+
+// maxHeight returns the highest height reported by any node.
+func maxHeight(nodeHeights ...map[health.NodeInfo]uint64) uint64 {
+	var highest uint64
+	for _, heights := range nodeHeights {
+		for _, height := range heights {
+			if height > highest {
+				highest = height
+			}
+		}
+	}
+
+	return highest
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"runtime/pprof"
+	"time"
+)
+
+// watchdogGracePeriod is the time a cancelled cycle gets to return once its connections are closed.
+var watchdogGracePeriod = 10 * time.Second
+
+const (
+	DefaultWatchdogMultiplier = 3
+
+	// watchdogAlertBudget is the time allowed for the alerts sent during a cycle, which block on Telegram and the pager.
+	watchdogAlertBudget = 30 * time.Second
+)
+
+// WatchdogAlert reports a check cycle that the watchdog cancelled. Recovered is false if the cycle did not return
+// after its connections were closed, i.e. it is most likely deadlocked rather than waiting on a node.
+type WatchdogAlert struct {
+	Checkpoint uint64
+	Timeout    time.Duration
+	Recovered  bool
+}
+
+func (w *WatchdogConfig) getMultiplier() float64 {
+	if w.Multiplier <= 0 {
+		return DefaultWatchdogMultiplier
+	}
+	return w.Multiplier
+}
+
+// getTimeout returns the time after which a check cycle over the nodes is restarted, or 0 if the watchdog is
+// disabled. Unless configured, it is a multiple of the expected duration of a cycle: every batch of concurrent
+// requests may take a full timeout to connect and discover peers, query the identities and links, and poll the
+// heights, followed by the hash deadline and the alerts.
+func (w *WatchdogConfig) getTimeout(pool PoolConfig, nodes int) time.Duration {
+	if w.Disabled {
+		return 0
+	}
+
+	if timeout := parseOptionalDuration(w.Timeout, "watchdog timeout", 0); timeout > 0 {
+		return timeout
+	}
+
+	batches := time.Duration((nodes + pool.getConcurrency() - 1) / pool.getConcurrency())
+	if batches == 0 {
+		batches = 1
+	}
+
+	request := pool.getRequestTimeout()
+	expected := batches*(pool.getConnectTimeout()+3*request) + // dial, handshake and peer list
+		batches*2*request + // identities and link probes
+		pool.getHeightWaitTimeout() + batches*request + // last height poll
+		pool.getHashDeadline() +
+		watchdogAlertBudget
+
+	return time.Duration(float64(expected) * w.getMultiplier())
+}
+
+// watchdogNodes is the number of nodes a cycle is expected to query: the configured ones, or as many as were
+// connected in the last cycle or may be connected with discovery, if more.
+func (fc *ForkChecker) watchdogNodes() int {
+	nodes := len(fc.alertManager.nodeInfos)
+	if connected := len(fc.nodePool.connections()); connected > nodes {
+		nodes = connected
+	}
+
+	if fc.cfg.Discover && fc.cfg.PoolConfig.MaxConnections > nodes {
+		nodes = fc.cfg.PoolConfig.MaxConnections
+	}
+
+	return nodes
+}
+
+// runCycle runs a check cycle under the watchdog. A cycle that overruns the watchdog timeout has its context
+// cancelled and the pool connections closed, so that requests blocked on a node fail; the next cycle dials the nodes
+// again. A cycle that still does not return cannot be stopped from the outside; it is reported as stuck and waited
+// for, as starting another cycle next to it would race on the checkpoint.
+func (fc *ForkChecker) runCycle() {
+	timeout := fc.cfg.WatchdogConfig.getTimeout(fc.cfg.PoolConfig, fc.watchdogNodes())
+	if timeout == 0 {
+		fc.checkCycle(context.Background())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	checkpoint := fc.checkpoint
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fc.checkCycle(ctx)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return
+	case <-timer.C:
+	}
+
+	log.Printf("Check cycle at %d height has not completed within %s, restarting it", checkpoint, timeout)
+	logGoroutines()
+
+	cancel()
+	fc.nodePool.reset()
+
+	alert := WatchdogAlert{Checkpoint: checkpoint, Timeout: timeout, Recovered: true}
+	select {
+	case <-done:
+	case <-time.After(watchdogGracePeriod):
+		log.Printf("Check cycle at %d height did not return after its connections were closed", checkpoint)
+		alert.Recovered = false
+	}
+
+	fc.audit.Record("watchdog", "restart check cycle", fmt.Sprintf("height %d, timeout %s, recovered %t", checkpoint, timeout, alert.Recovered))
+	fc.alertManager.sendUntracked(alert)
+
+	if alert.Recovered {
+		return
+	}
+
+	// The stuck cycle still owns the checkpoint and the cycle state, so no other cycle is started until it returns.
+	<-done
+	log.Printf("Check cycle at %d height returned, resuming the checks", checkpoint)
+	fc.audit.Record("watchdog", "stuck check cycle returned", fmt.Sprintf("height %d", checkpoint))
+}
+
+// logGoroutines logs the stacks of every goroutine, to tell where a stuck cycle is blocked.
+func logGoroutines() {
+	if err := pprof.Lookup("goroutine").WriteTo(os.Stderr, 1); err != nil {
+		log.Printf("failed to dump goroutines: %v", err)
+	}
+}
+
+func (a WatchdogAlert) getType() AlertType {
+	return WatchdogAlertType
+}
+
+func (a WatchdogAlert) createMessage() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "<b>⚠️ Check cycle restarted </b>\n\n")
+	fmt.Fprintf(&buf, "The check at %d height did not complete within %s. It was cancelled and the connections to the nodes were reset.", a.Checkpoint, a.Timeout)
+
+	if !a.Recovered {
+		fmt.Fprintf(&buf, "\n\nThe cycle did not stop after its connections were closed and is likely deadlocked. No further checks run until it returns, the checker should be restarted.")
+	}
+
+	return buf.String()
+}
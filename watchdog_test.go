@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/proximax-storage/go-xpx-chain-sdk/sdk"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health"
+	"github.com/proximax-storage/go-xpx-chain-sdk/tools/health/packets"
+	crypto "github.com/proximax-storage/go-xpx-crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchdogTimeout(t *testing.T) {
+	pool := PoolConfig{Concurrency: 2, ConnectTimeout: "1s", RequestTimeout: "2s", HeightWaitTimeout: "3s", HashDeadline: "4s"}
+
+	// Two batches of requests: 14s to connect, 8s for identities and links, 7s for heights, 4s for hashes and 30s for
+	// the alerts.
+	assert.Equal(t, 189*time.Second, (&WatchdogConfig{}).getTimeout(pool, 4))
+	assert.Equal(t, 94500*time.Millisecond, (&WatchdogConfig{Multiplier: 1.5}).getTimeout(pool, 4))
+	assert.Equal(t, 150*time.Second, (&WatchdogConfig{}).getTimeout(pool, 1))
+	assert.Equal(t, 2*time.Minute, (&WatchdogConfig{Timeout: "2m"}).getTimeout(pool, 4))
+	assert.Equal(t, 189*time.Second, (&WatchdogConfig{Timeout: "soon"}).getTimeout(pool, 4))
+	assert.Zero(t, (&WatchdogConfig{Disabled: true}).getTimeout(pool, 4))
+}
+
+func TestWatchdog(t *testing.T) {
+	node := newTestNode(t, 10, sdk.Hash{1})
+
+	fc := newReportTestForkChecker(t, node)
+	fc.audit = NewAuditLog(filepath.Join(t.TempDir(), "audit.log"))
+	fc.cfg.WatchdogConfig.Timeout = "100ms"
+
+	// The connection outlives the request timeout, so only the watchdog can unblock a node that stopped answering.
+	fc.nodePool.requestTimeout = time.Minute
+	_, err := fc.nodePool.ConnectToNodes([]*health.NodeInfo{node.info()}, false)
+	require.NoError(t, err)
+	node.delay.Store(int64(time.Hour))
+
+	started := time.Now()
+	fc.runCycle()
+	assert.Less(t, time.Since(started), watchdogGracePeriod)
+
+	// The cancelled cycle stopped right after reconnecting, without advancing the checkpoint.
+	assert.Equal(t, uint64(10), fc.checkpoint)
+
+	audit, err := os.ReadFile(fc.audit.file)
+	require.NoError(t, err)
+	assert.Contains(t, string(audit), "restart check cycle")
+	assert.Contains(t, string(audit), "recovered true")
+
+	t.Run("Slow connect", func(t *testing.T) {
+		var nodes []*testNode
+		for i := 0; i < 4; i++ {
+			nodes = append(nodes, newTestNode(t, 10, sdk.Hash{1}))
+		}
+
+		fc := newReportTestForkChecker(t, nodes...)
+		fc.audit = NewAuditLog(filepath.Join(t.TempDir(), "audit.log"))
+		fc.cfg.PoolConfig = PoolConfig{Concurrency: 1, ConnectTimeout: "50ms", RequestTimeout: "200ms", HeightWaitTimeout: "50ms", HashDeadline: "700ms"}
+		fc.cfg.WatchdogConfig.Multiplier = 1
+
+		keyPair, err := crypto.NewRandomKeyPair()
+		require.NoError(t, err)
+		fc.nodePool = NewNodePool(keyPair, packets.NoneConnectionSecurity, fc.cfg.PoolConfig)
+		_, err = fc.nodePool.ConnectToNodes(fc.alertManager.nodeInfos, false)
+		require.NoError(t, err)
+
+		// Every phase queries the nodes one at a time, so checking the connections alone takes longer than the
+		// timeouts of a single request, wait and deadline put together.
+		for _, node := range nodes {
+			node.delay.Store(int64(150 * time.Millisecond))
+		}
+
+		started := time.Now()
+		fc.runCycle()
+		assert.Greater(t, time.Since(started), time.Second)
+
+		// The slow but healthy cycle completed without a restart.
+		assert.Equal(t, uint64(11), fc.checkpoint)
+		assert.NoFileExists(t, fc.audit.file)
+	})
+
+	t.Run("Stuck cycle", func(t *testing.T) {
+		grace := watchdogGracePeriod
+		watchdogGracePeriod = 100 * time.Millisecond
+		t.Cleanup(func() { watchdogGracePeriod = grace })
+
+		fc := newReportTestForkChecker(t, newTestNode(t, 10, sdk.Hash{1}))
+		fc.audit = NewAuditLog(filepath.Join(t.TempDir(), "audit.log"))
+		fc.cfg.WatchdogConfig.Timeout = "100ms"
+
+		// A cycle blocked on a lock is not released by closing the connections.
+		fc.alertManager.mu.Lock()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			fc.runCycle()
+		}()
+
+		// The stuck cycle is reported, but no other cycle starts next to it.
+		require.Eventually(t, func() bool {
+			audit, _ := os.ReadFile(fc.audit.file)
+			return strings.Contains(string(audit), "recovered false")
+		}, 5*time.Second, 10*time.Millisecond)
+		select {
+		case <-done:
+			t.Fatal("watchdog returned before the stuck cycle")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		fc.alertManager.mu.Unlock()
+		<-done
+
+		audit, err := os.ReadFile(fc.audit.file)
+		require.NoError(t, err)
+		assert.Contains(t, string(audit), "stuck check cycle returned")
+	})
+
+	t.Run("Alert", func(t *testing.T) {
+		alert := WatchdogAlert{Checkpoint: 10, Timeout: time.Minute, Recovered: true}
+		assert.Equal(t, WatchdogAlertType, alert.getType())
+		assert.Contains(t, alert.createMessage(), "did not complete within 1m0s")
+		assert.NotContains(t, alert.createMessage(), "deadlocked")
+
+		alert.Recovered = false
+		assert.Contains(t, alert.createMessage(), "deadlocked")
+	})
+}